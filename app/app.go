@@ -92,6 +92,13 @@ func (a *App) IsReady() bool {
 
 func (a *App) Run() error {
 
+	if viper.GetInt("metrics.port") > 0 {
+		err := a.InitMetricsServer(":" + strconv.Itoa(viper.GetInt("metrics.port")))
+		if err != nil {
+			return err
+		}
+	}
+
 	port := strconv.Itoa(viper.GetInt("service.port"))
 	err := a.InitGRPCServer(":" + port)
 	if err != nil {
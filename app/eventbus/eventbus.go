@@ -81,11 +81,9 @@ func (eb *EventBus) Emit(eventName string, data []byte) error {
 	return nil
 }
 
-func (eb *EventBus) On(eventName string, fn func(*stan.Msg)) error {
+func (eb *EventBus) On(eventName string, fn func(*stan.Msg), opts ...stan.SubscriptionOption) (stan.Subscription, error) {
 
-	if _, err := eb.client.Subscribe(eventName, fn, stan.SetManualAckMode()); err != nil {
-		return err
-	}
+	opts = append(opts, stan.SetManualAckMode())
 
-	return nil
+	return eb.client.Subscribe(eventName, fn, opts...)
 }
@@ -1,9 +1,11 @@
 package app
 
 import (
+	"errors"
 	"net"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
@@ -30,6 +32,12 @@ func (a *App) InitGRPCServer(host string) error {
 
 	// Register data source adapter service
 	dataSnapshotService := data_snapshot.CreateService(app.AppImpl(a))
+	if dataSnapshotService == nil {
+		err := errors.New("failed to create data snapshot service")
+		log.Fatal(err)
+		return err
+	}
+
 	pb.RegisterDataSnapshotServer(s, dataSnapshotService)
 	reflection.Register(s)
 
@@ -37,6 +45,15 @@ func (a *App) InitGRPCServer(host string) error {
 		"service": "DataHandler",
 	}).Info("Registered service")
 
+	// Admin service is only served on its own address
+	if adminHost := viper.GetString("admin.listen"); adminHost != "" {
+		err := a.InitAdminServer(adminHost, data_snapshot.CreateAdminService(dataSnapshotService))
+		if err != nil {
+			log.Fatal(err)
+			return err
+		}
+	}
+
 	// Starting server
 	if err := s.Serve(lis); err != nil {
 		log.Fatal(err)
@@ -45,3 +62,29 @@ func (a *App) InitGRPCServer(host string) error {
 
 	return nil
 }
+
+// InitAdminServer serves admin service, which drops, truncates and rebuilds
+// collections, apart from clients of snapshots.
+func (a *App) InitAdminServer(host string, adminService *data_snapshot.AdminService) error {
+
+	lis, err := net.Listen("tcp", host)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"host": host,
+	}).Info("Starting admin server on " + host)
+
+	s := grpc.NewServer()
+	pb.RegisterDataSnapshotAdminServer(s, adminService)
+	reflection.Register(s)
+
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			log.Error(err)
+		}
+	}()
+
+	return nil
+}
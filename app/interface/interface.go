@@ -4,7 +4,7 @@ import "github.com/nats-io/stan.go"
 
 type EventBusImpl interface {
 	Emit(string, []byte) error
-	On(string, func(*stan.Msg)) error
+	On(string, func(*stan.Msg), ...stan.SubscriptionOption) (stan.Subscription, error)
 }
 
 type AppImpl interface {
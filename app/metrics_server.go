@@ -0,0 +1,33 @@
+package app
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+func (a *App) InitMetricsServer(host string) error {
+
+	// Start to listen on port
+	lis, err := net.Listen("tcp", host)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"host": host,
+	}).Info("Starting metrics server on " + host)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	go func() {
+		if err := http.Serve(lis, mux); err != nil {
+			log.Error(err)
+		}
+	}()
+
+	return nil
+}
@@ -3,14 +3,21 @@ module gravity-data-snapshot
 go 1.13
 
 require (
-	github.com/golang/protobuf v1.3.2
+	github.com/cockroachdb/errors v1.11.3
+	github.com/cockroachdb/pebble v1.1.5
+	github.com/golang/protobuf v1.5.3
+	github.com/golang/snappy v0.0.4
+	github.com/klauspost/compress v1.17.9
 	github.com/nats-io/nats-streaming-server v0.17.0 // indirect
-	github.com/nats-io/nats.go v1.9.1
+	github.com/nats-io/nats.go v1.23.0
 	github.com/nats-io/stan.go v0.6.0
-	github.com/prometheus/common v0.4.0
-	github.com/sirupsen/logrus v1.4.2
+	github.com/prometheus/client_golang v1.15.1
+	github.com/prometheus/common v0.42.0
+	github.com/sirupsen/logrus v1.9.0
 	github.com/sony/sonyflake v1.0.0
 	github.com/spf13/viper v1.6.2
 	github.com/syndtr/goleveldb v1.0.0
-	google.golang.org/grpc v1.21.0
+	go.etcd.io/bbolt v1.3.9
+	google.golang.org/grpc v1.56.3
+	google.golang.org/protobuf v1.33.0
 )
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"flag"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
 	app "gravity-data-snapshot/app"
+	data_snapshot "gravity-data-snapshot/services/data_snapshot"
 )
 
 func init() {
@@ -29,6 +31,31 @@ func init() {
 //go:generate protoc --go_out=plugins=grpc:. pb/data_snapshot.proto
 func main() {
 
+	restore := flag.String("restore", "", "restore collections from backup archive before starting")
+	migrateLayout := flag.String("migrate-layout", "", "move collections to directory or shared layout and exit")
+	flag.Parse()
+
+	if *migrateLayout != "" {
+		err := data_snapshot.MigrateLayout(*migrateLayout)
+		if err != nil {
+			log.Fatal(err)
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"layout": *migrateLayout,
+		}).Info("Migrated collections, set database.layout before starting")
+		return
+	}
+
+	if *restore != "" {
+		err := restoreBackup(*restore)
+		if err != nil {
+			log.Fatal(err)
+			return
+		}
+	}
+
 	// Initializing application
 	a := app.CreateApp()
 
@@ -45,3 +72,33 @@ func main() {
 		return
 	}
 }
+
+// restoreBackup restores collections and makes ingestion resume from the
+// oldest sequence of them, events which are applied already get skipped.
+func restoreBackup(filename string) error {
+
+	results, err := data_snapshot.RestoreArchive(filename)
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		return nil
+	}
+
+	start := results[0].Sequence
+	for _, meta := range results {
+		if meta.Sequence < start {
+			start = meta.Sequence
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"collections": len(results),
+		"start":       start + 1,
+	}).Info("Restored backup, ingestion resumes from archived sequence")
+
+	viper.Set("event_store.start_sequence", start+1)
+
+	return nil
+}
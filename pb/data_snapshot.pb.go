@@ -110,296 +110,4114 @@ func (m *GetSnapshotStateReply) GetSequence() uint64 {
 	return 0
 }
 
-type GetSnapshotRequest struct {
+type GetCollectionStatsRequest struct {
 	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *GetSnapshotRequest) Reset()         { *m = GetSnapshotRequest{} }
-func (m *GetSnapshotRequest) String() string { return proto.CompactTextString(m) }
-func (*GetSnapshotRequest) ProtoMessage()    {}
-func (*GetSnapshotRequest) Descriptor() ([]byte, []int) {
+func (m *GetCollectionStatsRequest) Reset()         { *m = GetCollectionStatsRequest{} }
+func (m *GetCollectionStatsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetCollectionStatsRequest) ProtoMessage()    {}
+func (*GetCollectionStatsRequest) Descriptor() ([]byte, []int) {
 	return fileDescriptor_83c47b6a48ae8a41, []int{2}
 }
 
-func (m *GetSnapshotRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_GetSnapshotRequest.Unmarshal(m, b)
+func (m *GetCollectionStatsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetCollectionStatsRequest.Unmarshal(m, b)
 }
-func (m *GetSnapshotRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_GetSnapshotRequest.Marshal(b, m, deterministic)
+func (m *GetCollectionStatsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetCollectionStatsRequest.Marshal(b, m, deterministic)
 }
-func (m *GetSnapshotRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GetSnapshotRequest.Merge(m, src)
+func (m *GetCollectionStatsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetCollectionStatsRequest.Merge(m, src)
 }
-func (m *GetSnapshotRequest) XXX_Size() int {
-	return xxx_messageInfo_GetSnapshotRequest.Size(m)
+func (m *GetCollectionStatsRequest) XXX_Size() int {
+	return xxx_messageInfo_GetCollectionStatsRequest.Size(m)
 }
-func (m *GetSnapshotRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_GetSnapshotRequest.DiscardUnknown(m)
+func (m *GetCollectionStatsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetCollectionStatsRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_GetSnapshotRequest proto.InternalMessageInfo
+var xxx_messageInfo_GetCollectionStatsRequest proto.InternalMessageInfo
 
-func (m *GetSnapshotRequest) GetCollection() string {
+func (m *GetCollectionStatsRequest) GetCollection() string {
 	if m != nil {
 		return m.Collection
 	}
 	return ""
 }
 
-type SnapshotPacket struct {
-	Collection           string           `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
-	Sequence             uint64           `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
-	Entries              []*SnapshotEntry `protobuf:"bytes,3,rep,name=entries,proto3" json:"entries,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
-	XXX_unrecognized     []byte           `json:"-"`
-	XXX_sizecache        int32            `json:"-"`
+// Total bytes is size of record data, last updated is unix time in nanoseconds.
+// Limits of quota are zero if they are not set, collection is frozen when
+// quota policy stopped ingestion.
+type GetCollectionStatsReply struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Sequence             uint64   `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	RecordCount          uint64   `protobuf:"varint,3,opt,name=record_count,json=recordCount,proto3" json:"record_count,omitempty"`
+	TotalBytes           uint64   `protobuf:"varint,4,opt,name=total_bytes,json=totalBytes,proto3" json:"total_bytes,omitempty"`
+	LastUpdated          int64    `protobuf:"varint,5,opt,name=last_updated,json=lastUpdated,proto3" json:"last_updated,omitempty"`
+	MaxRecords           uint64   `protobuf:"varint,6,opt,name=max_records,json=maxRecords,proto3" json:"max_records,omitempty"`
+	MaxBytes             uint64   `protobuf:"varint,7,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	MaxRecordSize        uint64   `protobuf:"varint,8,opt,name=max_record_size,json=maxRecordSize,proto3" json:"max_record_size,omitempty"`
+	QuotaPolicy          string   `protobuf:"bytes,9,opt,name=quota_policy,json=quotaPolicy,proto3" json:"quota_policy,omitempty"`
+	Frozen               bool     `protobuf:"varint,10,opt,name=frozen,proto3" json:"frozen,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *SnapshotPacket) Reset()         { *m = SnapshotPacket{} }
-func (m *SnapshotPacket) String() string { return proto.CompactTextString(m) }
-func (*SnapshotPacket) ProtoMessage()    {}
-func (*SnapshotPacket) Descriptor() ([]byte, []int) {
+func (m *GetCollectionStatsReply) Reset()         { *m = GetCollectionStatsReply{} }
+func (m *GetCollectionStatsReply) String() string { return proto.CompactTextString(m) }
+func (*GetCollectionStatsReply) ProtoMessage()    {}
+func (*GetCollectionStatsReply) Descriptor() ([]byte, []int) {
 	return fileDescriptor_83c47b6a48ae8a41, []int{3}
 }
 
-func (m *SnapshotPacket) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_SnapshotPacket.Unmarshal(m, b)
+func (m *GetCollectionStatsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetCollectionStatsReply.Unmarshal(m, b)
 }
-func (m *SnapshotPacket) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_SnapshotPacket.Marshal(b, m, deterministic)
+func (m *GetCollectionStatsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetCollectionStatsReply.Marshal(b, m, deterministic)
 }
-func (m *SnapshotPacket) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_SnapshotPacket.Merge(m, src)
+func (m *GetCollectionStatsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetCollectionStatsReply.Merge(m, src)
 }
-func (m *SnapshotPacket) XXX_Size() int {
-	return xxx_messageInfo_SnapshotPacket.Size(m)
+func (m *GetCollectionStatsReply) XXX_Size() int {
+	return xxx_messageInfo_GetCollectionStatsReply.Size(m)
 }
-func (m *SnapshotPacket) XXX_DiscardUnknown() {
-	xxx_messageInfo_SnapshotPacket.DiscardUnknown(m)
+func (m *GetCollectionStatsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetCollectionStatsReply.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_SnapshotPacket proto.InternalMessageInfo
+var xxx_messageInfo_GetCollectionStatsReply proto.InternalMessageInfo
 
-func (m *SnapshotPacket) GetCollection() string {
+func (m *GetCollectionStatsReply) GetCollection() string {
 	if m != nil {
 		return m.Collection
 	}
 	return ""
 }
 
-func (m *SnapshotPacket) GetSequence() uint64 {
+func (m *GetCollectionStatsReply) GetSequence() uint64 {
 	if m != nil {
 		return m.Sequence
 	}
 	return 0
 }
 
-func (m *SnapshotPacket) GetEntries() []*SnapshotEntry {
+func (m *GetCollectionStatsReply) GetRecordCount() uint64 {
 	if m != nil {
-		return m.Entries
+		return m.RecordCount
 	}
-	return nil
+	return 0
 }
 
-type SnapshotEntry struct {
-	Data                 []byte   `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+func (m *GetCollectionStatsReply) GetTotalBytes() uint64 {
+	if m != nil {
+		return m.TotalBytes
+	}
+	return 0
 }
 
-func (m *SnapshotEntry) Reset()         { *m = SnapshotEntry{} }
-func (m *SnapshotEntry) String() string { return proto.CompactTextString(m) }
-func (*SnapshotEntry) ProtoMessage()    {}
-func (*SnapshotEntry) Descriptor() ([]byte, []int) {
-	return fileDescriptor_83c47b6a48ae8a41, []int{4}
+func (m *GetCollectionStatsReply) GetLastUpdated() int64 {
+	if m != nil {
+		return m.LastUpdated
+	}
+	return 0
 }
 
-func (m *SnapshotEntry) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_SnapshotEntry.Unmarshal(m, b)
-}
-func (m *SnapshotEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_SnapshotEntry.Marshal(b, m, deterministic)
-}
-func (m *SnapshotEntry) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_SnapshotEntry.Merge(m, src)
-}
-func (m *SnapshotEntry) XXX_Size() int {
-	return xxx_messageInfo_SnapshotEntry.Size(m)
+func (m *GetCollectionStatsReply) GetMaxRecords() uint64 {
+	if m != nil {
+		return m.MaxRecords
+	}
+	return 0
 }
-func (m *SnapshotEntry) XXX_DiscardUnknown() {
-	xxx_messageInfo_SnapshotEntry.DiscardUnknown(m)
+
+func (m *GetCollectionStatsReply) GetMaxBytes() uint64 {
+	if m != nil {
+		return m.MaxBytes
+	}
+	return 0
 }
 
-var xxx_messageInfo_SnapshotEntry proto.InternalMessageInfo
+func (m *GetCollectionStatsReply) GetMaxRecordSize() uint64 {
+	if m != nil {
+		return m.MaxRecordSize
+	}
+	return 0
+}
 
-func (m *SnapshotEntry) GetData() []byte {
+func (m *GetCollectionStatsReply) GetQuotaPolicy() string {
 	if m != nil {
-		return m.Data
+		return m.QuotaPolicy
 	}
-	return nil
+	return ""
 }
 
-func init() {
-	proto.RegisterType((*GetSnapshotStateRequest)(nil), "gravity.GetSnapshotStateRequest")
-	proto.RegisterType((*GetSnapshotStateReply)(nil), "gravity.GetSnapshotStateReply")
-	proto.RegisterType((*GetSnapshotRequest)(nil), "gravity.GetSnapshotRequest")
-	proto.RegisterType((*SnapshotPacket)(nil), "gravity.SnapshotPacket")
-	proto.RegisterType((*SnapshotEntry)(nil), "gravity.SnapshotEntry")
+func (m *GetCollectionStatsReply) GetFrozen() bool {
+	if m != nil {
+		return m.Frozen
+	}
+	return false
 }
 
-func init() { proto.RegisterFile("pb/data_snapshot.proto", fileDescriptor_83c47b6a48ae8a41) }
+// Clients which set accept_compressed get records as they are stored, with
+// compression of entry set to codec name. Records are read from checkpoint
+// if it is set. Only records which match filter are sent if it is set, e.g.
+// tenant = "acme" AND (status IN ("active", "trial") OR score >= 10). Filter
+// compares dotted paths of fields to JSON values with =, !=, <, <=, >, >=,
+// IN, NOT IN, STARTS WITH, IS NULL and IS NOT NULL, and combines them with
+// AND, OR, NOT and parentheses. Missing fields are null. Records only carry
+// included fields along with primary key if any are given, and excluded
+// fields are removed, both as dotted paths. Such records are never sent
+// compressed.
+//
+// Packets of streams which read checkpoint carry cursor, a stream which was
+// cut short resumes after the last packet received with the same request
+// along with cursor, which stands for checkpoint too. Cursor is rejected if
+// collection, filter, fields or partition differ from the request it came
+// from. Resumable streams read a checkpoint of their own if request has
+// none, it is released once stream is over and expires after
+// checkpoint.stream_ttl otherwise, which starts over whenever stream resumes
+// or breaks.
+//
+// Partition limits records to one of partitions of checkpoint, which have to
+// be read from the same checkpoint. Indexes don't serve filters of
+// partitions.
+type GetSnapshotRequest struct {
+	Collection           string             `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	AcceptCompressed     bool               `protobuf:"varint,2,opt,name=accept_compressed,json=acceptCompressed,proto3" json:"accept_compressed,omitempty"`
+	Checkpoint           string             `protobuf:"bytes,3,opt,name=checkpoint,proto3" json:"checkpoint,omitempty"`
+	Filter               string             `protobuf:"bytes,4,opt,name=filter,proto3" json:"filter,omitempty"`
+	IncludeFields        []string           `protobuf:"bytes,5,rep,name=include_fields,json=includeFields,proto3" json:"include_fields,omitempty"`
+	ExcludeFields        []string           `protobuf:"bytes,6,rep,name=exclude_fields,json=excludeFields,proto3" json:"exclude_fields,omitempty"`
+	Cursor               []byte             `protobuf:"bytes,7,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Resumable            bool               `protobuf:"varint,8,opt,name=resumable,proto3" json:"resumable,omitempty"`
+	Partition            *SnapshotPartition `protobuf:"bytes,9,opt,name=partition,proto3" json:"partition,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
 
-var fileDescriptor_83c47b6a48ae8a41 = []byte{
-	// 267 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x52, 0x4d, 0x4b, 0x03, 0x31,
-	0x14, 0x6c, 0x6c, 0xb1, 0xfa, 0x5a, 0x45, 0x1e, 0xd8, 0x96, 0x0a, 0x65, 0x89, 0x97, 0x3d, 0xad,
-	0xa5, 0x7a, 0xf1, 0xae, 0xf4, 0x2a, 0x59, 0xf0, 0x2a, 0xe9, 0xfa, 0xd0, 0xc5, 0x25, 0x89, 0x9b,
-	0xa7, 0xb0, 0x17, 0xff, 0x92, 0x7f, 0x51, 0xba, 0x36, 0x65, 0xb5, 0x7e, 0x81, 0xb7, 0x24, 0x33,
-	0xf3, 0x66, 0x32, 0x09, 0x0c, 0xdc, 0xe2, 0xe4, 0x56, 0xb3, 0xbe, 0xf1, 0x46, 0x3b, 0x7f, 0x6f,
-	0x39, 0x71, 0xa5, 0x65, 0x8b, 0xdd, 0xbb, 0x52, 0x3f, 0xe7, 0x5c, 0xc9, 0x73, 0x18, 0xce, 0x89,
-	0xd3, 0x15, 0x9a, 0xb2, 0x66, 0x52, 0xf4, 0xf8, 0x44, 0x9e, 0x71, 0x02, 0x90, 0xd9, 0xa2, 0xa0,
-	0x8c, 0x73, 0x6b, 0x46, 0x22, 0x12, 0xf1, 0xae, 0x6a, 0x9c, 0xc8, 0x14, 0x0e, 0x37, 0xa5, 0xae,
-	0xa8, 0x7e, 0x13, 0xe2, 0x18, 0x76, 0xfc, 0xd2, 0xc3, 0x64, 0x34, 0xda, 0x8a, 0x44, 0xdc, 0x51,
-	0xeb, 0xbd, 0x3c, 0x03, 0x6c, 0x0c, 0xfd, 0x6b, 0x94, 0x17, 0xd8, 0x0f, 0x92, 0x2b, 0x9d, 0x3d,
-	0x10, 0xff, 0x27, 0x03, 0x4e, 0xa1, 0x4b, 0x86, 0xcb, 0x9c, 0xfc, 0xa8, 0x1d, 0xb5, 0xe3, 0xde,
-	0x6c, 0x90, 0xac, 0xea, 0x4a, 0x82, 0xcb, 0xa5, 0xe1, 0xb2, 0x52, 0x81, 0x26, 0x8f, 0x61, 0xef,
-	0x03, 0x82, 0x08, 0x9d, 0x65, 0xed, 0xb5, 0x71, 0x5f, 0xd5, 0xeb, 0xd9, 0xab, 0x80, 0xfe, 0x85,
-	0x66, 0x1d, 0x98, 0x78, 0x0d, 0x07, 0x9f, 0x0b, 0xc4, 0x68, 0x6d, 0xf5, 0xcd, 0xb3, 0x8c, 0x27,
-	0x3f, 0x30, 0x5c, 0x51, 0xc9, 0x16, 0xce, 0xa1, 0xd7, 0x80, 0xf0, 0xe8, 0x2b, 0x41, 0x98, 0x36,
-	0xdc, 0xb8, 0xda, 0x7b, 0x81, 0xb2, 0x35, 0x15, 0x8b, 0xed, 0xfa, 0xb3, 0x9c, 0xbe, 0x05, 0x00,
-	0x00, 0xff, 0xff, 0xd1, 0x83, 0xfe, 0xcd, 0x46, 0x02, 0x00, 0x00,
+func (m *GetSnapshotRequest) Reset()         { *m = GetSnapshotRequest{} }
+func (m *GetSnapshotRequest) String() string { return proto.CompactTextString(m) }
+func (*GetSnapshotRequest) ProtoMessage()    {}
+func (*GetSnapshotRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{4}
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+func (m *GetSnapshotRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetSnapshotRequest.Unmarshal(m, b)
+}
+func (m *GetSnapshotRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetSnapshotRequest.Marshal(b, m, deterministic)
+}
+func (m *GetSnapshotRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetSnapshotRequest.Merge(m, src)
+}
+func (m *GetSnapshotRequest) XXX_Size() int {
+	return xxx_messageInfo_GetSnapshotRequest.Size(m)
+}
+func (m *GetSnapshotRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetSnapshotRequest.DiscardUnknown(m)
+}
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+var xxx_messageInfo_GetSnapshotRequest proto.InternalMessageInfo
 
-// DataSnapshotClient is the client API for DataSnapshot service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type DataSnapshotClient interface {
-	GetSnapshotState(ctx context.Context, in *GetSnapshotStateRequest, opts ...grpc.CallOption) (*GetSnapshotStateReply, error)
-	GetSnapshot(ctx context.Context, in *GetSnapshotRequest, opts ...grpc.CallOption) (DataSnapshot_GetSnapshotClient, error)
+func (m *GetSnapshotRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
 }
 
-type dataSnapshotClient struct {
-	cc *grpc.ClientConn
+func (m *GetSnapshotRequest) GetAcceptCompressed() bool {
+	if m != nil {
+		return m.AcceptCompressed
+	}
+	return false
 }
 
-func NewDataSnapshotClient(cc *grpc.ClientConn) DataSnapshotClient {
-	return &dataSnapshotClient{cc}
+func (m *GetSnapshotRequest) GetCheckpoint() string {
+	if m != nil {
+		return m.Checkpoint
+	}
+	return ""
 }
 
-func (c *dataSnapshotClient) GetSnapshotState(ctx context.Context, in *GetSnapshotStateRequest, opts ...grpc.CallOption) (*GetSnapshotStateReply, error) {
-	out := new(GetSnapshotStateReply)
-	err := c.cc.Invoke(ctx, "/gravity.DataSnapshot/GetSnapshotState", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *GetSnapshotRequest) GetFilter() string {
+	if m != nil {
+		return m.Filter
 	}
-	return out, nil
+	return ""
 }
 
-func (c *dataSnapshotClient) GetSnapshot(ctx context.Context, in *GetSnapshotRequest, opts ...grpc.CallOption) (DataSnapshot_GetSnapshotClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_DataSnapshot_serviceDesc.Streams[0], "/gravity.DataSnapshot/GetSnapshot", opts...)
-	if err != nil {
-		return nil, err
-	}
-	x := &dataSnapshotGetSnapshotClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
-		return nil, err
+func (m *GetSnapshotRequest) GetIncludeFields() []string {
+	if m != nil {
+		return m.IncludeFields
 	}
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
+	return nil
+}
+
+func (m *GetSnapshotRequest) GetExcludeFields() []string {
+	if m != nil {
+		return m.ExcludeFields
 	}
-	return x, nil
+	return nil
 }
 
-type DataSnapshot_GetSnapshotClient interface {
-	Recv() (*SnapshotPacket, error)
-	grpc.ClientStream
+func (m *GetSnapshotRequest) GetCursor() []byte {
+	if m != nil {
+		return m.Cursor
+	}
+	return nil
 }
 
-type dataSnapshotGetSnapshotClient struct {
-	grpc.ClientStream
+func (m *GetSnapshotRequest) GetResumable() bool {
+	if m != nil {
+		return m.Resumable
+	}
+	return false
 }
 
-func (x *dataSnapshotGetSnapshotClient) Recv() (*SnapshotPacket, error) {
-	m := new(SnapshotPacket)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
-		return nil, err
+func (m *GetSnapshotRequest) GetPartition() *SnapshotPartition {
+	if m != nil {
+		return m.Partition
 	}
-	return m, nil
+	return nil
 }
 
-// DataSnapshotServer is the server API for DataSnapshot service.
-type DataSnapshotServer interface {
-	GetSnapshotState(context.Context, *GetSnapshotStateRequest) (*GetSnapshotStateReply, error)
-	GetSnapshot(*GetSnapshotRequest, DataSnapshot_GetSnapshotServer) error
+// Changes at or after since_sequence are returned, deleted records are
+// returned as entries with primary key data and deleted flag.
+type GetSnapshotDeltaRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	SinceSequence        uint64   `protobuf:"varint,2,opt,name=since_sequence,json=sinceSequence,proto3" json:"since_sequence,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-// UnimplementedDataSnapshotServer can be embedded to have forward compatible implementations.
-type UnimplementedDataSnapshotServer struct {
+func (m *GetSnapshotDeltaRequest) Reset()         { *m = GetSnapshotDeltaRequest{} }
+func (m *GetSnapshotDeltaRequest) String() string { return proto.CompactTextString(m) }
+func (*GetSnapshotDeltaRequest) ProtoMessage()    {}
+func (*GetSnapshotDeltaRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{5}
 }
 
-func (*UnimplementedDataSnapshotServer) GetSnapshotState(ctx context.Context, req *GetSnapshotStateRequest) (*GetSnapshotStateReply, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetSnapshotState not implemented")
+func (m *GetSnapshotDeltaRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetSnapshotDeltaRequest.Unmarshal(m, b)
 }
-func (*UnimplementedDataSnapshotServer) GetSnapshot(req *GetSnapshotRequest, srv DataSnapshot_GetSnapshotServer) error {
-	return status.Errorf(codes.Unimplemented, "method GetSnapshot not implemented")
+func (m *GetSnapshotDeltaRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetSnapshotDeltaRequest.Marshal(b, m, deterministic)
 }
-
-func RegisterDataSnapshotServer(s *grpc.Server, srv DataSnapshotServer) {
-	s.RegisterService(&_DataSnapshot_serviceDesc, srv)
+func (m *GetSnapshotDeltaRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetSnapshotDeltaRequest.Merge(m, src)
+}
+func (m *GetSnapshotDeltaRequest) XXX_Size() int {
+	return xxx_messageInfo_GetSnapshotDeltaRequest.Size(m)
+}
+func (m *GetSnapshotDeltaRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetSnapshotDeltaRequest.DiscardUnknown(m)
 }
 
-func _DataSnapshot_GetSnapshotState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetSnapshotStateRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(DataSnapshotServer).GetSnapshotState(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/gravity.DataSnapshot/GetSnapshotState",
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(DataSnapshotServer).GetSnapshotState(ctx, req.(*GetSnapshotStateRequest))
+var xxx_messageInfo_GetSnapshotDeltaRequest proto.InternalMessageInfo
+
+func (m *GetSnapshotDeltaRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
 	}
-	return interceptor(ctx, in, info, handler)
+	return ""
 }
 
-func _DataSnapshot_GetSnapshot_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(GetSnapshotRequest)
-	if err := stream.RecvMsg(m); err != nil {
-		return err
+func (m *GetSnapshotDeltaRequest) GetSinceSequence() uint64 {
+	if m != nil {
+		return m.SinceSequence
 	}
-	return srv.(DataSnapshotServer).GetSnapshot(m, &dataSnapshotGetSnapshotServer{stream})
+	return 0
 }
 
-type DataSnapshot_GetSnapshotServer interface {
-	Send(*SnapshotPacket) error
-	grpc.ServerStream
+// Values are JSON encoded. Records whose leading indexed fields equal to
+// values are returned, range applies to the next field of index with
+// inclusive start and exclusive end.
+type QueryIndexRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Index                string   `protobuf:"bytes,2,opt,name=index,proto3" json:"index,omitempty"`
+	Values               [][]byte `protobuf:"bytes,3,rep,name=values,proto3" json:"values,omitempty"`
+	RangeStart           []byte   `protobuf:"bytes,4,opt,name=range_start,json=rangeStart,proto3" json:"range_start,omitempty"`
+	RangeEnd             []byte   `protobuf:"bytes,5,opt,name=range_end,json=rangeEnd,proto3" json:"range_end,omitempty"`
+	Limit                uint32   `protobuf:"varint,6,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-type dataSnapshotGetSnapshotServer struct {
-	grpc.ServerStream
+func (m *QueryIndexRequest) Reset()         { *m = QueryIndexRequest{} }
+func (m *QueryIndexRequest) String() string { return proto.CompactTextString(m) }
+func (*QueryIndexRequest) ProtoMessage()    {}
+func (*QueryIndexRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{6}
 }
 
-func (x *dataSnapshotGetSnapshotServer) Send(m *SnapshotPacket) error {
-	return x.ServerStream.SendMsg(m)
+func (m *QueryIndexRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_QueryIndexRequest.Unmarshal(m, b)
 }
-
-var _DataSnapshot_serviceDesc = grpc.ServiceDesc{
+func (m *QueryIndexRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_QueryIndexRequest.Marshal(b, m, deterministic)
+}
+func (m *QueryIndexRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueryIndexRequest.Merge(m, src)
+}
+func (m *QueryIndexRequest) XXX_Size() int {
+	return xxx_messageInfo_QueryIndexRequest.Size(m)
+}
+func (m *QueryIndexRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueryIndexRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueryIndexRequest proto.InternalMessageInfo
+
+func (m *QueryIndexRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *QueryIndexRequest) GetIndex() string {
+	if m != nil {
+		return m.Index
+	}
+	return ""
+}
+
+func (m *QueryIndexRequest) GetValues() [][]byte {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+func (m *QueryIndexRequest) GetRangeStart() []byte {
+	if m != nil {
+		return m.RangeStart
+	}
+	return nil
+}
+
+func (m *QueryIndexRequest) GetRangeEnd() []byte {
+	if m != nil {
+		return m.RangeEnd
+	}
+	return nil
+}
+
+func (m *QueryIndexRequest) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+// Key is JSON encoded value of primary key. Collection has to enable history.
+type GetRecordHistoryRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Key                  []byte   `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetRecordHistoryRequest) Reset()         { *m = GetRecordHistoryRequest{} }
+func (m *GetRecordHistoryRequest) String() string { return proto.CompactTextString(m) }
+func (*GetRecordHistoryRequest) ProtoMessage()    {}
+func (*GetRecordHistoryRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{7}
+}
+
+func (m *GetRecordHistoryRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetRecordHistoryRequest.Unmarshal(m, b)
+}
+func (m *GetRecordHistoryRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetRecordHistoryRequest.Marshal(b, m, deterministic)
+}
+func (m *GetRecordHistoryRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetRecordHistoryRequest.Merge(m, src)
+}
+func (m *GetRecordHistoryRequest) XXX_Size() int {
+	return xxx_messageInfo_GetRecordHistoryRequest.Size(m)
+}
+func (m *GetRecordHistoryRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetRecordHistoryRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetRecordHistoryRequest proto.InternalMessageInfo
+
+func (m *GetRecordHistoryRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *GetRecordHistoryRequest) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+type GetRecordHistoryReply struct {
+	Collection           string           `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Sequence             uint64           `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Versions             []*RecordVersion `protobuf:"bytes,3,rep,name=versions,proto3" json:"versions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *GetRecordHistoryReply) Reset()         { *m = GetRecordHistoryReply{} }
+func (m *GetRecordHistoryReply) String() string { return proto.CompactTextString(m) }
+func (*GetRecordHistoryReply) ProtoMessage()    {}
+func (*GetRecordHistoryReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{8}
+}
+
+func (m *GetRecordHistoryReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetRecordHistoryReply.Unmarshal(m, b)
+}
+func (m *GetRecordHistoryReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetRecordHistoryReply.Marshal(b, m, deterministic)
+}
+func (m *GetRecordHistoryReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetRecordHistoryReply.Merge(m, src)
+}
+func (m *GetRecordHistoryReply) XXX_Size() int {
+	return xxx_messageInfo_GetRecordHistoryReply.Size(m)
+}
+func (m *GetRecordHistoryReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetRecordHistoryReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetRecordHistoryReply proto.InternalMessageInfo
+
+func (m *GetRecordHistoryReply) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *GetRecordHistoryReply) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *GetRecordHistoryReply) GetVersions() []*RecordVersion {
+	if m != nil {
+		return m.Versions
+	}
+	return nil
+}
+
+// Versions are in order of sequence, updated at is unix time in nanoseconds.
+// Deleted versions carry primary key data only.
+type RecordVersion struct {
+	Sequence             uint64   `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	UpdatedAt            int64    `protobuf:"varint,2,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	Deleted              bool     `protobuf:"varint,3,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	Data                 []byte   `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RecordVersion) Reset()         { *m = RecordVersion{} }
+func (m *RecordVersion) String() string { return proto.CompactTextString(m) }
+func (*RecordVersion) ProtoMessage()    {}
+func (*RecordVersion) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{9}
+}
+
+func (m *RecordVersion) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RecordVersion.Unmarshal(m, b)
+}
+func (m *RecordVersion) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RecordVersion.Marshal(b, m, deterministic)
+}
+func (m *RecordVersion) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RecordVersion.Merge(m, src)
+}
+func (m *RecordVersion) XXX_Size() int {
+	return xxx_messageInfo_RecordVersion.Size(m)
+}
+func (m *RecordVersion) XXX_DiscardUnknown() {
+	xxx_messageInfo_RecordVersion.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RecordVersion proto.InternalMessageInfo
+
+func (m *RecordVersion) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *RecordVersion) GetUpdatedAt() int64 {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return 0
+}
+
+func (m *RecordVersion) GetDeleted() bool {
+	if m != nil {
+		return m.Deleted
+	}
+	return false
+}
+
+func (m *RecordVersion) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+// Records are returned as they were at sequence, which has to be within
+// history of collection.
+type GetSnapshotAtRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Sequence             uint64   `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetSnapshotAtRequest) Reset()         { *m = GetSnapshotAtRequest{} }
+func (m *GetSnapshotAtRequest) String() string { return proto.CompactTextString(m) }
+func (*GetSnapshotAtRequest) ProtoMessage()    {}
+func (*GetSnapshotAtRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{10}
+}
+
+func (m *GetSnapshotAtRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetSnapshotAtRequest.Unmarshal(m, b)
+}
+func (m *GetSnapshotAtRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetSnapshotAtRequest.Marshal(b, m, deterministic)
+}
+func (m *GetSnapshotAtRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetSnapshotAtRequest.Merge(m, src)
+}
+func (m *GetSnapshotAtRequest) XXX_Size() int {
+	return xxx_messageInfo_GetSnapshotAtRequest.Size(m)
+}
+func (m *GetSnapshotAtRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetSnapshotAtRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetSnapshotAtRequest proto.InternalMessageInfo
+
+func (m *GetSnapshotAtRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *GetSnapshotAtRequest) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+// Checkpoint holds snapshots of collections until its lease expires or it is
+// released. Name is generated if it is empty, ttl is in milliseconds and
+// falls back to default of server. Snapshots are taken one after another, so
+// collections are not at the same moment of event store, reply tells
+// sequence of each.
+type CreateCheckpointRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Collections          []string `protobuf:"bytes,2,rep,name=collections,proto3" json:"collections,omitempty"`
+	Ttl                  uint64   `protobuf:"varint,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateCheckpointRequest) Reset()         { *m = CreateCheckpointRequest{} }
+func (m *CreateCheckpointRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateCheckpointRequest) ProtoMessage()    {}
+func (*CreateCheckpointRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{11}
+}
+
+func (m *CreateCheckpointRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateCheckpointRequest.Unmarshal(m, b)
+}
+func (m *CreateCheckpointRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateCheckpointRequest.Marshal(b, m, deterministic)
+}
+func (m *CreateCheckpointRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateCheckpointRequest.Merge(m, src)
+}
+func (m *CreateCheckpointRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateCheckpointRequest.Size(m)
+}
+func (m *CreateCheckpointRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateCheckpointRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateCheckpointRequest proto.InternalMessageInfo
+
+func (m *CreateCheckpointRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *CreateCheckpointRequest) GetCollections() []string {
+	if m != nil {
+		return m.Collections
+	}
+	return nil
+}
+
+func (m *CreateCheckpointRequest) GetTtl() uint64 {
+	if m != nil {
+		return m.Ttl
+	}
+	return 0
+}
+
+type RenewCheckpointRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Ttl                  uint64   `protobuf:"varint,2,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RenewCheckpointRequest) Reset()         { *m = RenewCheckpointRequest{} }
+func (m *RenewCheckpointRequest) String() string { return proto.CompactTextString(m) }
+func (*RenewCheckpointRequest) ProtoMessage()    {}
+func (*RenewCheckpointRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{12}
+}
+
+func (m *RenewCheckpointRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RenewCheckpointRequest.Unmarshal(m, b)
+}
+func (m *RenewCheckpointRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RenewCheckpointRequest.Marshal(b, m, deterministic)
+}
+func (m *RenewCheckpointRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RenewCheckpointRequest.Merge(m, src)
+}
+func (m *RenewCheckpointRequest) XXX_Size() int {
+	return xxx_messageInfo_RenewCheckpointRequest.Size(m)
+}
+func (m *RenewCheckpointRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RenewCheckpointRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RenewCheckpointRequest proto.InternalMessageInfo
+
+func (m *RenewCheckpointRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *RenewCheckpointRequest) GetTtl() uint64 {
+	if m != nil {
+		return m.Ttl
+	}
+	return 0
+}
+
+// Expires at is unix time in nanoseconds.
+type CheckpointReply struct {
+	Name                 string                  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	ExpiresAt            int64                   `protobuf:"varint,2,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	Collections          []*CheckpointCollection `protobuf:"bytes,3,rep,name=collections,proto3" json:"collections,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                `json:"-"`
+	XXX_unrecognized     []byte                  `json:"-"`
+	XXX_sizecache        int32                   `json:"-"`
+}
+
+func (m *CheckpointReply) Reset()         { *m = CheckpointReply{} }
+func (m *CheckpointReply) String() string { return proto.CompactTextString(m) }
+func (*CheckpointReply) ProtoMessage()    {}
+func (*CheckpointReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{13}
+}
+
+func (m *CheckpointReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CheckpointReply.Unmarshal(m, b)
+}
+func (m *CheckpointReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CheckpointReply.Marshal(b, m, deterministic)
+}
+func (m *CheckpointReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CheckpointReply.Merge(m, src)
+}
+func (m *CheckpointReply) XXX_Size() int {
+	return xxx_messageInfo_CheckpointReply.Size(m)
+}
+func (m *CheckpointReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_CheckpointReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CheckpointReply proto.InternalMessageInfo
+
+func (m *CheckpointReply) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *CheckpointReply) GetExpiresAt() int64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
+func (m *CheckpointReply) GetCollections() []*CheckpointCollection {
+	if m != nil {
+		return m.Collections
+	}
+	return nil
+}
+
+type CheckpointCollection struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Sequence             uint64   `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CheckpointCollection) Reset()         { *m = CheckpointCollection{} }
+func (m *CheckpointCollection) String() string { return proto.CompactTextString(m) }
+func (*CheckpointCollection) ProtoMessage()    {}
+func (*CheckpointCollection) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{14}
+}
+
+func (m *CheckpointCollection) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CheckpointCollection.Unmarshal(m, b)
+}
+func (m *CheckpointCollection) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CheckpointCollection.Marshal(b, m, deterministic)
+}
+func (m *CheckpointCollection) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CheckpointCollection.Merge(m, src)
+}
+func (m *CheckpointCollection) XXX_Size() int {
+	return xxx_messageInfo_CheckpointCollection.Size(m)
+}
+func (m *CheckpointCollection) XXX_DiscardUnknown() {
+	xxx_messageInfo_CheckpointCollection.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CheckpointCollection proto.InternalMessageInfo
+
+func (m *CheckpointCollection) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *CheckpointCollection) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+type ReleaseCheckpointRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReleaseCheckpointRequest) Reset()         { *m = ReleaseCheckpointRequest{} }
+func (m *ReleaseCheckpointRequest) String() string { return proto.CompactTextString(m) }
+func (*ReleaseCheckpointRequest) ProtoMessage()    {}
+func (*ReleaseCheckpointRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{15}
+}
+
+func (m *ReleaseCheckpointRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReleaseCheckpointRequest.Unmarshal(m, b)
+}
+func (m *ReleaseCheckpointRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReleaseCheckpointRequest.Marshal(b, m, deterministic)
+}
+func (m *ReleaseCheckpointRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReleaseCheckpointRequest.Merge(m, src)
+}
+func (m *ReleaseCheckpointRequest) XXX_Size() int {
+	return xxx_messageInfo_ReleaseCheckpointRequest.Size(m)
+}
+func (m *ReleaseCheckpointRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReleaseCheckpointRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReleaseCheckpointRequest proto.InternalMessageInfo
+
+func (m *ReleaseCheckpointRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type ReleaseCheckpointReply struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReleaseCheckpointReply) Reset()         { *m = ReleaseCheckpointReply{} }
+func (m *ReleaseCheckpointReply) String() string { return proto.CompactTextString(m) }
+func (*ReleaseCheckpointReply) ProtoMessage()    {}
+func (*ReleaseCheckpointReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{16}
+}
+
+func (m *ReleaseCheckpointReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReleaseCheckpointReply.Unmarshal(m, b)
+}
+func (m *ReleaseCheckpointReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReleaseCheckpointReply.Marshal(b, m, deterministic)
+}
+func (m *ReleaseCheckpointReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReleaseCheckpointReply.Merge(m, src)
+}
+func (m *ReleaseCheckpointReply) XXX_Size() int {
+	return xxx_messageInfo_ReleaseCheckpointReply.Size(m)
+}
+func (m *ReleaseCheckpointReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReleaseCheckpointReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReleaseCheckpointReply proto.InternalMessageInfo
+
+func (m *ReleaseCheckpointReply) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+// Format is json_schema or protobuf to export schema along with fields.
+type DescribeCollectionRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Format               string   `protobuf:"bytes,2,opt,name=format,proto3" json:"format,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DescribeCollectionRequest) Reset()         { *m = DescribeCollectionRequest{} }
+func (m *DescribeCollectionRequest) String() string { return proto.CompactTextString(m) }
+func (*DescribeCollectionRequest) ProtoMessage()    {}
+func (*DescribeCollectionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{17}
+}
+
+func (m *DescribeCollectionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DescribeCollectionRequest.Unmarshal(m, b)
+}
+func (m *DescribeCollectionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DescribeCollectionRequest.Marshal(b, m, deterministic)
+}
+func (m *DescribeCollectionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DescribeCollectionRequest.Merge(m, src)
+}
+func (m *DescribeCollectionRequest) XXX_Size() int {
+	return xxx_messageInfo_DescribeCollectionRequest.Size(m)
+}
+func (m *DescribeCollectionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DescribeCollectionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DescribeCollectionRequest proto.InternalMessageInfo
+
+func (m *DescribeCollectionRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *DescribeCollectionRequest) GetFormat() string {
+	if m != nil {
+		return m.Format
+	}
+	return ""
+}
+
+// Types are of JSON Schema, null is told by nullable. Fields are seen first
+// and last at sequences of events.
+type SchemaField struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Number               int32    `protobuf:"varint,2,opt,name=number,proto3" json:"number,omitempty"`
+	Types                []string `protobuf:"bytes,3,rep,name=types,proto3" json:"types,omitempty"`
+	Nullable             bool     `protobuf:"varint,4,opt,name=nullable,proto3" json:"nullable,omitempty"`
+	Primary              bool     `protobuf:"varint,5,opt,name=primary,proto3" json:"primary,omitempty"`
+	FirstSeen            uint64   `protobuf:"varint,6,opt,name=first_seen,json=firstSeen,proto3" json:"first_seen,omitempty"`
+	LastSeen             uint64   `protobuf:"varint,7,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SchemaField) Reset()         { *m = SchemaField{} }
+func (m *SchemaField) String() string { return proto.CompactTextString(m) }
+func (*SchemaField) ProtoMessage()    {}
+func (*SchemaField) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{18}
+}
+
+func (m *SchemaField) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SchemaField.Unmarshal(m, b)
+}
+func (m *SchemaField) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SchemaField.Marshal(b, m, deterministic)
+}
+func (m *SchemaField) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SchemaField.Merge(m, src)
+}
+func (m *SchemaField) XXX_Size() int {
+	return xxx_messageInfo_SchemaField.Size(m)
+}
+func (m *SchemaField) XXX_DiscardUnknown() {
+	xxx_messageInfo_SchemaField.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SchemaField proto.InternalMessageInfo
+
+func (m *SchemaField) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *SchemaField) GetNumber() int32 {
+	if m != nil {
+		return m.Number
+	}
+	return 0
+}
+
+func (m *SchemaField) GetTypes() []string {
+	if m != nil {
+		return m.Types
+	}
+	return nil
+}
+
+func (m *SchemaField) GetNullable() bool {
+	if m != nil {
+		return m.Nullable
+	}
+	return false
+}
+
+func (m *SchemaField) GetPrimary() bool {
+	if m != nil {
+		return m.Primary
+	}
+	return false
+}
+
+func (m *SchemaField) GetFirstSeen() uint64 {
+	if m != nil {
+		return m.FirstSeen
+	}
+	return 0
+}
+
+func (m *SchemaField) GetLastSeen() uint64 {
+	if m != nil {
+		return m.LastSeen
+	}
+	return 0
+}
+
+// Descriptor set holds one file with message named after collection, which
+// depends on struct.proto and wrappers.proto of well-known types.
+type DescribeCollectionReply struct {
+	Collection           string         `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Fields               []*SchemaField `protobuf:"bytes,2,rep,name=fields,proto3" json:"fields,omitempty"`
+	JsonSchema           string         `protobuf:"bytes,3,opt,name=json_schema,json=jsonSchema,proto3" json:"json_schema,omitempty"`
+	DescriptorSet        []byte         `protobuf:"bytes,4,opt,name=descriptor_set,json=descriptorSet,proto3" json:"descriptor_set,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *DescribeCollectionReply) Reset()         { *m = DescribeCollectionReply{} }
+func (m *DescribeCollectionReply) String() string { return proto.CompactTextString(m) }
+func (*DescribeCollectionReply) ProtoMessage()    {}
+func (*DescribeCollectionReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{19}
+}
+
+func (m *DescribeCollectionReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DescribeCollectionReply.Unmarshal(m, b)
+}
+func (m *DescribeCollectionReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DescribeCollectionReply.Marshal(b, m, deterministic)
+}
+func (m *DescribeCollectionReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DescribeCollectionReply.Merge(m, src)
+}
+func (m *DescribeCollectionReply) XXX_Size() int {
+	return xxx_messageInfo_DescribeCollectionReply.Size(m)
+}
+func (m *DescribeCollectionReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_DescribeCollectionReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DescribeCollectionReply proto.InternalMessageInfo
+
+func (m *DescribeCollectionReply) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *DescribeCollectionReply) GetFields() []*SchemaField {
+	if m != nil {
+		return m.Fields
+	}
+	return nil
+}
+
+func (m *DescribeCollectionReply) GetJsonSchema() string {
+	if m != nil {
+		return m.JsonSchema
+	}
+	return ""
+}
+
+func (m *DescribeCollectionReply) GetDescriptorSet() []byte {
+	if m != nil {
+		return m.DescriptorSet
+	}
+	return nil
+}
+
+// Checksum is read from checkpoint, or at sequence within history, or at
+// the current sequence if both are empty. Nodes of level are returned, all
+// of them if no indexes are given.
+type GetChecksumRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Checkpoint           string   `protobuf:"bytes,2,opt,name=checkpoint,proto3" json:"checkpoint,omitempty"`
+	Sequence             uint64   `protobuf:"varint,3,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Level                uint32   `protobuf:"varint,4,opt,name=level,proto3" json:"level,omitempty"`
+	Nodes                []uint32 `protobuf:"varint,5,rep,packed,name=nodes,proto3" json:"nodes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetChecksumRequest) Reset()         { *m = GetChecksumRequest{} }
+func (m *GetChecksumRequest) String() string { return proto.CompactTextString(m) }
+func (*GetChecksumRequest) ProtoMessage()    {}
+func (*GetChecksumRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{20}
+}
+
+func (m *GetChecksumRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetChecksumRequest.Unmarshal(m, b)
+}
+func (m *GetChecksumRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetChecksumRequest.Marshal(b, m, deterministic)
+}
+func (m *GetChecksumRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetChecksumRequest.Merge(m, src)
+}
+func (m *GetChecksumRequest) XXX_Size() int {
+	return xxx_messageInfo_GetChecksumRequest.Size(m)
+}
+func (m *GetChecksumRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetChecksumRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetChecksumRequest proto.InternalMessageInfo
+
+func (m *GetChecksumRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *GetChecksumRequest) GetCheckpoint() string {
+	if m != nil {
+		return m.Checkpoint
+	}
+	return ""
+}
+
+func (m *GetChecksumRequest) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *GetChecksumRequest) GetLevel() uint32 {
+	if m != nil {
+		return m.Level
+	}
+	return 0
+}
+
+func (m *GetChecksumRequest) GetNodes() []uint32 {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+// Hash of node is sum modulo 2^256 of SHA-256 of records under it, record is
+// hashed by its primary key along with data. Records belong to leaf chosen
+// by the first 4 bits of hash of their primary key per level.
+type ChecksumNode struct {
+	Level                uint32   `protobuf:"varint,1,opt,name=level,proto3" json:"level,omitempty"`
+	Index                uint32   `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
+	Hash                 []byte   `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
+	Count                uint64   `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ChecksumNode) Reset()         { *m = ChecksumNode{} }
+func (m *ChecksumNode) String() string { return proto.CompactTextString(m) }
+func (*ChecksumNode) ProtoMessage()    {}
+func (*ChecksumNode) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{21}
+}
+
+func (m *ChecksumNode) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChecksumNode.Unmarshal(m, b)
+}
+func (m *ChecksumNode) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChecksumNode.Marshal(b, m, deterministic)
+}
+func (m *ChecksumNode) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChecksumNode.Merge(m, src)
+}
+func (m *ChecksumNode) XXX_Size() int {
+	return xxx_messageInfo_ChecksumNode.Size(m)
+}
+func (m *ChecksumNode) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChecksumNode.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChecksumNode proto.InternalMessageInfo
+
+func (m *ChecksumNode) GetLevel() uint32 {
+	if m != nil {
+		return m.Level
+	}
+	return 0
+}
+
+func (m *ChecksumNode) GetIndex() uint32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *ChecksumNode) GetHash() []byte {
+	if m != nil {
+		return m.Hash
+	}
+	return nil
+}
+
+func (m *ChecksumNode) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+type GetChecksumReply struct {
+	Collection           string          `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Sequence             uint64          `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Root                 []byte          `protobuf:"bytes,3,opt,name=root,proto3" json:"root,omitempty"`
+	Count                uint64          `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`
+	Nodes                []*ChecksumNode `protobuf:"bytes,5,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	Depth                uint32          `protobuf:"varint,6,opt,name=depth,proto3" json:"depth,omitempty"`
+	Fanout               uint32          `protobuf:"varint,7,opt,name=fanout,proto3" json:"fanout,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *GetChecksumReply) Reset()         { *m = GetChecksumReply{} }
+func (m *GetChecksumReply) String() string { return proto.CompactTextString(m) }
+func (*GetChecksumReply) ProtoMessage()    {}
+func (*GetChecksumReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{22}
+}
+
+func (m *GetChecksumReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetChecksumReply.Unmarshal(m, b)
+}
+func (m *GetChecksumReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetChecksumReply.Marshal(b, m, deterministic)
+}
+func (m *GetChecksumReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetChecksumReply.Merge(m, src)
+}
+func (m *GetChecksumReply) XXX_Size() int {
+	return xxx_messageInfo_GetChecksumReply.Size(m)
+}
+func (m *GetChecksumReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetChecksumReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetChecksumReply proto.InternalMessageInfo
+
+func (m *GetChecksumReply) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *GetChecksumReply) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *GetChecksumReply) GetRoot() []byte {
+	if m != nil {
+		return m.Root
+	}
+	return nil
+}
+
+func (m *GetChecksumReply) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *GetChecksumReply) GetNodes() []*ChecksumNode {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+func (m *GetChecksumReply) GetDepth() uint32 {
+	if m != nil {
+		return m.Depth
+	}
+	return 0
+}
+
+func (m *GetChecksumReply) GetFanout() uint32 {
+	if m != nil {
+		return m.Fanout
+	}
+	return 0
+}
+
+// Records under leaf of hash tree, leaf is index of node of the deepest
+// level. They are read from checkpoint, or at the current sequence.
+type GetChecksumLeafRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Checkpoint           string   `protobuf:"bytes,2,opt,name=checkpoint,proto3" json:"checkpoint,omitempty"`
+	Leaf                 uint32   `protobuf:"varint,3,opt,name=leaf,proto3" json:"leaf,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetChecksumLeafRequest) Reset()         { *m = GetChecksumLeafRequest{} }
+func (m *GetChecksumLeafRequest) String() string { return proto.CompactTextString(m) }
+func (*GetChecksumLeafRequest) ProtoMessage()    {}
+func (*GetChecksumLeafRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{23}
+}
+
+func (m *GetChecksumLeafRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetChecksumLeafRequest.Unmarshal(m, b)
+}
+func (m *GetChecksumLeafRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetChecksumLeafRequest.Marshal(b, m, deterministic)
+}
+func (m *GetChecksumLeafRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetChecksumLeafRequest.Merge(m, src)
+}
+func (m *GetChecksumLeafRequest) XXX_Size() int {
+	return xxx_messageInfo_GetChecksumLeafRequest.Size(m)
+}
+func (m *GetChecksumLeafRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetChecksumLeafRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetChecksumLeafRequest proto.InternalMessageInfo
+
+func (m *GetChecksumLeafRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *GetChecksumLeafRequest) GetCheckpoint() string {
+	if m != nil {
+		return m.Checkpoint
+	}
+	return ""
+}
+
+func (m *GetChecksumLeafRequest) GetLeaf() uint32 {
+	if m != nil {
+		return m.Leaf
+	}
+	return 0
+}
+
+// Pattern is a glob of collection names like "orders-*", all collections are
+// listed if it is empty.
+type ListCollectionsRequest struct {
+	Pattern              string   `protobuf:"bytes,1,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListCollectionsRequest) Reset()         { *m = ListCollectionsRequest{} }
+func (m *ListCollectionsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListCollectionsRequest) ProtoMessage()    {}
+func (*ListCollectionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{24}
+}
+
+func (m *ListCollectionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListCollectionsRequest.Unmarshal(m, b)
+}
+func (m *ListCollectionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListCollectionsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListCollectionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListCollectionsRequest.Merge(m, src)
+}
+func (m *ListCollectionsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListCollectionsRequest.Size(m)
+}
+func (m *ListCollectionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListCollectionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListCollectionsRequest proto.InternalMessageInfo
+
+func (m *ListCollectionsRequest) GetPattern() string {
+	if m != nil {
+		return m.Pattern
+	}
+	return ""
+}
+
+// State is ok, frozen, rebuilding, failed, closed while collection is not
+// open, busy while collection is being dropped, truncated or renamed, or
+// error if its stats can't be read. Sequence and sizes of collection which
+// isn't open are the ones of the last time it was opened or closed. Created
+// at is unix time in nanoseconds.
+type CollectionInfo struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Sequence             uint64   `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	RecordCount          uint64   `protobuf:"varint,3,opt,name=record_count,json=recordCount,proto3" json:"record_count,omitempty"`
+	TotalBytes           uint64   `protobuf:"varint,4,opt,name=total_bytes,json=totalBytes,proto3" json:"total_bytes,omitempty"`
+	CreatedAt            int64    `protobuf:"varint,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	LastUpdated          int64    `protobuf:"varint,6,opt,name=last_updated,json=lastUpdated,proto3" json:"last_updated,omitempty"`
+	State                string   `protobuf:"bytes,7,opt,name=state,proto3" json:"state,omitempty"`
+	Error                string   `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CollectionInfo) Reset()         { *m = CollectionInfo{} }
+func (m *CollectionInfo) String() string { return proto.CompactTextString(m) }
+func (*CollectionInfo) ProtoMessage()    {}
+func (*CollectionInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{25}
+}
+
+func (m *CollectionInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CollectionInfo.Unmarshal(m, b)
+}
+func (m *CollectionInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CollectionInfo.Marshal(b, m, deterministic)
+}
+func (m *CollectionInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CollectionInfo.Merge(m, src)
+}
+func (m *CollectionInfo) XXX_Size() int {
+	return xxx_messageInfo_CollectionInfo.Size(m)
+}
+func (m *CollectionInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_CollectionInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CollectionInfo proto.InternalMessageInfo
+
+func (m *CollectionInfo) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *CollectionInfo) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *CollectionInfo) GetRecordCount() uint64 {
+	if m != nil {
+		return m.RecordCount
+	}
+	return 0
+}
+
+func (m *CollectionInfo) GetTotalBytes() uint64 {
+	if m != nil {
+		return m.TotalBytes
+	}
+	return 0
+}
+
+func (m *CollectionInfo) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+func (m *CollectionInfo) GetLastUpdated() int64 {
+	if m != nil {
+		return m.LastUpdated
+	}
+	return 0
+}
+
+func (m *CollectionInfo) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *CollectionInfo) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type ListCollectionsReply struct {
+	Collections          []*CollectionInfo `protobuf:"bytes,1,rep,name=collections,proto3" json:"collections,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *ListCollectionsReply) Reset()         { *m = ListCollectionsReply{} }
+func (m *ListCollectionsReply) String() string { return proto.CompactTextString(m) }
+func (*ListCollectionsReply) ProtoMessage()    {}
+func (*ListCollectionsReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{26}
+}
+
+func (m *ListCollectionsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListCollectionsReply.Unmarshal(m, b)
+}
+func (m *ListCollectionsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListCollectionsReply.Marshal(b, m, deterministic)
+}
+func (m *ListCollectionsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListCollectionsReply.Merge(m, src)
+}
+func (m *ListCollectionsReply) XXX_Size() int {
+	return xxx_messageInfo_ListCollectionsReply.Size(m)
+}
+func (m *ListCollectionsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListCollectionsReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListCollectionsReply proto.InternalMessageInfo
+
+func (m *ListCollectionsReply) GetCollections() []*CollectionInfo {
+	if m != nil {
+		return m.Collections
+	}
+	return nil
+}
+
+// Key is JSON encoded value of primary key as in events. Record is read from
+// checkpoint if it is set.
+type GetRecordRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Key                  []byte   `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Checkpoint           string   `protobuf:"bytes,3,opt,name=checkpoint,proto3" json:"checkpoint,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetRecordRequest) Reset()         { *m = GetRecordRequest{} }
+func (m *GetRecordRequest) String() string { return proto.CompactTextString(m) }
+func (*GetRecordRequest) ProtoMessage()    {}
+func (*GetRecordRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{27}
+}
+
+func (m *GetRecordRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetRecordRequest.Unmarshal(m, b)
+}
+func (m *GetRecordRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetRecordRequest.Marshal(b, m, deterministic)
+}
+func (m *GetRecordRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetRecordRequest.Merge(m, src)
+}
+func (m *GetRecordRequest) XXX_Size() int {
+	return xxx_messageInfo_GetRecordRequest.Size(m)
+}
+func (m *GetRecordRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetRecordRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetRecordRequest proto.InternalMessageInfo
+
+func (m *GetRecordRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *GetRecordRequest) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *GetRecordRequest) GetCheckpoint() string {
+	if m != nil {
+		return m.Checkpoint
+	}
+	return ""
+}
+
+// Sequence is the one record was read at, data is empty unless found.
+type GetRecordReply struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Sequence             uint64   `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Found                bool     `protobuf:"varint,3,opt,name=found,proto3" json:"found,omitempty"`
+	Data                 []byte   `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetRecordReply) Reset()         { *m = GetRecordReply{} }
+func (m *GetRecordReply) String() string { return proto.CompactTextString(m) }
+func (*GetRecordReply) ProtoMessage()    {}
+func (*GetRecordReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{28}
+}
+
+func (m *GetRecordReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetRecordReply.Unmarshal(m, b)
+}
+func (m *GetRecordReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetRecordReply.Marshal(b, m, deterministic)
+}
+func (m *GetRecordReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetRecordReply.Merge(m, src)
+}
+func (m *GetRecordReply) XXX_Size() int {
+	return xxx_messageInfo_GetRecordReply.Size(m)
+}
+func (m *GetRecordReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetRecordReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetRecordReply proto.InternalMessageInfo
+
+func (m *GetRecordReply) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *GetRecordReply) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *GetRecordReply) GetFound() bool {
+	if m != nil {
+		return m.Found
+	}
+	return false
+}
+
+func (m *GetRecordReply) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+// Keys are read from one snapshot, at most max_batch_keys of config.
+type BatchGetRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Keys                 [][]byte `protobuf:"bytes,2,rep,name=keys,proto3" json:"keys,omitempty"`
+	Checkpoint           string   `protobuf:"bytes,3,opt,name=checkpoint,proto3" json:"checkpoint,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BatchGetRequest) Reset()         { *m = BatchGetRequest{} }
+func (m *BatchGetRequest) String() string { return proto.CompactTextString(m) }
+func (*BatchGetRequest) ProtoMessage()    {}
+func (*BatchGetRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{29}
+}
+
+func (m *BatchGetRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchGetRequest.Unmarshal(m, b)
+}
+func (m *BatchGetRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchGetRequest.Marshal(b, m, deterministic)
+}
+func (m *BatchGetRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchGetRequest.Merge(m, src)
+}
+func (m *BatchGetRequest) XXX_Size() int {
+	return xxx_messageInfo_BatchGetRequest.Size(m)
+}
+func (m *BatchGetRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchGetRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchGetRequest proto.InternalMessageInfo
+
+func (m *BatchGetRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *BatchGetRequest) GetKeys() [][]byte {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+func (m *BatchGetRequest) GetCheckpoint() string {
+	if m != nil {
+		return m.Checkpoint
+	}
+	return ""
+}
+
+// Records are in order of keys of request.
+type BatchGetReply struct {
+	Collection           string          `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Sequence             uint64          `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Records              []*RecordLookup `protobuf:"bytes,3,rep,name=records,proto3" json:"records,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *BatchGetReply) Reset()         { *m = BatchGetReply{} }
+func (m *BatchGetReply) String() string { return proto.CompactTextString(m) }
+func (*BatchGetReply) ProtoMessage()    {}
+func (*BatchGetReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{30}
+}
+
+func (m *BatchGetReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchGetReply.Unmarshal(m, b)
+}
+func (m *BatchGetReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchGetReply.Marshal(b, m, deterministic)
+}
+func (m *BatchGetReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchGetReply.Merge(m, src)
+}
+func (m *BatchGetReply) XXX_Size() int {
+	return xxx_messageInfo_BatchGetReply.Size(m)
+}
+func (m *BatchGetReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchGetReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchGetReply proto.InternalMessageInfo
+
+func (m *BatchGetReply) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *BatchGetReply) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *BatchGetReply) GetRecords() []*RecordLookup {
+	if m != nil {
+		return m.Records
+	}
+	return nil
+}
+
+type RecordLookup struct {
+	Key                  []byte   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Found                bool     `protobuf:"varint,2,opt,name=found,proto3" json:"found,omitempty"`
+	Data                 []byte   `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RecordLookup) Reset()         { *m = RecordLookup{} }
+func (m *RecordLookup) String() string { return proto.CompactTextString(m) }
+func (*RecordLookup) ProtoMessage()    {}
+func (*RecordLookup) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{31}
+}
+
+func (m *RecordLookup) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RecordLookup.Unmarshal(m, b)
+}
+func (m *RecordLookup) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RecordLookup.Marshal(b, m, deterministic)
+}
+func (m *RecordLookup) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RecordLookup.Merge(m, src)
+}
+func (m *RecordLookup) XXX_Size() int {
+	return xxx_messageInfo_RecordLookup.Size(m)
+}
+func (m *RecordLookup) XXX_DiscardUnknown() {
+	xxx_messageInfo_RecordLookup.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RecordLookup proto.InternalMessageInfo
+
+func (m *RecordLookup) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *RecordLookup) GetFound() bool {
+	if m != nil {
+		return m.Found
+	}
+	return false
+}
+
+func (m *RecordLookup) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+// Records of collection in checkpoint are split into count partitions of
+// about the same count of records, at most 256. Checkpoint is created with
+// default lease if it is empty, and has to be released by client.
+type GetPartitionsRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Checkpoint           string   `protobuf:"bytes,2,opt,name=checkpoint,proto3" json:"checkpoint,omitempty"`
+	Count                uint32   `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetPartitionsRequest) Reset()         { *m = GetPartitionsRequest{} }
+func (m *GetPartitionsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetPartitionsRequest) ProtoMessage()    {}
+func (*GetPartitionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{32}
+}
+
+func (m *GetPartitionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetPartitionsRequest.Unmarshal(m, b)
+}
+func (m *GetPartitionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetPartitionsRequest.Marshal(b, m, deterministic)
+}
+func (m *GetPartitionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetPartitionsRequest.Merge(m, src)
+}
+func (m *GetPartitionsRequest) XXX_Size() int {
+	return xxx_messageInfo_GetPartitionsRequest.Size(m)
+}
+func (m *GetPartitionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetPartitionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetPartitionsRequest proto.InternalMessageInfo
+
+func (m *GetPartitionsRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *GetPartitionsRequest) GetCheckpoint() string {
+	if m != nil {
+		return m.Checkpoint
+	}
+	return ""
+}
+
+func (m *GetPartitionsRequest) GetCount() uint32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+// Start and end are opaque keys, which are passed back as they are. Fewer
+// partitions are returned if collection has fewer records.
+type SnapshotPartition struct {
+	Start                []byte   `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	End                  []byte   `protobuf:"bytes,2,opt,name=end,proto3" json:"end,omitempty"`
+	RecordCount          uint64   `protobuf:"varint,3,opt,name=record_count,json=recordCount,proto3" json:"record_count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SnapshotPartition) Reset()         { *m = SnapshotPartition{} }
+func (m *SnapshotPartition) String() string { return proto.CompactTextString(m) }
+func (*SnapshotPartition) ProtoMessage()    {}
+func (*SnapshotPartition) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{33}
+}
+
+func (m *SnapshotPartition) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SnapshotPartition.Unmarshal(m, b)
+}
+func (m *SnapshotPartition) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SnapshotPartition.Marshal(b, m, deterministic)
+}
+func (m *SnapshotPartition) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SnapshotPartition.Merge(m, src)
+}
+func (m *SnapshotPartition) XXX_Size() int {
+	return xxx_messageInfo_SnapshotPartition.Size(m)
+}
+func (m *SnapshotPartition) XXX_DiscardUnknown() {
+	xxx_messageInfo_SnapshotPartition.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SnapshotPartition proto.InternalMessageInfo
+
+func (m *SnapshotPartition) GetStart() []byte {
+	if m != nil {
+		return m.Start
+	}
+	return nil
+}
+
+func (m *SnapshotPartition) GetEnd() []byte {
+	if m != nil {
+		return m.End
+	}
+	return nil
+}
+
+func (m *SnapshotPartition) GetRecordCount() uint64 {
+	if m != nil {
+		return m.RecordCount
+	}
+	return 0
+}
+
+type GetPartitionsReply struct {
+	Collection           string               `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Checkpoint           string               `protobuf:"bytes,2,opt,name=checkpoint,proto3" json:"checkpoint,omitempty"`
+	Sequence             uint64               `protobuf:"varint,3,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Partitions           []*SnapshotPartition `protobuf:"bytes,4,rep,name=partitions,proto3" json:"partitions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *GetPartitionsReply) Reset()         { *m = GetPartitionsReply{} }
+func (m *GetPartitionsReply) String() string { return proto.CompactTextString(m) }
+func (*GetPartitionsReply) ProtoMessage()    {}
+func (*GetPartitionsReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{34}
+}
+
+func (m *GetPartitionsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetPartitionsReply.Unmarshal(m, b)
+}
+func (m *GetPartitionsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetPartitionsReply.Marshal(b, m, deterministic)
+}
+func (m *GetPartitionsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetPartitionsReply.Merge(m, src)
+}
+func (m *GetPartitionsReply) XXX_Size() int {
+	return xxx_messageInfo_GetPartitionsReply.Size(m)
+}
+func (m *GetPartitionsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetPartitionsReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetPartitionsReply proto.InternalMessageInfo
+
+func (m *GetPartitionsReply) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *GetPartitionsReply) GetCheckpoint() string {
+	if m != nil {
+		return m.Checkpoint
+	}
+	return ""
+}
+
+func (m *GetPartitionsReply) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *GetPartitionsReply) GetPartitions() []*SnapshotPartition {
+	if m != nil {
+		return m.Partitions
+	}
+	return nil
+}
+
+// Zstd dictionaries which entries were compressed with are sent in the
+// first packet of stream. Cursor tells where snapshot stream resumes after
+// packet, it is only set for streams of checkpoints.
+type SnapshotPacket struct {
+	Collection           string           `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Sequence             uint64           `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Entries              []*SnapshotEntry `protobuf:"bytes,3,rep,name=entries,proto3" json:"entries,omitempty"`
+	Dictionaries         [][]byte         `protobuf:"bytes,4,rep,name=dictionaries,proto3" json:"dictionaries,omitempty"`
+	Cursor               []byte           `protobuf:"bytes,5,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *SnapshotPacket) Reset()         { *m = SnapshotPacket{} }
+func (m *SnapshotPacket) String() string { return proto.CompactTextString(m) }
+func (*SnapshotPacket) ProtoMessage()    {}
+func (*SnapshotPacket) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{35}
+}
+
+func (m *SnapshotPacket) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SnapshotPacket.Unmarshal(m, b)
+}
+func (m *SnapshotPacket) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SnapshotPacket.Marshal(b, m, deterministic)
+}
+func (m *SnapshotPacket) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SnapshotPacket.Merge(m, src)
+}
+func (m *SnapshotPacket) XXX_Size() int {
+	return xxx_messageInfo_SnapshotPacket.Size(m)
+}
+func (m *SnapshotPacket) XXX_DiscardUnknown() {
+	xxx_messageInfo_SnapshotPacket.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SnapshotPacket proto.InternalMessageInfo
+
+func (m *SnapshotPacket) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *SnapshotPacket) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *SnapshotPacket) GetEntries() []*SnapshotEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+func (m *SnapshotPacket) GetDictionaries() [][]byte {
+	if m != nil {
+		return m.Dictionaries
+	}
+	return nil
+}
+
+func (m *SnapshotPacket) GetCursor() []byte {
+	if m != nil {
+		return m.Cursor
+	}
+	return nil
+}
+
+type SnapshotEntry struct {
+	Data                 []byte   `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Sequence             uint64   `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Deleted              bool     `protobuf:"varint,3,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	Compression          string   `protobuf:"bytes,4,opt,name=compression,proto3" json:"compression,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SnapshotEntry) Reset()         { *m = SnapshotEntry{} }
+func (m *SnapshotEntry) String() string { return proto.CompactTextString(m) }
+func (*SnapshotEntry) ProtoMessage()    {}
+func (*SnapshotEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{36}
+}
+
+func (m *SnapshotEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SnapshotEntry.Unmarshal(m, b)
+}
+func (m *SnapshotEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SnapshotEntry.Marshal(b, m, deterministic)
+}
+func (m *SnapshotEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SnapshotEntry.Merge(m, src)
+}
+func (m *SnapshotEntry) XXX_Size() int {
+	return xxx_messageInfo_SnapshotEntry.Size(m)
+}
+func (m *SnapshotEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_SnapshotEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SnapshotEntry proto.InternalMessageInfo
+
+func (m *SnapshotEntry) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *SnapshotEntry) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *SnapshotEntry) GetDeleted() bool {
+	if m != nil {
+		return m.Deleted
+	}
+	return false
+}
+
+func (m *SnapshotEntry) GetCompression() string {
+	if m != nil {
+		return m.Compression
+	}
+	return ""
+}
+
+// All collections are backed up if collections is empty.
+type CreateBackupRequest struct {
+	Collections          []string `protobuf:"bytes,1,rep,name=collections,proto3" json:"collections,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateBackupRequest) Reset()         { *m = CreateBackupRequest{} }
+func (m *CreateBackupRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateBackupRequest) ProtoMessage()    {}
+func (*CreateBackupRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{37}
+}
+
+func (m *CreateBackupRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateBackupRequest.Unmarshal(m, b)
+}
+func (m *CreateBackupRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateBackupRequest.Marshal(b, m, deterministic)
+}
+func (m *CreateBackupRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateBackupRequest.Merge(m, src)
+}
+func (m *CreateBackupRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateBackupRequest.Size(m)
+}
+func (m *CreateBackupRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateBackupRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateBackupRequest proto.InternalMessageInfo
+
+func (m *CreateBackupRequest) GetCollections() []string {
+	if m != nil {
+		return m.Collections
+	}
+	return nil
+}
+
+// Path is location of archive on server.
+type CreateBackupReply struct {
+	Path                 string              `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Collections          []*BackupCollection `protobuf:"bytes,2,rep,name=collections,proto3" json:"collections,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *CreateBackupReply) Reset()         { *m = CreateBackupReply{} }
+func (m *CreateBackupReply) String() string { return proto.CompactTextString(m) }
+func (*CreateBackupReply) ProtoMessage()    {}
+func (*CreateBackupReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{38}
+}
+
+func (m *CreateBackupReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateBackupReply.Unmarshal(m, b)
+}
+func (m *CreateBackupReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateBackupReply.Marshal(b, m, deterministic)
+}
+func (m *CreateBackupReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateBackupReply.Merge(m, src)
+}
+func (m *CreateBackupReply) XXX_Size() int {
+	return xxx_messageInfo_CreateBackupReply.Size(m)
+}
+func (m *CreateBackupReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateBackupReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateBackupReply proto.InternalMessageInfo
+
+func (m *CreateBackupReply) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *CreateBackupReply) GetCollections() []*BackupCollection {
+	if m != nil {
+		return m.Collections
+	}
+	return nil
+}
+
+type BackupCollection struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Sequence             uint64   `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	RecordCount          uint64   `protobuf:"varint,3,opt,name=record_count,json=recordCount,proto3" json:"record_count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BackupCollection) Reset()         { *m = BackupCollection{} }
+func (m *BackupCollection) String() string { return proto.CompactTextString(m) }
+func (*BackupCollection) ProtoMessage()    {}
+func (*BackupCollection) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{39}
+}
+
+func (m *BackupCollection) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BackupCollection.Unmarshal(m, b)
+}
+func (m *BackupCollection) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BackupCollection.Marshal(b, m, deterministic)
+}
+func (m *BackupCollection) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BackupCollection.Merge(m, src)
+}
+func (m *BackupCollection) XXX_Size() int {
+	return xxx_messageInfo_BackupCollection.Size(m)
+}
+func (m *BackupCollection) XXX_DiscardUnknown() {
+	xxx_messageInfo_BackupCollection.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BackupCollection proto.InternalMessageInfo
+
+func (m *BackupCollection) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *BackupCollection) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *BackupCollection) GetRecordCount() uint64 {
+	if m != nil {
+		return m.RecordCount
+	}
+	return 0
+}
+
+// In-flight requests of collection are given close_timeout to finish before
+// they are aborted by drop, truncate and rename.
+type DropCollectionRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DropCollectionRequest) Reset()         { *m = DropCollectionRequest{} }
+func (m *DropCollectionRequest) String() string { return proto.CompactTextString(m) }
+func (*DropCollectionRequest) ProtoMessage()    {}
+func (*DropCollectionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{40}
+}
+
+func (m *DropCollectionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DropCollectionRequest.Unmarshal(m, b)
+}
+func (m *DropCollectionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DropCollectionRequest.Marshal(b, m, deterministic)
+}
+func (m *DropCollectionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DropCollectionRequest.Merge(m, src)
+}
+func (m *DropCollectionRequest) XXX_Size() int {
+	return xxx_messageInfo_DropCollectionRequest.Size(m)
+}
+func (m *DropCollectionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DropCollectionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DropCollectionRequest proto.InternalMessageInfo
+
+func (m *DropCollectionRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+type DropCollectionReply struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DropCollectionReply) Reset()         { *m = DropCollectionReply{} }
+func (m *DropCollectionReply) String() string { return proto.CompactTextString(m) }
+func (*DropCollectionReply) ProtoMessage()    {}
+func (*DropCollectionReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{41}
+}
+
+func (m *DropCollectionReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DropCollectionReply.Unmarshal(m, b)
+}
+func (m *DropCollectionReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DropCollectionReply.Marshal(b, m, deterministic)
+}
+func (m *DropCollectionReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DropCollectionReply.Merge(m, src)
+}
+func (m *DropCollectionReply) XXX_Size() int {
+	return xxx_messageInfo_DropCollectionReply.Size(m)
+}
+func (m *DropCollectionReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_DropCollectionReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DropCollectionReply proto.InternalMessageInfo
+
+func (m *DropCollectionReply) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+// Sequence is kept, changes up to it are no longer available as delta.
+type TruncateCollectionRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TruncateCollectionRequest) Reset()         { *m = TruncateCollectionRequest{} }
+func (m *TruncateCollectionRequest) String() string { return proto.CompactTextString(m) }
+func (*TruncateCollectionRequest) ProtoMessage()    {}
+func (*TruncateCollectionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{42}
+}
+
+func (m *TruncateCollectionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TruncateCollectionRequest.Unmarshal(m, b)
+}
+func (m *TruncateCollectionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TruncateCollectionRequest.Marshal(b, m, deterministic)
+}
+func (m *TruncateCollectionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TruncateCollectionRequest.Merge(m, src)
+}
+func (m *TruncateCollectionRequest) XXX_Size() int {
+	return xxx_messageInfo_TruncateCollectionRequest.Size(m)
+}
+func (m *TruncateCollectionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TruncateCollectionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TruncateCollectionRequest proto.InternalMessageInfo
+
+func (m *TruncateCollectionRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+type TruncateCollectionReply struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Sequence             uint64   `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TruncateCollectionReply) Reset()         { *m = TruncateCollectionReply{} }
+func (m *TruncateCollectionReply) String() string { return proto.CompactTextString(m) }
+func (*TruncateCollectionReply) ProtoMessage()    {}
+func (*TruncateCollectionReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{43}
+}
+
+func (m *TruncateCollectionReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TruncateCollectionReply.Unmarshal(m, b)
+}
+func (m *TruncateCollectionReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TruncateCollectionReply.Marshal(b, m, deterministic)
+}
+func (m *TruncateCollectionReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TruncateCollectionReply.Merge(m, src)
+}
+func (m *TruncateCollectionReply) XXX_Size() int {
+	return xxx_messageInfo_TruncateCollectionReply.Size(m)
+}
+func (m *TruncateCollectionReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_TruncateCollectionReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TruncateCollectionReply proto.InternalMessageInfo
+
+func (m *TruncateCollectionReply) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *TruncateCollectionReply) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+type RenameCollectionRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	NewName              string   `protobuf:"bytes,2,opt,name=new_name,json=newName,proto3" json:"new_name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RenameCollectionRequest) Reset()         { *m = RenameCollectionRequest{} }
+func (m *RenameCollectionRequest) String() string { return proto.CompactTextString(m) }
+func (*RenameCollectionRequest) ProtoMessage()    {}
+func (*RenameCollectionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{44}
+}
+
+func (m *RenameCollectionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RenameCollectionRequest.Unmarshal(m, b)
+}
+func (m *RenameCollectionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RenameCollectionRequest.Marshal(b, m, deterministic)
+}
+func (m *RenameCollectionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RenameCollectionRequest.Merge(m, src)
+}
+func (m *RenameCollectionRequest) XXX_Size() int {
+	return xxx_messageInfo_RenameCollectionRequest.Size(m)
+}
+func (m *RenameCollectionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RenameCollectionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RenameCollectionRequest proto.InternalMessageInfo
+
+func (m *RenameCollectionRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *RenameCollectionRequest) GetNewName() string {
+	if m != nil {
+		return m.NewName
+	}
+	return ""
+}
+
+type RenameCollectionReply struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RenameCollectionReply) Reset()         { *m = RenameCollectionReply{} }
+func (m *RenameCollectionReply) String() string { return proto.CompactTextString(m) }
+func (*RenameCollectionReply) ProtoMessage()    {}
+func (*RenameCollectionReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{45}
+}
+
+func (m *RenameCollectionReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RenameCollectionReply.Unmarshal(m, b)
+}
+func (m *RenameCollectionReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RenameCollectionReply.Marshal(b, m, deterministic)
+}
+func (m *RenameCollectionReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RenameCollectionReply.Merge(m, src)
+}
+func (m *RenameCollectionReply) XXX_Size() int {
+	return xxx_messageInfo_RenameCollectionReply.Size(m)
+}
+func (m *RenameCollectionReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_RenameCollectionReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RenameCollectionReply proto.InternalMessageInfo
+
+func (m *RenameCollectionReply) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+// Frozen collection queues events instead of applying them, queued events
+// are applied once it is unfrozen.
+type FreezeCollectionRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Frozen               bool     `protobuf:"varint,2,opt,name=frozen,proto3" json:"frozen,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FreezeCollectionRequest) Reset()         { *m = FreezeCollectionRequest{} }
+func (m *FreezeCollectionRequest) String() string { return proto.CompactTextString(m) }
+func (*FreezeCollectionRequest) ProtoMessage()    {}
+func (*FreezeCollectionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{46}
+}
+
+func (m *FreezeCollectionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FreezeCollectionRequest.Unmarshal(m, b)
+}
+func (m *FreezeCollectionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FreezeCollectionRequest.Marshal(b, m, deterministic)
+}
+func (m *FreezeCollectionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FreezeCollectionRequest.Merge(m, src)
+}
+func (m *FreezeCollectionRequest) XXX_Size() int {
+	return xxx_messageInfo_FreezeCollectionRequest.Size(m)
+}
+func (m *FreezeCollectionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_FreezeCollectionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FreezeCollectionRequest proto.InternalMessageInfo
+
+func (m *FreezeCollectionRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *FreezeCollectionRequest) GetFrozen() bool {
+	if m != nil {
+		return m.Frozen
+	}
+	return false
+}
+
+type FreezeCollectionReply struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Frozen               bool     `protobuf:"varint,2,opt,name=frozen,proto3" json:"frozen,omitempty"`
+	PendingEvents        uint64   `protobuf:"varint,3,opt,name=pending_events,json=pendingEvents,proto3" json:"pending_events,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FreezeCollectionReply) Reset()         { *m = FreezeCollectionReply{} }
+func (m *FreezeCollectionReply) String() string { return proto.CompactTextString(m) }
+func (*FreezeCollectionReply) ProtoMessage()    {}
+func (*FreezeCollectionReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{47}
+}
+
+func (m *FreezeCollectionReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FreezeCollectionReply.Unmarshal(m, b)
+}
+func (m *FreezeCollectionReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FreezeCollectionReply.Marshal(b, m, deterministic)
+}
+func (m *FreezeCollectionReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FreezeCollectionReply.Merge(m, src)
+}
+func (m *FreezeCollectionReply) XXX_Size() int {
+	return xxx_messageInfo_FreezeCollectionReply.Size(m)
+}
+func (m *FreezeCollectionReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_FreezeCollectionReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FreezeCollectionReply proto.InternalMessageInfo
+
+func (m *FreezeCollectionReply) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *FreezeCollectionReply) GetFrozen() bool {
+	if m != nil {
+		return m.Frozen
+	}
+	return false
+}
+
+func (m *FreezeCollectionReply) GetPendingEvents() uint64 {
+	if m != nil {
+		return m.PendingEvents
+	}
+	return 0
+}
+
+// Compaction runs on whole collection, or on records whose primary keys are
+// within start, inclusive, and limit, exclusive, if either is set. Sizes are
+// bytes on disk before and after and duration is in milliseconds.
+type CompactCollectionRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Start                []byte   `protobuf:"bytes,2,opt,name=start,proto3" json:"start,omitempty"`
+	Limit                []byte   `protobuf:"bytes,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CompactCollectionRequest) Reset()         { *m = CompactCollectionRequest{} }
+func (m *CompactCollectionRequest) String() string { return proto.CompactTextString(m) }
+func (*CompactCollectionRequest) ProtoMessage()    {}
+func (*CompactCollectionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{48}
+}
+
+func (m *CompactCollectionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CompactCollectionRequest.Unmarshal(m, b)
+}
+func (m *CompactCollectionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CompactCollectionRequest.Marshal(b, m, deterministic)
+}
+func (m *CompactCollectionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CompactCollectionRequest.Merge(m, src)
+}
+func (m *CompactCollectionRequest) XXX_Size() int {
+	return xxx_messageInfo_CompactCollectionRequest.Size(m)
+}
+func (m *CompactCollectionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CompactCollectionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CompactCollectionRequest proto.InternalMessageInfo
+
+func (m *CompactCollectionRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *CompactCollectionRequest) GetStart() []byte {
+	if m != nil {
+		return m.Start
+	}
+	return nil
+}
+
+func (m *CompactCollectionRequest) GetLimit() []byte {
+	if m != nil {
+		return m.Limit
+	}
+	return nil
+}
+
+type CompactCollectionReply struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	SizeBefore           int64    `protobuf:"varint,2,opt,name=size_before,json=sizeBefore,proto3" json:"size_before,omitempty"`
+	SizeAfter            int64    `protobuf:"varint,3,opt,name=size_after,json=sizeAfter,proto3" json:"size_after,omitempty"`
+	Duration             int64    `protobuf:"varint,4,opt,name=duration,proto3" json:"duration,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CompactCollectionReply) Reset()         { *m = CompactCollectionReply{} }
+func (m *CompactCollectionReply) String() string { return proto.CompactTextString(m) }
+func (*CompactCollectionReply) ProtoMessage()    {}
+func (*CompactCollectionReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{49}
+}
+
+func (m *CompactCollectionReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CompactCollectionReply.Unmarshal(m, b)
+}
+func (m *CompactCollectionReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CompactCollectionReply.Marshal(b, m, deterministic)
+}
+func (m *CompactCollectionReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CompactCollectionReply.Merge(m, src)
+}
+func (m *CompactCollectionReply) XXX_Size() int {
+	return xxx_messageInfo_CompactCollectionReply.Size(m)
+}
+func (m *CompactCollectionReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_CompactCollectionReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CompactCollectionReply proto.InternalMessageInfo
+
+func (m *CompactCollectionReply) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *CompactCollectionReply) GetSizeBefore() int64 {
+	if m != nil {
+		return m.SizeBefore
+	}
+	return 0
+}
+
+func (m *CompactCollectionReply) GetSizeAfter() int64 {
+	if m != nil {
+		return m.SizeAfter
+	}
+	return 0
+}
+
+func (m *CompactCollectionReply) GetDuration() int64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+type GetStorageReportRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetStorageReportRequest) Reset()         { *m = GetStorageReportRequest{} }
+func (m *GetStorageReportRequest) String() string { return proto.CompactTextString(m) }
+func (*GetStorageReportRequest) ProtoMessage()    {}
+func (*GetStorageReportRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{50}
+}
+
+func (m *GetStorageReportRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetStorageReportRequest.Unmarshal(m, b)
+}
+func (m *GetStorageReportRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetStorageReportRequest.Marshal(b, m, deterministic)
+}
+func (m *GetStorageReportRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetStorageReportRequest.Merge(m, src)
+}
+func (m *GetStorageReportRequest) XXX_Size() int {
+	return xxx_messageInfo_GetStorageReportRequest.Size(m)
+}
+func (m *GetStorageReportRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetStorageReportRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetStorageReportRequest proto.InternalMessageInfo
+
+func (m *GetStorageReportRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+// Levels are empty for engines which are not LSM trees, properties are
+// specific to engine.
+type GetStorageReportReply struct {
+	Collection           string            `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	Engine               string            `protobuf:"bytes,2,opt,name=engine,proto3" json:"engine,omitempty"`
+	DiskSize             int64             `protobuf:"varint,3,opt,name=disk_size,json=diskSize,proto3" json:"disk_size,omitempty"`
+	Levels               []*StorageLevel   `protobuf:"bytes,4,rep,name=levels,proto3" json:"levels,omitempty"`
+	WriteAmplification   float64           `protobuf:"fixed64,5,opt,name=write_amplification,json=writeAmplification,proto3" json:"write_amplification,omitempty"`
+	OpenTables           int64             `protobuf:"varint,6,opt,name=open_tables,json=openTables,proto3" json:"open_tables,omitempty"`
+	Properties           map[string]string `protobuf:"bytes,7,rep,name=properties,proto3" json:"properties,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *GetStorageReportReply) Reset()         { *m = GetStorageReportReply{} }
+func (m *GetStorageReportReply) String() string { return proto.CompactTextString(m) }
+func (*GetStorageReportReply) ProtoMessage()    {}
+func (*GetStorageReportReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{51}
+}
+
+func (m *GetStorageReportReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetStorageReportReply.Unmarshal(m, b)
+}
+func (m *GetStorageReportReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetStorageReportReply.Marshal(b, m, deterministic)
+}
+func (m *GetStorageReportReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetStorageReportReply.Merge(m, src)
+}
+func (m *GetStorageReportReply) XXX_Size() int {
+	return xxx_messageInfo_GetStorageReportReply.Size(m)
+}
+func (m *GetStorageReportReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetStorageReportReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetStorageReportReply proto.InternalMessageInfo
+
+func (m *GetStorageReportReply) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *GetStorageReportReply) GetEngine() string {
+	if m != nil {
+		return m.Engine
+	}
+	return ""
+}
+
+func (m *GetStorageReportReply) GetDiskSize() int64 {
+	if m != nil {
+		return m.DiskSize
+	}
+	return 0
+}
+
+func (m *GetStorageReportReply) GetLevels() []*StorageLevel {
+	if m != nil {
+		return m.Levels
+	}
+	return nil
+}
+
+func (m *GetStorageReportReply) GetWriteAmplification() float64 {
+	if m != nil {
+		return m.WriteAmplification
+	}
+	return 0
+}
+
+func (m *GetStorageReportReply) GetOpenTables() int64 {
+	if m != nil {
+		return m.OpenTables
+	}
+	return 0
+}
+
+func (m *GetStorageReportReply) GetProperties() map[string]string {
+	if m != nil {
+		return m.Properties
+	}
+	return nil
+}
+
+type StorageLevel struct {
+	Level                int32    `protobuf:"varint,1,opt,name=level,proto3" json:"level,omitempty"`
+	Tables               int64    `protobuf:"varint,2,opt,name=tables,proto3" json:"tables,omitempty"`
+	Size                 int64    `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StorageLevel) Reset()         { *m = StorageLevel{} }
+func (m *StorageLevel) String() string { return proto.CompactTextString(m) }
+func (*StorageLevel) ProtoMessage()    {}
+func (*StorageLevel) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{52}
+}
+
+func (m *StorageLevel) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StorageLevel.Unmarshal(m, b)
+}
+func (m *StorageLevel) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StorageLevel.Marshal(b, m, deterministic)
+}
+func (m *StorageLevel) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StorageLevel.Merge(m, src)
+}
+func (m *StorageLevel) XXX_Size() int {
+	return xxx_messageInfo_StorageLevel.Size(m)
+}
+func (m *StorageLevel) XXX_DiscardUnknown() {
+	xxx_messageInfo_StorageLevel.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StorageLevel proto.InternalMessageInfo
+
+func (m *StorageLevel) GetLevel() int32 {
+	if m != nil {
+		return m.Level
+	}
+	return 0
+}
+
+func (m *StorageLevel) GetTables() int64 {
+	if m != nil {
+		return m.Tables
+	}
+	return 0
+}
+
+func (m *StorageLevel) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+type GetCollectionStatusRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetCollectionStatusRequest) Reset()         { *m = GetCollectionStatusRequest{} }
+func (m *GetCollectionStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*GetCollectionStatusRequest) ProtoMessage()    {}
+func (*GetCollectionStatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{53}
+}
+
+func (m *GetCollectionStatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetCollectionStatusRequest.Unmarshal(m, b)
+}
+func (m *GetCollectionStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetCollectionStatusRequest.Marshal(b, m, deterministic)
+}
+func (m *GetCollectionStatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetCollectionStatusRequest.Merge(m, src)
+}
+func (m *GetCollectionStatusRequest) XXX_Size() int {
+	return xxx_messageInfo_GetCollectionStatusRequest.Size(m)
+}
+func (m *GetCollectionStatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetCollectionStatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetCollectionStatusRequest proto.InternalMessageInfo
+
+func (m *GetCollectionStatusRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+// State is ok, failed, rebuilding or frozen. Quarantine is where data of
+// failed collection was moved to.
+type GetCollectionStatusReply struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	State                string   `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`
+	Error                string   `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Quarantine           string   `protobuf:"bytes,4,opt,name=quarantine,proto3" json:"quarantine,omitempty"`
+	FailedAt             int64    `protobuf:"varint,5,opt,name=failed_at,json=failedAt,proto3" json:"failed_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetCollectionStatusReply) Reset()         { *m = GetCollectionStatusReply{} }
+func (m *GetCollectionStatusReply) String() string { return proto.CompactTextString(m) }
+func (*GetCollectionStatusReply) ProtoMessage()    {}
+func (*GetCollectionStatusReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{54}
+}
+
+func (m *GetCollectionStatusReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetCollectionStatusReply.Unmarshal(m, b)
+}
+func (m *GetCollectionStatusReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetCollectionStatusReply.Marshal(b, m, deterministic)
+}
+func (m *GetCollectionStatusReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetCollectionStatusReply.Merge(m, src)
+}
+func (m *GetCollectionStatusReply) XXX_Size() int {
+	return xxx_messageInfo_GetCollectionStatusReply.Size(m)
+}
+func (m *GetCollectionStatusReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetCollectionStatusReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetCollectionStatusReply proto.InternalMessageInfo
+
+func (m *GetCollectionStatusReply) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func (m *GetCollectionStatusReply) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *GetCollectionStatusReply) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *GetCollectionStatusReply) GetQuarantine() string {
+	if m != nil {
+		return m.Quarantine
+	}
+	return ""
+}
+
+func (m *GetCollectionStatusReply) GetFailedAt() int64 {
+	if m != nil {
+		return m.FailedAt
+	}
+	return 0
+}
+
+type RebuildCollectionRequest struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RebuildCollectionRequest) Reset()         { *m = RebuildCollectionRequest{} }
+func (m *RebuildCollectionRequest) String() string { return proto.CompactTextString(m) }
+func (*RebuildCollectionRequest) ProtoMessage()    {}
+func (*RebuildCollectionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{55}
+}
+
+func (m *RebuildCollectionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RebuildCollectionRequest.Unmarshal(m, b)
+}
+func (m *RebuildCollectionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RebuildCollectionRequest.Marshal(b, m, deterministic)
+}
+func (m *RebuildCollectionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RebuildCollectionRequest.Merge(m, src)
+}
+func (m *RebuildCollectionRequest) XXX_Size() int {
+	return xxx_messageInfo_RebuildCollectionRequest.Size(m)
+}
+func (m *RebuildCollectionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RebuildCollectionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RebuildCollectionRequest proto.InternalMessageInfo
+
+func (m *RebuildCollectionRequest) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+type RebuildCollectionReply struct {
+	Collection           string   `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RebuildCollectionReply) Reset()         { *m = RebuildCollectionReply{} }
+func (m *RebuildCollectionReply) String() string { return proto.CompactTextString(m) }
+func (*RebuildCollectionReply) ProtoMessage()    {}
+func (*RebuildCollectionReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_83c47b6a48ae8a41, []int{56}
+}
+
+func (m *RebuildCollectionReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RebuildCollectionReply.Unmarshal(m, b)
+}
+func (m *RebuildCollectionReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RebuildCollectionReply.Marshal(b, m, deterministic)
+}
+func (m *RebuildCollectionReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RebuildCollectionReply.Merge(m, src)
+}
+func (m *RebuildCollectionReply) XXX_Size() int {
+	return xxx_messageInfo_RebuildCollectionReply.Size(m)
+}
+func (m *RebuildCollectionReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_RebuildCollectionReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RebuildCollectionReply proto.InternalMessageInfo
+
+func (m *RebuildCollectionReply) GetCollection() string {
+	if m != nil {
+		return m.Collection
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*GetSnapshotStateRequest)(nil), "gravity.GetSnapshotStateRequest")
+	proto.RegisterType((*GetSnapshotStateReply)(nil), "gravity.GetSnapshotStateReply")
+	proto.RegisterType((*GetCollectionStatsRequest)(nil), "gravity.GetCollectionStatsRequest")
+	proto.RegisterType((*GetCollectionStatsReply)(nil), "gravity.GetCollectionStatsReply")
+	proto.RegisterType((*GetSnapshotRequest)(nil), "gravity.GetSnapshotRequest")
+	proto.RegisterType((*GetSnapshotDeltaRequest)(nil), "gravity.GetSnapshotDeltaRequest")
+	proto.RegisterType((*QueryIndexRequest)(nil), "gravity.QueryIndexRequest")
+	proto.RegisterType((*GetRecordHistoryRequest)(nil), "gravity.GetRecordHistoryRequest")
+	proto.RegisterType((*GetRecordHistoryReply)(nil), "gravity.GetRecordHistoryReply")
+	proto.RegisterType((*RecordVersion)(nil), "gravity.RecordVersion")
+	proto.RegisterType((*GetSnapshotAtRequest)(nil), "gravity.GetSnapshotAtRequest")
+	proto.RegisterType((*CreateCheckpointRequest)(nil), "gravity.CreateCheckpointRequest")
+	proto.RegisterType((*RenewCheckpointRequest)(nil), "gravity.RenewCheckpointRequest")
+	proto.RegisterType((*CheckpointReply)(nil), "gravity.CheckpointReply")
+	proto.RegisterType((*CheckpointCollection)(nil), "gravity.CheckpointCollection")
+	proto.RegisterType((*ReleaseCheckpointRequest)(nil), "gravity.ReleaseCheckpointRequest")
+	proto.RegisterType((*ReleaseCheckpointReply)(nil), "gravity.ReleaseCheckpointReply")
+	proto.RegisterType((*DescribeCollectionRequest)(nil), "gravity.DescribeCollectionRequest")
+	proto.RegisterType((*SchemaField)(nil), "gravity.SchemaField")
+	proto.RegisterType((*DescribeCollectionReply)(nil), "gravity.DescribeCollectionReply")
+	proto.RegisterType((*GetChecksumRequest)(nil), "gravity.GetChecksumRequest")
+	proto.RegisterType((*ChecksumNode)(nil), "gravity.ChecksumNode")
+	proto.RegisterType((*GetChecksumReply)(nil), "gravity.GetChecksumReply")
+	proto.RegisterType((*GetChecksumLeafRequest)(nil), "gravity.GetChecksumLeafRequest")
+	proto.RegisterType((*ListCollectionsRequest)(nil), "gravity.ListCollectionsRequest")
+	proto.RegisterType((*CollectionInfo)(nil), "gravity.CollectionInfo")
+	proto.RegisterType((*ListCollectionsReply)(nil), "gravity.ListCollectionsReply")
+	proto.RegisterType((*GetRecordRequest)(nil), "gravity.GetRecordRequest")
+	proto.RegisterType((*GetRecordReply)(nil), "gravity.GetRecordReply")
+	proto.RegisterType((*BatchGetRequest)(nil), "gravity.BatchGetRequest")
+	proto.RegisterType((*BatchGetReply)(nil), "gravity.BatchGetReply")
+	proto.RegisterType((*RecordLookup)(nil), "gravity.RecordLookup")
+	proto.RegisterType((*GetPartitionsRequest)(nil), "gravity.GetPartitionsRequest")
+	proto.RegisterType((*SnapshotPartition)(nil), "gravity.SnapshotPartition")
+	proto.RegisterType((*GetPartitionsReply)(nil), "gravity.GetPartitionsReply")
+	proto.RegisterType((*SnapshotPacket)(nil), "gravity.SnapshotPacket")
+	proto.RegisterType((*SnapshotEntry)(nil), "gravity.SnapshotEntry")
+	proto.RegisterType((*CreateBackupRequest)(nil), "gravity.CreateBackupRequest")
+	proto.RegisterType((*CreateBackupReply)(nil), "gravity.CreateBackupReply")
+	proto.RegisterType((*BackupCollection)(nil), "gravity.BackupCollection")
+	proto.RegisterType((*DropCollectionRequest)(nil), "gravity.DropCollectionRequest")
+	proto.RegisterType((*DropCollectionReply)(nil), "gravity.DropCollectionReply")
+	proto.RegisterType((*TruncateCollectionRequest)(nil), "gravity.TruncateCollectionRequest")
+	proto.RegisterType((*TruncateCollectionReply)(nil), "gravity.TruncateCollectionReply")
+	proto.RegisterType((*RenameCollectionRequest)(nil), "gravity.RenameCollectionRequest")
+	proto.RegisterType((*RenameCollectionReply)(nil), "gravity.RenameCollectionReply")
+	proto.RegisterType((*FreezeCollectionRequest)(nil), "gravity.FreezeCollectionRequest")
+	proto.RegisterType((*FreezeCollectionReply)(nil), "gravity.FreezeCollectionReply")
+	proto.RegisterType((*CompactCollectionRequest)(nil), "gravity.CompactCollectionRequest")
+	proto.RegisterType((*CompactCollectionReply)(nil), "gravity.CompactCollectionReply")
+	proto.RegisterType((*GetStorageReportRequest)(nil), "gravity.GetStorageReportRequest")
+	proto.RegisterType((*GetStorageReportReply)(nil), "gravity.GetStorageReportReply")
+	proto.RegisterMapType((map[string]string)(nil), "gravity.GetStorageReportReply.PropertiesEntry")
+	proto.RegisterType((*StorageLevel)(nil), "gravity.StorageLevel")
+	proto.RegisterType((*GetCollectionStatusRequest)(nil), "gravity.GetCollectionStatusRequest")
+	proto.RegisterType((*GetCollectionStatusReply)(nil), "gravity.GetCollectionStatusReply")
+	proto.RegisterType((*RebuildCollectionRequest)(nil), "gravity.RebuildCollectionRequest")
+	proto.RegisterType((*RebuildCollectionReply)(nil), "gravity.RebuildCollectionReply")
+}
+
+func init() { proto.RegisterFile("pb/data_snapshot.proto", fileDescriptor_83c47b6a48ae8a41) }
+
+var fileDescriptor_83c47b6a48ae8a41 = []byte{
+	// 2447 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x3a, 0x4b, 0x6f, 0x1b, 0xc9,
+	0xd1, 0x3b, 0xa4, 0x1e, 0x64, 0x91, 0xd4, 0xa3, 0x2d, 0x51, 0xd4, 0xd8, 0x92, 0xe8, 0xf9, 0xb0,
+	0x1f, 0x0c, 0xec, 0x46, 0x5e, 0x28, 0x08, 0xec, 0xf5, 0x26, 0x1b, 0xc8, 0x8f, 0x75, 0x76, 0xd7,
+	0x71, 0xe4, 0xa6, 0xd7, 0x40, 0x80, 0x45, 0x98, 0xd6, 0x4c, 0xd3, 0x9a, 0x68, 0x38, 0x33, 0xee,
+	0x69, 0xca, 0xa2, 0x93, 0x6b, 0x90, 0x3f, 0x90, 0x63, 0x6e, 0xb9, 0x06, 0xc9, 0x29, 0x97, 0xdc,
+	0xf2, 0x1b, 0x72, 0xc8, 0x29, 0xf7, 0xfc, 0x88, 0x20, 0x08, 0xba, 0x7b, 0x1e, 0x3d, 0x9c, 0x21,
+	0x3d, 0x5e, 0x6d, 0x90, 0xdb, 0x54, 0x75, 0x55, 0x75, 0x77, 0x75, 0xbd, 0x49, 0xe8, 0x86, 0xa7,
+	0xb7, 0x1d, 0xc2, 0xc9, 0x30, 0xf2, 0x49, 0x18, 0x9d, 0x05, 0xfc, 0x30, 0x64, 0x01, 0x0f, 0xd0,
+	0xea, 0x4b, 0x46, 0x2e, 0x5c, 0x3e, 0xb5, 0x3e, 0x86, 0x9d, 0xc7, 0x94, 0x0f, 0xe2, 0xd5, 0x01,
+	0x27, 0x9c, 0x62, 0xfa, 0x6a, 0x42, 0x23, 0x8e, 0xf6, 0x01, 0xec, 0xc0, 0xf3, 0xa8, 0xcd, 0xdd,
+	0xc0, 0xef, 0x19, 0x7d, 0xe3, 0x56, 0x13, 0x6b, 0x18, 0x6b, 0x00, 0xdb, 0x45, 0xd6, 0xd0, 0x9b,
+	0xbe, 0x8d, 0x11, 0x99, 0xd0, 0x88, 0xc4, 0x1e, 0xbe, 0x4d, 0x7b, 0xb5, 0xbe, 0x71, 0x6b, 0x09,
+	0xa7, 0xb0, 0xf5, 0x09, 0xec, 0x3e, 0xa6, 0xfc, 0x41, 0x4a, 0x2c, 0xc4, 0x46, 0x55, 0x4f, 0xf4,
+	0x8f, 0x1a, 0xec, 0x94, 0x71, 0x5f, 0xf1, 0x50, 0xe8, 0x26, 0xb4, 0x19, 0xb5, 0x03, 0xe6, 0x0c,
+	0xed, 0x60, 0xe2, 0xf3, 0x5e, 0x5d, 0xae, 0xb7, 0x14, 0xee, 0x81, 0x40, 0xa1, 0x03, 0x68, 0xf1,
+	0x80, 0x13, 0x6f, 0x78, 0x3a, 0xe5, 0x34, 0xea, 0x2d, 0x49, 0x0a, 0x90, 0xa8, 0xfb, 0x02, 0x23,
+	0x64, 0x78, 0x24, 0xe2, 0xc3, 0x49, 0xe8, 0x10, 0x4e, 0x9d, 0xde, 0x72, 0xdf, 0xb8, 0x55, 0xc7,
+	0x2d, 0x81, 0xfb, 0x4a, 0xa1, 0x84, 0x8c, 0x31, 0xb9, 0x1c, 0x2a, 0xb1, 0x51, 0x6f, 0x45, 0xc9,
+	0x18, 0x93, 0x4b, 0xac, 0x30, 0xe8, 0x3a, 0x34, 0x05, 0x81, 0xda, 0x62, 0x55, 0x1d, 0x72, 0x4c,
+	0x2e, 0xd5, 0x06, 0xff, 0x0f, 0xeb, 0x19, 0xf7, 0x30, 0x72, 0xdf, 0xd0, 0x5e, 0x43, 0x92, 0x74,
+	0x52, 0x09, 0x03, 0xf7, 0x8d, 0xbc, 0xcc, 0xab, 0x49, 0xc0, 0xc9, 0x30, 0x0c, 0x3c, 0xd7, 0x9e,
+	0xf6, 0x9a, 0x52, 0x15, 0x2d, 0x89, 0x3b, 0x91, 0x28, 0xd4, 0x85, 0x95, 0x11, 0x0b, 0xde, 0x50,
+	0xbf, 0x07, 0x7d, 0xe3, 0x56, 0x03, 0xc7, 0x90, 0xf5, 0xf7, 0x1a, 0x20, 0xed, 0xc9, 0x2b, 0x3e,
+	0x0b, 0xfa, 0x00, 0x36, 0x89, 0x6d, 0xd3, 0x90, 0x0f, 0xed, 0x60, 0x1c, 0x32, 0x1a, 0x45, 0xd4,
+	0x91, 0x3a, 0x6e, 0xe0, 0x0d, 0xb5, 0xf0, 0x20, 0xc5, 0x4b, 0x61, 0x67, 0xd4, 0x3e, 0x0f, 0x03,
+	0x37, 0xd6, 0x74, 0x13, 0x6b, 0x18, 0x79, 0x36, 0xd7, 0xe3, 0x94, 0x49, 0x1d, 0x37, 0x71, 0x0c,
+	0xa1, 0xf7, 0x61, 0xcd, 0xf5, 0x6d, 0x6f, 0xe2, 0xd0, 0xe1, 0xc8, 0xa5, 0x9e, 0x13, 0xf5, 0x96,
+	0xfb, 0xf5, 0x5b, 0x4d, 0xdc, 0x89, 0xb1, 0x9f, 0x49, 0xa4, 0x20, 0xa3, 0x97, 0x39, 0xb2, 0x15,
+	0x45, 0x46, 0x2f, 0x75, 0xb2, 0x2e, 0xac, 0xd8, 0x13, 0x16, 0x05, 0x4c, 0xaa, 0xb9, 0x8d, 0x63,
+	0x08, 0xdd, 0x80, 0x26, 0xa3, 0xd1, 0x64, 0x4c, 0x4e, 0x3d, 0xa5, 0xde, 0x06, 0xce, 0x10, 0xe8,
+	0x2e, 0x34, 0x43, 0xc2, 0xb8, 0x2b, 0xf5, 0x20, 0xf4, 0xda, 0x3a, 0x32, 0x0f, 0x63, 0x4f, 0x3b,
+	0x4c, 0xb4, 0x76, 0x92, 0x50, 0xe0, 0x8c, 0xd8, 0xfa, 0x79, 0xce, 0x0d, 0x1f, 0x52, 0x8f, 0x93,
+	0xaa, 0xda, 0x7d, 0x1f, 0xd6, 0x22, 0xd7, 0xb7, 0xe9, 0x70, 0xc6, 0x7c, 0x3b, 0x12, 0x3b, 0x48,
+	0x1c, 0xeb, 0xcf, 0x06, 0x6c, 0x3e, 0x9b, 0x50, 0x36, 0xfd, 0xdc, 0x77, 0xe8, 0x65, 0x55, 0xe1,
+	0x5b, 0xb0, 0xec, 0x0a, 0x7a, 0x29, 0xb3, 0x89, 0x15, 0x20, 0xb4, 0x73, 0x41, 0xbc, 0x09, 0x8d,
+	0x7a, 0xf5, 0x7e, 0x5d, 0x68, 0x47, 0x41, 0xc2, 0x80, 0x19, 0xf1, 0x5f, 0xd2, 0x61, 0xc4, 0x09,
+	0xe3, 0xf2, 0x81, 0xda, 0x18, 0x24, 0x6a, 0x20, 0x30, 0xc2, 0x80, 0x15, 0x01, 0xf5, 0x95, 0x07,
+	0xb4, 0x71, 0x43, 0x22, 0x1e, 0xf9, 0x8e, 0xd8, 0xcb, 0x73, 0xc7, 0x2e, 0x97, 0x86, 0xdf, 0xc1,
+	0x0a, 0xb0, 0xbe, 0x94, 0x9a, 0x51, 0xf6, 0xfb, 0x23, 0x37, 0xe2, 0x01, 0x9b, 0x56, 0x3d, 0xfc,
+	0x06, 0xd4, 0xcf, 0xe9, 0x54, 0x1e, 0xbd, 0x8d, 0xc5, 0xa7, 0xf5, 0x1b, 0x03, 0xb6, 0x8b, 0xd2,
+	0xae, 0x1a, 0x1e, 0x8e, 0xa0, 0x71, 0x41, 0x59, 0xe4, 0x06, 0xbe, 0x52, 0x48, 0xeb, 0xa8, 0x9b,
+	0xbe, 0xba, 0xda, 0xea, 0x85, 0x5a, 0xc6, 0x29, 0x9d, 0x75, 0x09, 0x9d, 0xdc, 0x52, 0x6e, 0x03,
+	0x63, 0x66, 0x83, 0x3d, 0x80, 0x38, 0x6c, 0x0c, 0x09, 0x97, 0xdb, 0xd7, 0x71, 0x33, 0xc6, 0x1c,
+	0x73, 0xd4, 0x83, 0x55, 0x87, 0x7a, 0x54, 0x44, 0x95, 0xba, 0x34, 0xc9, 0x04, 0x44, 0x08, 0x96,
+	0x44, 0xf4, 0x8f, 0x5f, 0x42, 0x7e, 0x5b, 0x18, 0xb6, 0x34, 0x53, 0x3b, 0xae, 0xec, 0xc5, 0x8b,
+	0xa2, 0x36, 0x81, 0x9d, 0x07, 0x8c, 0x12, 0x4e, 0x1f, 0xa4, 0x8e, 0x9a, 0x88, 0x45, 0xb0, 0xe4,
+	0x93, 0x31, 0x8d, 0x05, 0xca, 0x6f, 0xd4, 0x87, 0x56, 0x26, 0x38, 0xea, 0xd5, 0xa4, 0x07, 0xea,
+	0x28, 0xf1, 0x74, 0x9c, 0x7b, 0x71, 0xa0, 0x15, 0x9f, 0xd6, 0xa7, 0xd0, 0xc5, 0xd4, 0xa7, 0xaf,
+	0xab, 0xed, 0x10, 0xf3, 0xd7, 0x32, 0xfe, 0x5f, 0x1b, 0xb0, 0xae, 0xf3, 0x8a, 0x47, 0x2f, 0xe3,
+	0xdc, 0x03, 0xa0, 0x97, 0xa1, 0xcb, 0x68, 0xa4, 0xe9, 0x3a, 0xc6, 0x1c, 0x73, 0xf4, 0xc3, 0xfc,
+	0xd1, 0xd5, 0x73, 0xef, 0xa5, 0xcf, 0x9d, 0xed, 0x90, 0x25, 0xa1, 0xdc, 0xcd, 0x84, 0xfa, 0xcb,
+	0x88, 0xae, 0xa4, 0xfe, 0x43, 0xe8, 0x61, 0xea, 0x51, 0x12, 0x55, 0xd3, 0xbf, 0xf5, 0x21, 0x74,
+	0x4b, 0xe8, 0xe7, 0x68, 0xc4, 0x1a, 0xc0, 0xee, 0x43, 0x1a, 0xd9, 0xcc, 0x3d, 0xa5, 0xda, 0xa5,
+	0x2a, 0x5a, 0x8d, 0x08, 0xd7, 0x01, 0x1b, 0xc7, 0xaa, 0x6c, 0xe2, 0x18, 0xb2, 0xfe, 0x6a, 0x40,
+	0x6b, 0x60, 0x9f, 0xd1, 0x31, 0x91, 0x11, 0xb7, 0xf4, 0x29, 0xba, 0xb0, 0xe2, 0x4f, 0xc6, 0xa7,
+	0x94, 0x49, 0xde, 0x65, 0x1c, 0x43, 0x22, 0x50, 0xf0, 0x69, 0x18, 0x47, 0x9f, 0x26, 0x56, 0x80,
+	0x50, 0x90, 0x3f, 0xf1, 0x3c, 0x19, 0x99, 0x97, 0xa4, 0x1b, 0xa4, 0xb0, 0xf0, 0x90, 0x90, 0xb9,
+	0x63, 0xc2, 0xa6, 0x32, 0xea, 0x34, 0x70, 0x02, 0x8a, 0xe7, 0x1e, 0xb9, 0x2c, 0xe2, 0xc3, 0x88,
+	0x52, 0x3f, 0x4e, 0xb9, 0x4d, 0x89, 0x19, 0x50, 0xea, 0x8b, 0x80, 0xe5, 0x91, 0x64, 0x35, 0xce,
+	0xb8, 0x1e, 0x51, 0x8b, 0xd6, 0x9f, 0x0c, 0xd8, 0x29, 0xd3, 0x4c, 0x95, 0x78, 0xf2, 0xa1, 0x48,
+	0x63, 0x32, 0xff, 0xd4, 0xa4, 0x09, 0x6d, 0x65, 0x79, 0x22, 0xd3, 0x0a, 0x8e, 0x69, 0x44, 0x60,
+	0xfd, 0x45, 0x14, 0xf8, 0xc3, 0x48, 0xae, 0x25, 0x59, 0x51, 0xa0, 0x14, 0xb5, 0x48, 0x02, 0x8e,
+	0x3c, 0x49, 0xc8, 0x03, 0x36, 0x8c, 0x68, 0x12, 0x7c, 0x3b, 0x19, 0x76, 0x40, 0xb9, 0xf5, 0x3b,
+	0x43, 0x26, 0x70, 0xf9, 0xea, 0xd1, 0x64, 0x5c, 0xf5, 0x11, 0xf3, 0x39, 0xb9, 0x56, 0xc8, 0xc9,
+	0xba, 0x6d, 0xd6, 0x67, 0x62, 0x97, 0x88, 0xea, 0xf4, 0x82, 0x7a, 0xbd, 0xa5, 0x38, 0xaa, 0x0b,
+	0x40, 0x60, 0xfd, 0xc0, 0xa1, 0x2a, 0x49, 0x77, 0xb0, 0x02, 0x2c, 0x07, 0xda, 0xc9, 0xd1, 0x9e,
+	0x06, 0x8e, 0xc6, 0x6b, 0xcc, 0xf0, 0x66, 0x39, 0xa9, 0x93, 0xe4, 0x24, 0x04, 0x4b, 0x67, 0x24,
+	0x3a, 0x93, 0xfb, 0xb7, 0xb1, 0xfc, 0x16, 0x94, 0xaa, 0x60, 0x53, 0xe5, 0x98, 0x02, 0xac, 0xbf,
+	0x19, 0xb0, 0x91, 0x53, 0xc2, 0x55, 0xe3, 0x3f, 0x82, 0x25, 0x16, 0x04, 0x3c, 0xd9, 0x5a, 0x7c,
+	0x97, 0x6f, 0x8d, 0x3e, 0xd0, 0xaf, 0xdd, 0x3a, 0xda, 0xce, 0xc7, 0x8d, 0xf8, 0xda, 0xb1, 0x36,
+	0x84, 0x08, 0x87, 0x86, 0xfc, 0x2c, 0xc9, 0x87, 0x12, 0x90, 0x0e, 0x45, 0xfc, 0x60, 0xc2, 0xa5,
+	0x39, 0x76, 0x70, 0x0c, 0x59, 0x1e, 0x74, 0xb5, 0x4b, 0x3d, 0xa1, 0x64, 0xf4, 0x6d, 0xbd, 0x2e,
+	0x82, 0x25, 0x8f, 0x92, 0x91, 0xbc, 0x5e, 0x07, 0xcb, 0x6f, 0xeb, 0x08, 0xba, 0x4f, 0xdc, 0x48,
+	0x8b, 0x5f, 0x69, 0x8d, 0x2e, 0x5c, 0x8d, 0x70, 0x4e, 0x59, 0xb2, 0x55, 0x02, 0x5a, 0xff, 0x36,
+	0x60, 0x2d, 0x63, 0xf8, 0xdc, 0x1f, 0x05, 0xff, 0xf3, 0xa2, 0x7c, 0x0f, 0xc0, 0x66, 0x34, 0x49,
+	0xac, 0xaa, 0x24, 0x6f, 0xc6, 0x98, 0x63, 0x5e, 0xa8, 0xd9, 0x57, 0x8a, 0x35, 0xfb, 0x16, 0x2c,
+	0x47, 0x9c, 0x70, 0x2a, 0x5f, 0xa3, 0x89, 0x15, 0x20, 0xb0, 0x94, 0xb1, 0x80, 0xc9, 0x12, 0xb1,
+	0x89, 0x15, 0x60, 0x3d, 0x83, 0xad, 0x82, 0xd2, 0x84, 0xed, 0x7d, 0x9c, 0xcf, 0x29, 0x86, 0xb4,
+	0x8d, 0x9d, 0xcc, 0x36, 0x72, 0x3a, 0xcb, 0x67, 0x13, 0x47, 0x9a, 0xb2, 0xaa, 0x24, 0xbe, 0x71,
+	0x59, 0xf4, 0xb6, 0x9a, 0xdb, 0xba, 0x80, 0x35, 0x6d, 0x97, 0xab, 0xba, 0xcb, 0x16, 0x2c, 0x8f,
+	0x82, 0x89, 0x9f, 0x14, 0x2b, 0x0a, 0x28, 0x2d, 0x55, 0x28, 0xac, 0xdf, 0x27, 0xdc, 0x3e, 0x93,
+	0x9b, 0x57, 0xbb, 0x1c, 0x82, 0xa5, 0x73, 0x3a, 0x55, 0x51, 0xb5, 0x8d, 0xe5, 0xf7, 0x5b, 0xaf,
+	0xf7, 0x2b, 0xe8, 0x64, 0xdb, 0x5c, 0xf5, 0x76, 0xb7, 0x61, 0x35, 0x69, 0xe0, 0xea, 0x33, 0x4e,
+	0xae, 0x14, 0xf8, 0x24, 0x08, 0xce, 0x27, 0x21, 0x4e, 0xa8, 0xac, 0x2f, 0xa0, 0xad, 0x2f, 0x24,
+	0xcf, 0x63, 0x64, 0xcf, 0x93, 0x2a, 0xac, 0x56, 0xa6, 0xb0, 0xba, 0xa6, 0x30, 0x4f, 0xd6, 0x76,
+	0x69, 0x87, 0x11, 0x7d, 0x5b, 0x21, 0x20, 0x8d, 0x66, 0x2a, 0x06, 0x28, 0xc0, 0xfa, 0x19, 0x6c,
+	0x16, 0x9a, 0x9a, 0xd8, 0x21, 0x18, 0x8f, 0x2f, 0xa0, 0x00, 0x71, 0x29, 0x1a, 0x5f, 0xa0, 0x8d,
+	0xc5, 0x67, 0x05, 0xf7, 0xb5, 0xfe, 0xa0, 0xb2, 0x95, 0x7e, 0x9d, 0x2a, 0xaf, 0x73, 0x95, 0x6c,
+	0x75, 0x0f, 0x20, 0x6d, 0xca, 0x44, 0xc0, 0xa8, 0xbf, 0xa5, 0x85, 0xd3, 0xa8, 0xad, 0xbf, 0x18,
+	0xb0, 0x96, 0x51, 0xd8, 0xe7, 0xf4, 0x4a, 0x35, 0x35, 0xfa, 0x08, 0x56, 0xa9, 0xcf, 0x99, 0x4b,
+	0x8b, 0x4d, 0x45, 0xb2, 0xcb, 0x23, 0x9f, 0xb3, 0x29, 0x4e, 0xc8, 0x90, 0x05, 0x6d, 0xc7, 0x95,
+	0x82, 0x89, 0x64, 0x5b, 0x92, 0x3e, 0x90, 0xc3, 0x69, 0x8d, 0xed, 0xb2, 0xde, 0xd8, 0x5a, 0xbf,
+	0x84, 0x4e, 0x4e, 0x6a, 0x6a, 0x5e, 0x46, 0x66, 0x5e, 0x0b, 0x8f, 0x3b, 0xbf, 0x09, 0x91, 0xd5,
+	0xbe, 0xea, 0xef, 0x85, 0x16, 0x54, 0xdb, 0xae, 0xa3, 0xac, 0x3b, 0x70, 0x4d, 0xb5, 0x0f, 0xf7,
+	0x89, 0x2d, 0x7c, 0x23, 0xb6, 0xda, 0x7e, 0x31, 0x2e, 0x36, 0x67, 0xc3, 0xdf, 0x66, 0x9e, 0x31,
+	0xae, 0x61, 0x43, 0xc2, 0xcf, 0x92, 0x52, 0x52, 0x7c, 0xa3, 0x4f, 0x8a, 0x1d, 0x47, 0xeb, 0x68,
+	0x37, 0x55, 0xa8, 0x62, 0x9f, 0x57, 0xb2, 0xbf, 0x82, 0x8d, 0x59, 0x82, 0xff, 0x72, 0xe6, 0xb2,
+	0xee, 0xc0, 0xf6, 0x43, 0x16, 0x84, 0xef, 0x5c, 0x6f, 0x5b, 0xdf, 0x83, 0x6b, 0xb3, 0x8c, 0x15,
+	0x7c, 0x46, 0x8c, 0xdd, 0x9e, 0xb3, 0x89, 0x6f, 0x8b, 0x16, 0xee, 0x9d, 0xf7, 0xfc, 0x0a, 0x76,
+	0xca, 0x98, 0xaf, 0x3a, 0x0a, 0x7c, 0x0e, 0x3b, 0x98, 0x8a, 0x46, 0xe0, 0xdd, 0xbb, 0x8e, 0x5d,
+	0x68, 0xf8, 0xf4, 0xf5, 0x50, 0x30, 0xc7, 0x01, 0x60, 0xd5, 0xa7, 0xaf, 0x9f, 0x8a, 0x6e, 0xe6,
+	0x0e, 0x6c, 0x17, 0xa5, 0x56, 0x51, 0xd1, 0x33, 0xd8, 0xf9, 0x8c, 0x51, 0xfa, 0xe6, 0x1b, 0x36,
+	0x41, 0x6a, 0x9e, 0x56, 0xcb, 0xcd, 0xd3, 0x2e, 0x60, 0xbb, 0x28, 0xb2, 0x8a, 0xda, 0xe6, 0x08,
+	0x14, 0x6d, 0x40, 0x48, 0x7d, 0xc7, 0xf5, 0x5f, 0x0e, 0xe9, 0x05, 0xf5, 0x79, 0x14, 0xdb, 0x56,
+	0x27, 0xc6, 0x3e, 0x92, 0x48, 0x6b, 0x04, 0x3d, 0x31, 0x71, 0x23, 0x36, 0x7f, 0xf7, 0xbb, 0xa4,
+	0xf1, 0xbd, 0xa6, 0xc7, 0xf7, 0x74, 0x76, 0xa3, 0xb2, 0x91, 0x02, 0xac, 0xdf, 0x1a, 0xd0, 0x2d,
+	0xd9, 0xa8, 0xca, 0x0d, 0x0f, 0xa0, 0x25, 0x46, 0x98, 0xc3, 0x53, 0x3a, 0x0a, 0x18, 0x8d, 0xfb,
+	0x70, 0x10, 0xa8, 0xfb, 0x12, 0x23, 0x4a, 0x37, 0x49, 0x40, 0x46, 0x9c, 0x32, 0xb9, 0x6d, 0x1d,
+	0x37, 0x05, 0xe6, 0x58, 0x20, 0x84, 0x61, 0x39, 0x13, 0x46, 0x78, 0x12, 0x71, 0xea, 0x38, 0x85,
+	0x93, 0x99, 0x37, 0x0f, 0x18, 0x79, 0x49, 0x31, 0x0d, 0x03, 0x56, 0xb5, 0xbc, 0xb0, 0xfe, 0x55,
+	0x83, 0xed, 0x22, 0x6f, 0xc5, 0x27, 0xa3, 0xfe, 0x4b, 0xd7, 0x4f, 0x0c, 0x32, 0x86, 0x44, 0x87,
+	0xe9, 0xb8, 0xd1, 0xb9, 0x1a, 0xd8, 0xd6, 0xe3, 0x93, 0xba, 0xd1, 0xb9, 0x9c, 0xd5, 0x7e, 0x07,
+	0x56, 0x64, 0xcf, 0x93, 0xa4, 0xa2, 0xac, 0x96, 0x88, 0x4f, 0xf0, 0x44, 0xac, 0xe2, 0x98, 0x08,
+	0xdd, 0x86, 0x6b, 0xaf, 0x99, 0xcb, 0xe9, 0x90, 0x8c, 0x43, 0xcf, 0x1d, 0xb9, 0xb6, 0xba, 0xbf,
+	0x88, 0xf4, 0x06, 0x46, 0x72, 0xe9, 0x58, 0x5f, 0x11, 0x5a, 0x0e, 0x42, 0xea, 0x0f, 0xb9, 0xe8,
+	0x92, 0xa3, 0xb8, 0xbe, 0x05, 0x81, 0x7a, 0x2e, 0x31, 0xe8, 0x29, 0x40, 0xc8, 0x82, 0x90, 0x32,
+	0xee, 0xca, 0x91, 0xb3, 0x38, 0xc4, 0x61, 0x7a, 0x88, 0x52, 0x4d, 0x1c, 0x9e, 0xa4, 0x0c, 0x2a,
+	0x3f, 0x69, 0x12, 0xcc, 0x1f, 0xc0, 0xfa, 0xcc, 0xb2, 0x5e, 0xef, 0x34, 0xd3, 0x7a, 0x47, 0x0e,
+	0x14, 0x93, 0xa1, 0xa3, 0x04, 0xee, 0xd5, 0xee, 0x1a, 0xd6, 0x09, 0xb4, 0xf5, 0x8b, 0xe7, 0x1b,
+	0xc4, 0xe5, 0xa4, 0x41, 0xec, 0xc2, 0x4a, 0x7c, 0x21, 0x65, 0x36, 0x31, 0x24, 0x12, 0x83, 0xa6,
+	0x65, 0xf9, 0x6d, 0x7d, 0x1f, 0xcc, 0xc2, 0x2f, 0x06, 0x93, 0xca, 0x3f, 0x38, 0xfc, 0xde, 0x80,
+	0x5e, 0x29, 0x7b, 0x15, 0x8b, 0x48, 0x5b, 0x87, 0x5a, 0x69, 0xeb, 0x50, 0xd7, 0x5a, 0x07, 0x21,
+	0xeb, 0xd5, 0x84, 0x30, 0xe2, 0x73, 0x61, 0x41, 0x2a, 0x85, 0x6a, 0x18, 0x61, 0x45, 0x23, 0xe2,
+	0x7a, 0x7a, 0x1f, 0xd3, 0x50, 0x88, 0x63, 0x6e, 0xdd, 0x13, 0xe3, 0xa1, 0xd3, 0x89, 0xeb, 0x39,
+	0xef, 0x1e, 0xdb, 0xef, 0x42, 0xb7, 0x84, 0xb7, 0xc2, 0xf5, 0x8e, 0xfe, 0x09, 0xd0, 0x7e, 0x48,
+	0x38, 0x49, 0xca, 0x0a, 0xf4, 0x42, 0xf6, 0x2a, 0xb9, 0xdf, 0x8b, 0x50, 0x3f, 0x67, 0x4b, 0x25,
+	0xbf, 0x42, 0x99, 0xfb, 0x0b, 0x28, 0x42, 0x6f, 0x6a, 0xbd, 0x87, 0x1e, 0x43, 0x4b, 0x5b, 0x42,
+	0xd7, 0xcb, 0x18, 0x12, 0x69, 0x3b, 0x25, 0xb5, 0x9c, 0xa8, 0xd4, 0xac, 0xf7, 0x3e, 0x32, 0xd0,
+	0x33, 0xd8, 0x98, 0x1d, 0xc2, 0x97, 0x1f, 0x50, 0x9f, 0xcf, 0x2f, 0x16, 0xf9, 0x08, 0x20, 0x1b,
+	0xba, 0xa3, 0xac, 0x92, 0x2c, 0x4c, 0xe2, 0x17, 0x8b, 0xf9, 0x5a, 0x8d, 0x6d, 0xf2, 0xbf, 0x6b,
+	0x21, 0x4b, 0x3f, 0x5b, 0xf9, 0x4f, 0x66, 0x66, 0x7f, 0x21, 0x8d, 0x52, 0xe0, 0x0b, 0xd8, 0x98,
+	0x1d, 0x8a, 0xe7, 0xef, 0x5d, 0x36, 0x7d, 0x37, 0xf7, 0x17, 0x50, 0x28, 0xb9, 0x5f, 0x42, 0x27,
+	0x37, 0x69, 0x46, 0x7b, 0x65, 0xca, 0x3c, 0xae, 0xf6, 0x38, 0x27, 0xb0, 0x31, 0x3b, 0x62, 0xd6,
+	0x0e, 0x39, 0x67, 0xfa, 0x6c, 0xf6, 0x4a, 0x26, 0xb3, 0xc9, 0xf1, 0x9e, 0xc2, 0xfa, 0xcc, 0x44,
+	0x19, 0x1d, 0x68, 0xbd, 0x5a, 0xd9, 0xac, 0x79, 0xa1, 0xbc, 0x9f, 0xc2, 0x66, 0x61, 0xaa, 0x8a,
+	0x6e, 0x6a, 0x12, 0xcb, 0x27, 0xb4, 0xe6, 0xc1, 0x22, 0x12, 0x25, 0xfa, 0x6b, 0x40, 0xc5, 0x41,
+	0xa3, 0xf6, 0xfe, 0x73, 0xe7, 0xb3, 0x66, 0x7f, 0x21, 0x8d, 0xee, 0x40, 0xc9, 0xe8, 0x28, 0xef,
+	0x40, 0x33, 0xa3, 0x42, 0x73, 0xb7, 0x7c, 0x51, 0x09, 0xfa, 0x09, 0xac, 0xcf, 0xcc, 0xa0, 0x34,
+	0x8d, 0x96, 0x4f, 0xa7, 0x16, 0x3f, 0xfa, 0x00, 0xd6, 0x67, 0x26, 0x26, 0x9a, 0xc0, 0xf2, 0x01,
+	0x94, 0xb9, 0x37, 0x9f, 0x40, 0x9d, 0xf2, 0x18, 0x9a, 0xa9, 0xc5, 0xa2, 0xdd, 0xa2, 0x15, 0x17,
+	0x4f, 0x96, 0x1f, 0x7e, 0x58, 0xef, 0xa1, 0x4f, 0xa1, 0x91, 0x4c, 0x0c, 0x50, 0x4f, 0xeb, 0x22,
+	0x72, 0xb3, 0x0a, 0xb3, 0x5b, 0xb2, 0xa2, 0xf8, 0x7f, 0x2c, 0x3d, 0x23, 0x6b, 0x6c, 0xf3, 0x9e,
+	0x51, 0xe8, 0xdf, 0xcd, 0xeb, 0xf3, 0x96, 0xa5, 0xb8, 0xa3, 0x3f, 0xae, 0xc0, 0xa6, 0x1e, 0x6a,
+	0x8f, 0x9d, 0xb1, 0xeb, 0xa3, 0x2f, 0xa0, 0xad, 0x37, 0x47, 0xe8, 0xc6, 0x8c, 0xb7, 0xe4, 0x9a,
+	0x2d, 0xd3, 0x9c, 0xb3, 0xaa, 0x0e, 0x7c, 0x02, 0x6b, 0xf9, 0xb6, 0x02, 0x65, 0xee, 0x5f, 0xda,
+	0xa8, 0x98, 0x37, 0xe6, 0xae, 0xa7, 0x26, 0x5d, 0x6c, 0x1a, 0x34, 0x93, 0x9e, 0xdb, 0x8e, 0x98,
+	0xfd, 0x85, 0x34, 0x69, 0x48, 0x9b, 0xad, 0xf2, 0xb5, 0x68, 0x31, 0xa7, 0xad, 0x30, 0xf7, 0x17,
+	0x50, 0xa4, 0x72, 0x67, 0x2b, 0x76, 0x4d, 0xee, 0x9c, 0xfe, 0xc0, 0xdc, 0x5f, 0x40, 0x91, 0xc6,
+	0x8e, 0x42, 0xa1, 0xac, 0xc5, 0x8e, 0x79, 0xd5, 0xba, 0x79, 0xb0, 0x88, 0x44, 0x8f, 0xee, 0xb9,
+	0x3a, 0x6d, 0x26, 0xab, 0x95, 0x14, 0xc2, 0xe6, 0xfe, 0x02, 0x0a, 0x25, 0x77, 0x08, 0xd7, 0x4a,
+	0x4a, 0x1f, 0xf4, 0x7f, 0xf3, 0x13, 0x4e, 0x5a, 0x57, 0x99, 0x37, 0x17, 0x13, 0x69, 0xf1, 0x74,
+	0xa6, 0xf4, 0xc8, 0xc5, 0xd3, 0xf2, 0x92, 0xc6, 0x3c, 0x58, 0x44, 0x22, 0x45, 0x9f, 0xae, 0xc8,
+	0x7f, 0xc1, 0x7c, 0xf7, 0x3f, 0x03, 0x00, 0xbe, 0xae, 0x55, 0x6b, 0x1f, 0x23, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// DataSnapshotClient is the client API for DataSnapshot service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type DataSnapshotClient interface {
+	GetSnapshotState(ctx context.Context, in *GetSnapshotStateRequest, opts ...grpc.CallOption) (*GetSnapshotStateReply, error)
+	GetSnapshot(ctx context.Context, in *GetSnapshotRequest, opts ...grpc.CallOption) (DataSnapshot_GetSnapshotClient, error)
+	GetSnapshotDelta(ctx context.Context, in *GetSnapshotDeltaRequest, opts ...grpc.CallOption) (DataSnapshot_GetSnapshotDeltaClient, error)
+	QueryIndex(ctx context.Context, in *QueryIndexRequest, opts ...grpc.CallOption) (DataSnapshot_QueryIndexClient, error)
+	GetCollectionStats(ctx context.Context, in *GetCollectionStatsRequest, opts ...grpc.CallOption) (*GetCollectionStatsReply, error)
+	GetRecordHistory(ctx context.Context, in *GetRecordHistoryRequest, opts ...grpc.CallOption) (*GetRecordHistoryReply, error)
+	GetSnapshotAt(ctx context.Context, in *GetSnapshotAtRequest, opts ...grpc.CallOption) (DataSnapshot_GetSnapshotAtClient, error)
+	CreateCheckpoint(ctx context.Context, in *CreateCheckpointRequest, opts ...grpc.CallOption) (*CheckpointReply, error)
+	RenewCheckpoint(ctx context.Context, in *RenewCheckpointRequest, opts ...grpc.CallOption) (*CheckpointReply, error)
+	ReleaseCheckpoint(ctx context.Context, in *ReleaseCheckpointRequest, opts ...grpc.CallOption) (*ReleaseCheckpointReply, error)
+	DescribeCollection(ctx context.Context, in *DescribeCollectionRequest, opts ...grpc.CallOption) (*DescribeCollectionReply, error)
+	GetChecksum(ctx context.Context, in *GetChecksumRequest, opts ...grpc.CallOption) (*GetChecksumReply, error)
+	GetChecksumLeaf(ctx context.Context, in *GetChecksumLeafRequest, opts ...grpc.CallOption) (DataSnapshot_GetChecksumLeafClient, error)
+	ListCollections(ctx context.Context, in *ListCollectionsRequest, opts ...grpc.CallOption) (*ListCollectionsReply, error)
+	GetRecord(ctx context.Context, in *GetRecordRequest, opts ...grpc.CallOption) (*GetRecordReply, error)
+	BatchGet(ctx context.Context, in *BatchGetRequest, opts ...grpc.CallOption) (*BatchGetReply, error)
+	GetPartitions(ctx context.Context, in *GetPartitionsRequest, opts ...grpc.CallOption) (*GetPartitionsReply, error)
+}
+
+type dataSnapshotClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewDataSnapshotClient(cc *grpc.ClientConn) DataSnapshotClient {
+	return &dataSnapshotClient{cc}
+}
+
+func (c *dataSnapshotClient) GetSnapshotState(ctx context.Context, in *GetSnapshotStateRequest, opts ...grpc.CallOption) (*GetSnapshotStateReply, error) {
+	out := new(GetSnapshotStateReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshot/GetSnapshotState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotClient) GetSnapshot(ctx context.Context, in *GetSnapshotRequest, opts ...grpc.CallOption) (DataSnapshot_GetSnapshotClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_DataSnapshot_serviceDesc.Streams[0], "/gravity.DataSnapshot/GetSnapshot", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dataSnapshotGetSnapshotClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DataSnapshot_GetSnapshotClient interface {
+	Recv() (*SnapshotPacket, error)
+	grpc.ClientStream
+}
+
+type dataSnapshotGetSnapshotClient struct {
+	grpc.ClientStream
+}
+
+func (x *dataSnapshotGetSnapshotClient) Recv() (*SnapshotPacket, error) {
+	m := new(SnapshotPacket)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *dataSnapshotClient) GetSnapshotDelta(ctx context.Context, in *GetSnapshotDeltaRequest, opts ...grpc.CallOption) (DataSnapshot_GetSnapshotDeltaClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_DataSnapshot_serviceDesc.Streams[1], "/gravity.DataSnapshot/GetSnapshotDelta", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dataSnapshotGetSnapshotDeltaClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DataSnapshot_GetSnapshotDeltaClient interface {
+	Recv() (*SnapshotPacket, error)
+	grpc.ClientStream
+}
+
+type dataSnapshotGetSnapshotDeltaClient struct {
+	grpc.ClientStream
+}
+
+func (x *dataSnapshotGetSnapshotDeltaClient) Recv() (*SnapshotPacket, error) {
+	m := new(SnapshotPacket)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *dataSnapshotClient) QueryIndex(ctx context.Context, in *QueryIndexRequest, opts ...grpc.CallOption) (DataSnapshot_QueryIndexClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_DataSnapshot_serviceDesc.Streams[2], "/gravity.DataSnapshot/QueryIndex", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dataSnapshotQueryIndexClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DataSnapshot_QueryIndexClient interface {
+	Recv() (*SnapshotPacket, error)
+	grpc.ClientStream
+}
+
+type dataSnapshotQueryIndexClient struct {
+	grpc.ClientStream
+}
+
+func (x *dataSnapshotQueryIndexClient) Recv() (*SnapshotPacket, error) {
+	m := new(SnapshotPacket)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *dataSnapshotClient) GetCollectionStats(ctx context.Context, in *GetCollectionStatsRequest, opts ...grpc.CallOption) (*GetCollectionStatsReply, error) {
+	out := new(GetCollectionStatsReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshot/GetCollectionStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotClient) GetRecordHistory(ctx context.Context, in *GetRecordHistoryRequest, opts ...grpc.CallOption) (*GetRecordHistoryReply, error) {
+	out := new(GetRecordHistoryReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshot/GetRecordHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotClient) GetSnapshotAt(ctx context.Context, in *GetSnapshotAtRequest, opts ...grpc.CallOption) (DataSnapshot_GetSnapshotAtClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_DataSnapshot_serviceDesc.Streams[3], "/gravity.DataSnapshot/GetSnapshotAt", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dataSnapshotGetSnapshotAtClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DataSnapshot_GetSnapshotAtClient interface {
+	Recv() (*SnapshotPacket, error)
+	grpc.ClientStream
+}
+
+type dataSnapshotGetSnapshotAtClient struct {
+	grpc.ClientStream
+}
+
+func (x *dataSnapshotGetSnapshotAtClient) Recv() (*SnapshotPacket, error) {
+	m := new(SnapshotPacket)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *dataSnapshotClient) CreateCheckpoint(ctx context.Context, in *CreateCheckpointRequest, opts ...grpc.CallOption) (*CheckpointReply, error) {
+	out := new(CheckpointReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshot/CreateCheckpoint", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotClient) RenewCheckpoint(ctx context.Context, in *RenewCheckpointRequest, opts ...grpc.CallOption) (*CheckpointReply, error) {
+	out := new(CheckpointReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshot/RenewCheckpoint", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotClient) ReleaseCheckpoint(ctx context.Context, in *ReleaseCheckpointRequest, opts ...grpc.CallOption) (*ReleaseCheckpointReply, error) {
+	out := new(ReleaseCheckpointReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshot/ReleaseCheckpoint", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotClient) DescribeCollection(ctx context.Context, in *DescribeCollectionRequest, opts ...grpc.CallOption) (*DescribeCollectionReply, error) {
+	out := new(DescribeCollectionReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshot/DescribeCollection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotClient) GetChecksum(ctx context.Context, in *GetChecksumRequest, opts ...grpc.CallOption) (*GetChecksumReply, error) {
+	out := new(GetChecksumReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshot/GetChecksum", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotClient) GetChecksumLeaf(ctx context.Context, in *GetChecksumLeafRequest, opts ...grpc.CallOption) (DataSnapshot_GetChecksumLeafClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_DataSnapshot_serviceDesc.Streams[4], "/gravity.DataSnapshot/GetChecksumLeaf", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dataSnapshotGetChecksumLeafClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DataSnapshot_GetChecksumLeafClient interface {
+	Recv() (*SnapshotPacket, error)
+	grpc.ClientStream
+}
+
+type dataSnapshotGetChecksumLeafClient struct {
+	grpc.ClientStream
+}
+
+func (x *dataSnapshotGetChecksumLeafClient) Recv() (*SnapshotPacket, error) {
+	m := new(SnapshotPacket)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *dataSnapshotClient) ListCollections(ctx context.Context, in *ListCollectionsRequest, opts ...grpc.CallOption) (*ListCollectionsReply, error) {
+	out := new(ListCollectionsReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshot/ListCollections", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotClient) GetRecord(ctx context.Context, in *GetRecordRequest, opts ...grpc.CallOption) (*GetRecordReply, error) {
+	out := new(GetRecordReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshot/GetRecord", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotClient) BatchGet(ctx context.Context, in *BatchGetRequest, opts ...grpc.CallOption) (*BatchGetReply, error) {
+	out := new(BatchGetReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshot/BatchGet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotClient) GetPartitions(ctx context.Context, in *GetPartitionsRequest, opts ...grpc.CallOption) (*GetPartitionsReply, error) {
+	out := new(GetPartitionsReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshot/GetPartitions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DataSnapshotServer is the server API for DataSnapshot service.
+type DataSnapshotServer interface {
+	GetSnapshotState(context.Context, *GetSnapshotStateRequest) (*GetSnapshotStateReply, error)
+	GetSnapshot(*GetSnapshotRequest, DataSnapshot_GetSnapshotServer) error
+	GetSnapshotDelta(*GetSnapshotDeltaRequest, DataSnapshot_GetSnapshotDeltaServer) error
+	QueryIndex(*QueryIndexRequest, DataSnapshot_QueryIndexServer) error
+	GetCollectionStats(context.Context, *GetCollectionStatsRequest) (*GetCollectionStatsReply, error)
+	GetRecordHistory(context.Context, *GetRecordHistoryRequest) (*GetRecordHistoryReply, error)
+	GetSnapshotAt(*GetSnapshotAtRequest, DataSnapshot_GetSnapshotAtServer) error
+	CreateCheckpoint(context.Context, *CreateCheckpointRequest) (*CheckpointReply, error)
+	RenewCheckpoint(context.Context, *RenewCheckpointRequest) (*CheckpointReply, error)
+	ReleaseCheckpoint(context.Context, *ReleaseCheckpointRequest) (*ReleaseCheckpointReply, error)
+	DescribeCollection(context.Context, *DescribeCollectionRequest) (*DescribeCollectionReply, error)
+	GetChecksum(context.Context, *GetChecksumRequest) (*GetChecksumReply, error)
+	GetChecksumLeaf(*GetChecksumLeafRequest, DataSnapshot_GetChecksumLeafServer) error
+	ListCollections(context.Context, *ListCollectionsRequest) (*ListCollectionsReply, error)
+	GetRecord(context.Context, *GetRecordRequest) (*GetRecordReply, error)
+	BatchGet(context.Context, *BatchGetRequest) (*BatchGetReply, error)
+	GetPartitions(context.Context, *GetPartitionsRequest) (*GetPartitionsReply, error)
+}
+
+// UnimplementedDataSnapshotServer can be embedded to have forward compatible implementations.
+type UnimplementedDataSnapshotServer struct {
+}
+
+func (*UnimplementedDataSnapshotServer) GetSnapshotState(ctx context.Context, req *GetSnapshotStateRequest) (*GetSnapshotStateReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSnapshotState not implemented")
+}
+func (*UnimplementedDataSnapshotServer) GetSnapshot(req *GetSnapshotRequest, srv DataSnapshot_GetSnapshotServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetSnapshot not implemented")
+}
+func (*UnimplementedDataSnapshotServer) GetSnapshotDelta(req *GetSnapshotDeltaRequest, srv DataSnapshot_GetSnapshotDeltaServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetSnapshotDelta not implemented")
+}
+func (*UnimplementedDataSnapshotServer) QueryIndex(req *QueryIndexRequest, srv DataSnapshot_QueryIndexServer) error {
+	return status.Errorf(codes.Unimplemented, "method QueryIndex not implemented")
+}
+func (*UnimplementedDataSnapshotServer) GetCollectionStats(ctx context.Context, req *GetCollectionStatsRequest) (*GetCollectionStatsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCollectionStats not implemented")
+}
+func (*UnimplementedDataSnapshotServer) GetRecordHistory(ctx context.Context, req *GetRecordHistoryRequest) (*GetRecordHistoryReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRecordHistory not implemented")
+}
+func (*UnimplementedDataSnapshotServer) GetSnapshotAt(req *GetSnapshotAtRequest, srv DataSnapshot_GetSnapshotAtServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetSnapshotAt not implemented")
+}
+func (*UnimplementedDataSnapshotServer) CreateCheckpoint(ctx context.Context, req *CreateCheckpointRequest) (*CheckpointReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCheckpoint not implemented")
+}
+func (*UnimplementedDataSnapshotServer) RenewCheckpoint(ctx context.Context, req *RenewCheckpointRequest) (*CheckpointReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenewCheckpoint not implemented")
+}
+func (*UnimplementedDataSnapshotServer) ReleaseCheckpoint(ctx context.Context, req *ReleaseCheckpointRequest) (*ReleaseCheckpointReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseCheckpoint not implemented")
+}
+func (*UnimplementedDataSnapshotServer) DescribeCollection(ctx context.Context, req *DescribeCollectionRequest) (*DescribeCollectionReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DescribeCollection not implemented")
+}
+func (*UnimplementedDataSnapshotServer) GetChecksum(ctx context.Context, req *GetChecksumRequest) (*GetChecksumReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChecksum not implemented")
+}
+func (*UnimplementedDataSnapshotServer) GetChecksumLeaf(req *GetChecksumLeafRequest, srv DataSnapshot_GetChecksumLeafServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetChecksumLeaf not implemented")
+}
+func (*UnimplementedDataSnapshotServer) ListCollections(ctx context.Context, req *ListCollectionsRequest) (*ListCollectionsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCollections not implemented")
+}
+func (*UnimplementedDataSnapshotServer) GetRecord(ctx context.Context, req *GetRecordRequest) (*GetRecordReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRecord not implemented")
+}
+func (*UnimplementedDataSnapshotServer) BatchGet(ctx context.Context, req *BatchGetRequest) (*BatchGetReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGet not implemented")
+}
+func (*UnimplementedDataSnapshotServer) GetPartitions(ctx context.Context, req *GetPartitionsRequest) (*GetPartitionsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPartitions not implemented")
+}
+
+func RegisterDataSnapshotServer(s *grpc.Server, srv DataSnapshotServer) {
+	s.RegisterService(&_DataSnapshot_serviceDesc, srv)
+}
+
+func _DataSnapshot_GetSnapshotState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSnapshotStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotServer).GetSnapshotState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshot/GetSnapshotState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotServer).GetSnapshotState(ctx, req.(*GetSnapshotStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshot_GetSnapshot_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetSnapshotRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DataSnapshotServer).GetSnapshot(m, &dataSnapshotGetSnapshotServer{stream})
+}
+
+type DataSnapshot_GetSnapshotServer interface {
+	Send(*SnapshotPacket) error
+	grpc.ServerStream
+}
+
+type dataSnapshotGetSnapshotServer struct {
+	grpc.ServerStream
+}
+
+func (x *dataSnapshotGetSnapshotServer) Send(m *SnapshotPacket) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _DataSnapshot_GetSnapshotDelta_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetSnapshotDeltaRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DataSnapshotServer).GetSnapshotDelta(m, &dataSnapshotGetSnapshotDeltaServer{stream})
+}
+
+type DataSnapshot_GetSnapshotDeltaServer interface {
+	Send(*SnapshotPacket) error
+	grpc.ServerStream
+}
+
+type dataSnapshotGetSnapshotDeltaServer struct {
+	grpc.ServerStream
+}
+
+func (x *dataSnapshotGetSnapshotDeltaServer) Send(m *SnapshotPacket) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _DataSnapshot_QueryIndex_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryIndexRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DataSnapshotServer).QueryIndex(m, &dataSnapshotQueryIndexServer{stream})
+}
+
+type DataSnapshot_QueryIndexServer interface {
+	Send(*SnapshotPacket) error
+	grpc.ServerStream
+}
+
+type dataSnapshotQueryIndexServer struct {
+	grpc.ServerStream
+}
+
+func (x *dataSnapshotQueryIndexServer) Send(m *SnapshotPacket) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _DataSnapshot_GetCollectionStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCollectionStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotServer).GetCollectionStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshot/GetCollectionStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotServer).GetCollectionStats(ctx, req.(*GetCollectionStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshot_GetRecordHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRecordHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotServer).GetRecordHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshot/GetRecordHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotServer).GetRecordHistory(ctx, req.(*GetRecordHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshot_GetSnapshotAt_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetSnapshotAtRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DataSnapshotServer).GetSnapshotAt(m, &dataSnapshotGetSnapshotAtServer{stream})
+}
+
+type DataSnapshot_GetSnapshotAtServer interface {
+	Send(*SnapshotPacket) error
+	grpc.ServerStream
+}
+
+type dataSnapshotGetSnapshotAtServer struct {
+	grpc.ServerStream
+}
+
+func (x *dataSnapshotGetSnapshotAtServer) Send(m *SnapshotPacket) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _DataSnapshot_CreateCheckpoint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCheckpointRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotServer).CreateCheckpoint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshot/CreateCheckpoint",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotServer).CreateCheckpoint(ctx, req.(*CreateCheckpointRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshot_RenewCheckpoint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewCheckpointRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotServer).RenewCheckpoint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshot/RenewCheckpoint",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotServer).RenewCheckpoint(ctx, req.(*RenewCheckpointRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshot_ReleaseCheckpoint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseCheckpointRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotServer).ReleaseCheckpoint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshot/ReleaseCheckpoint",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotServer).ReleaseCheckpoint(ctx, req.(*ReleaseCheckpointRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshot_DescribeCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DescribeCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotServer).DescribeCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshot/DescribeCollection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotServer).DescribeCollection(ctx, req.(*DescribeCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshot_GetChecksum_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetChecksumRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotServer).GetChecksum(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshot/GetChecksum",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotServer).GetChecksum(ctx, req.(*GetChecksumRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshot_GetChecksumLeaf_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetChecksumLeafRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DataSnapshotServer).GetChecksumLeaf(m, &dataSnapshotGetChecksumLeafServer{stream})
+}
+
+type DataSnapshot_GetChecksumLeafServer interface {
+	Send(*SnapshotPacket) error
+	grpc.ServerStream
+}
+
+type dataSnapshotGetChecksumLeafServer struct {
+	grpc.ServerStream
+}
+
+func (x *dataSnapshotGetChecksumLeafServer) Send(m *SnapshotPacket) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _DataSnapshot_ListCollections_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCollectionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotServer).ListCollections(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshot/ListCollections",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotServer).ListCollections(ctx, req.(*ListCollectionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshot_GetRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRecordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotServer).GetRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshot/GetRecord",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotServer).GetRecord(ctx, req.(*GetRecordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshot_BatchGet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotServer).BatchGet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshot/BatchGet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotServer).BatchGet(ctx, req.(*BatchGetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshot_GetPartitions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPartitionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotServer).GetPartitions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshot/GetPartitions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotServer).GetPartitions(ctx, req.(*GetPartitionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _DataSnapshot_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "gravity.DataSnapshot",
 	HandlerType: (*DataSnapshotServer)(nil),
 	Methods: []grpc.MethodDesc{
@@ -407,6 +4225,50 @@ var _DataSnapshot_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetSnapshotState",
 			Handler:    _DataSnapshot_GetSnapshotState_Handler,
 		},
+		{
+			MethodName: "GetCollectionStats",
+			Handler:    _DataSnapshot_GetCollectionStats_Handler,
+		},
+		{
+			MethodName: "GetRecordHistory",
+			Handler:    _DataSnapshot_GetRecordHistory_Handler,
+		},
+		{
+			MethodName: "CreateCheckpoint",
+			Handler:    _DataSnapshot_CreateCheckpoint_Handler,
+		},
+		{
+			MethodName: "RenewCheckpoint",
+			Handler:    _DataSnapshot_RenewCheckpoint_Handler,
+		},
+		{
+			MethodName: "ReleaseCheckpoint",
+			Handler:    _DataSnapshot_ReleaseCheckpoint_Handler,
+		},
+		{
+			MethodName: "DescribeCollection",
+			Handler:    _DataSnapshot_DescribeCollection_Handler,
+		},
+		{
+			MethodName: "GetChecksum",
+			Handler:    _DataSnapshot_GetChecksum_Handler,
+		},
+		{
+			MethodName: "ListCollections",
+			Handler:    _DataSnapshot_ListCollections_Handler,
+		},
+		{
+			MethodName: "GetRecord",
+			Handler:    _DataSnapshot_GetRecord_Handler,
+		},
+		{
+			MethodName: "BatchGet",
+			Handler:    _DataSnapshot_BatchGet_Handler,
+		},
+		{
+			MethodName: "GetPartitions",
+			Handler:    _DataSnapshot_GetPartitions_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -414,6 +4276,386 @@ var _DataSnapshot_serviceDesc = grpc.ServiceDesc{
 			Handler:       _DataSnapshot_GetSnapshot_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "GetSnapshotDelta",
+			Handler:       _DataSnapshot_GetSnapshotDelta_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "QueryIndex",
+			Handler:       _DataSnapshot_QueryIndex_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetSnapshotAt",
+			Handler:       _DataSnapshot_GetSnapshotAt_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetChecksumLeaf",
+			Handler:       _DataSnapshot_GetChecksumLeaf_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pb/data_snapshot.proto",
+}
+
+// DataSnapshotAdminClient is the client API for DataSnapshotAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type DataSnapshotAdminClient interface {
+	CreateBackup(ctx context.Context, in *CreateBackupRequest, opts ...grpc.CallOption) (*CreateBackupReply, error)
+	DropCollection(ctx context.Context, in *DropCollectionRequest, opts ...grpc.CallOption) (*DropCollectionReply, error)
+	TruncateCollection(ctx context.Context, in *TruncateCollectionRequest, opts ...grpc.CallOption) (*TruncateCollectionReply, error)
+	RenameCollection(ctx context.Context, in *RenameCollectionRequest, opts ...grpc.CallOption) (*RenameCollectionReply, error)
+	FreezeCollection(ctx context.Context, in *FreezeCollectionRequest, opts ...grpc.CallOption) (*FreezeCollectionReply, error)
+	CompactCollection(ctx context.Context, in *CompactCollectionRequest, opts ...grpc.CallOption) (*CompactCollectionReply, error)
+	GetStorageReport(ctx context.Context, in *GetStorageReportRequest, opts ...grpc.CallOption) (*GetStorageReportReply, error)
+	GetCollectionStatus(ctx context.Context, in *GetCollectionStatusRequest, opts ...grpc.CallOption) (*GetCollectionStatusReply, error)
+	RebuildCollection(ctx context.Context, in *RebuildCollectionRequest, opts ...grpc.CallOption) (*RebuildCollectionReply, error)
+}
+
+type dataSnapshotAdminClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewDataSnapshotAdminClient(cc *grpc.ClientConn) DataSnapshotAdminClient {
+	return &dataSnapshotAdminClient{cc}
+}
+
+func (c *dataSnapshotAdminClient) CreateBackup(ctx context.Context, in *CreateBackupRequest, opts ...grpc.CallOption) (*CreateBackupReply, error) {
+	out := new(CreateBackupReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshotAdmin/CreateBackup", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotAdminClient) DropCollection(ctx context.Context, in *DropCollectionRequest, opts ...grpc.CallOption) (*DropCollectionReply, error) {
+	out := new(DropCollectionReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshotAdmin/DropCollection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotAdminClient) TruncateCollection(ctx context.Context, in *TruncateCollectionRequest, opts ...grpc.CallOption) (*TruncateCollectionReply, error) {
+	out := new(TruncateCollectionReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshotAdmin/TruncateCollection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotAdminClient) RenameCollection(ctx context.Context, in *RenameCollectionRequest, opts ...grpc.CallOption) (*RenameCollectionReply, error) {
+	out := new(RenameCollectionReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshotAdmin/RenameCollection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotAdminClient) FreezeCollection(ctx context.Context, in *FreezeCollectionRequest, opts ...grpc.CallOption) (*FreezeCollectionReply, error) {
+	out := new(FreezeCollectionReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshotAdmin/FreezeCollection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotAdminClient) CompactCollection(ctx context.Context, in *CompactCollectionRequest, opts ...grpc.CallOption) (*CompactCollectionReply, error) {
+	out := new(CompactCollectionReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshotAdmin/CompactCollection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotAdminClient) GetStorageReport(ctx context.Context, in *GetStorageReportRequest, opts ...grpc.CallOption) (*GetStorageReportReply, error) {
+	out := new(GetStorageReportReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshotAdmin/GetStorageReport", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotAdminClient) GetCollectionStatus(ctx context.Context, in *GetCollectionStatusRequest, opts ...grpc.CallOption) (*GetCollectionStatusReply, error) {
+	out := new(GetCollectionStatusReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshotAdmin/GetCollectionStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataSnapshotAdminClient) RebuildCollection(ctx context.Context, in *RebuildCollectionRequest, opts ...grpc.CallOption) (*RebuildCollectionReply, error) {
+	out := new(RebuildCollectionReply)
+	err := c.cc.Invoke(ctx, "/gravity.DataSnapshotAdmin/RebuildCollection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DataSnapshotAdminServer is the server API for DataSnapshotAdmin service.
+type DataSnapshotAdminServer interface {
+	CreateBackup(context.Context, *CreateBackupRequest) (*CreateBackupReply, error)
+	DropCollection(context.Context, *DropCollectionRequest) (*DropCollectionReply, error)
+	TruncateCollection(context.Context, *TruncateCollectionRequest) (*TruncateCollectionReply, error)
+	RenameCollection(context.Context, *RenameCollectionRequest) (*RenameCollectionReply, error)
+	FreezeCollection(context.Context, *FreezeCollectionRequest) (*FreezeCollectionReply, error)
+	CompactCollection(context.Context, *CompactCollectionRequest) (*CompactCollectionReply, error)
+	GetStorageReport(context.Context, *GetStorageReportRequest) (*GetStorageReportReply, error)
+	GetCollectionStatus(context.Context, *GetCollectionStatusRequest) (*GetCollectionStatusReply, error)
+	RebuildCollection(context.Context, *RebuildCollectionRequest) (*RebuildCollectionReply, error)
+}
+
+// UnimplementedDataSnapshotAdminServer can be embedded to have forward compatible implementations.
+type UnimplementedDataSnapshotAdminServer struct {
+}
+
+func (*UnimplementedDataSnapshotAdminServer) CreateBackup(ctx context.Context, req *CreateBackupRequest) (*CreateBackupReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateBackup not implemented")
+}
+func (*UnimplementedDataSnapshotAdminServer) DropCollection(ctx context.Context, req *DropCollectionRequest) (*DropCollectionReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DropCollection not implemented")
+}
+func (*UnimplementedDataSnapshotAdminServer) TruncateCollection(ctx context.Context, req *TruncateCollectionRequest) (*TruncateCollectionReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TruncateCollection not implemented")
+}
+func (*UnimplementedDataSnapshotAdminServer) RenameCollection(ctx context.Context, req *RenameCollectionRequest) (*RenameCollectionReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenameCollection not implemented")
+}
+func (*UnimplementedDataSnapshotAdminServer) FreezeCollection(ctx context.Context, req *FreezeCollectionRequest) (*FreezeCollectionReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FreezeCollection not implemented")
+}
+func (*UnimplementedDataSnapshotAdminServer) CompactCollection(ctx context.Context, req *CompactCollectionRequest) (*CompactCollectionReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompactCollection not implemented")
+}
+func (*UnimplementedDataSnapshotAdminServer) GetStorageReport(ctx context.Context, req *GetStorageReportRequest) (*GetStorageReportReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStorageReport not implemented")
+}
+func (*UnimplementedDataSnapshotAdminServer) GetCollectionStatus(ctx context.Context, req *GetCollectionStatusRequest) (*GetCollectionStatusReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCollectionStatus not implemented")
+}
+func (*UnimplementedDataSnapshotAdminServer) RebuildCollection(ctx context.Context, req *RebuildCollectionRequest) (*RebuildCollectionReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RebuildCollection not implemented")
+}
+
+func RegisterDataSnapshotAdminServer(s *grpc.Server, srv DataSnapshotAdminServer) {
+	s.RegisterService(&_DataSnapshotAdmin_serviceDesc, srv)
+}
+
+func _DataSnapshotAdmin_CreateBackup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateBackupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotAdminServer).CreateBackup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshotAdmin/CreateBackup",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotAdminServer).CreateBackup(ctx, req.(*CreateBackupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshotAdmin_DropCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DropCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotAdminServer).DropCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshotAdmin/DropCollection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotAdminServer).DropCollection(ctx, req.(*DropCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshotAdmin_TruncateCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TruncateCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotAdminServer).TruncateCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshotAdmin/TruncateCollection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotAdminServer).TruncateCollection(ctx, req.(*TruncateCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshotAdmin_RenameCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenameCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotAdminServer).RenameCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshotAdmin/RenameCollection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotAdminServer).RenameCollection(ctx, req.(*RenameCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshotAdmin_FreezeCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FreezeCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotAdminServer).FreezeCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshotAdmin/FreezeCollection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotAdminServer).FreezeCollection(ctx, req.(*FreezeCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshotAdmin_CompactCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompactCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotAdminServer).CompactCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshotAdmin/CompactCollection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotAdminServer).CompactCollection(ctx, req.(*CompactCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshotAdmin_GetStorageReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStorageReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotAdminServer).GetStorageReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshotAdmin/GetStorageReport",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotAdminServer).GetStorageReport(ctx, req.(*GetStorageReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshotAdmin_GetCollectionStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCollectionStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotAdminServer).GetCollectionStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshotAdmin/GetCollectionStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotAdminServer).GetCollectionStatus(ctx, req.(*GetCollectionStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataSnapshotAdmin_RebuildCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RebuildCollectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataSnapshotAdminServer).RebuildCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gravity.DataSnapshotAdmin/RebuildCollection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataSnapshotAdminServer).RebuildCollection(ctx, req.(*RebuildCollectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _DataSnapshotAdmin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "gravity.DataSnapshotAdmin",
+	HandlerType: (*DataSnapshotAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateBackup",
+			Handler:    _DataSnapshotAdmin_CreateBackup_Handler,
+		},
+		{
+			MethodName: "DropCollection",
+			Handler:    _DataSnapshotAdmin_DropCollection_Handler,
+		},
+		{
+			MethodName: "TruncateCollection",
+			Handler:    _DataSnapshotAdmin_TruncateCollection_Handler,
+		},
+		{
+			MethodName: "RenameCollection",
+			Handler:    _DataSnapshotAdmin_RenameCollection_Handler,
+		},
+		{
+			MethodName: "FreezeCollection",
+			Handler:    _DataSnapshotAdmin_FreezeCollection_Handler,
+		},
+		{
+			MethodName: "CompactCollection",
+			Handler:    _DataSnapshotAdmin_CompactCollection_Handler,
+		},
+		{
+			MethodName: "GetStorageReport",
+			Handler:    _DataSnapshotAdmin_GetStorageReport_Handler,
+		},
+		{
+			MethodName: "GetCollectionStatus",
+			Handler:    _DataSnapshotAdmin_GetCollectionStatus_Handler,
+		},
+		{
+			MethodName: "RebuildCollection",
+			Handler:    _DataSnapshotAdmin_RebuildCollection_Handler,
+		},
 	},
+	Streams:  []grpc.StreamDesc{},
 	Metadata: "pb/data_snapshot.proto",
 }
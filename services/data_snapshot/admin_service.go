@@ -0,0 +1,243 @@
+package data_snapshot
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "gravity-data-snapshot/pb"
+)
+
+// AdminService serves operations for operators on the same collections as
+// Service.
+type AdminService struct {
+	dbMgr *DatabaseManager
+}
+
+func CreateAdminService(service *Service) *AdminService {
+	return &AdminService{
+		dbMgr: service.dbMgr,
+	}
+}
+
+func (service *AdminService) CreateBackup(ctx context.Context, in *pb.CreateBackupRequest) (*pb.CreateBackupReply, error) {
+
+	path, results, err := service.dbMgr.CreateBackup(in.Collections)
+	if err != nil {
+		return &pb.CreateBackupReply{}, adminError(err)
+	}
+
+	reply := &pb.CreateBackupReply{
+		Path:        path,
+		Collections: make([]*pb.BackupCollection, 0, len(results)),
+	}
+
+	for _, meta := range results {
+		reply.Collections = append(reply.Collections, &pb.BackupCollection{
+			Collection:  meta.Collection,
+			Sequence:    meta.Sequence,
+			RecordCount: meta.RecordCount,
+		})
+	}
+
+	return reply, nil
+}
+
+func adminError(err error) error {
+
+	switch err {
+	case ErrCollectionNotFound:
+		return status.Error(codes.NotFound, "No such collection")
+	case ErrCollectionExists:
+		return status.Error(codes.AlreadyExists, err.Error())
+	case ErrCollectionBusy:
+		return status.Error(codes.Unavailable, err.Error())
+	case ErrCollectionInMemory, ErrQuotaExceeded:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case ErrCompactionUnsupported, ErrReportUnsupported:
+		return status.Error(codes.Unimplemented, err.Error())
+	case ErrCompactionRunning, ErrCollectionRebuilding, ErrShutdownTimeout:
+		return status.Error(codes.Unavailable, err.Error())
+	case ErrCollectionFailed:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case ErrRebuildUnavailable:
+		return status.Error(codes.Unimplemented, err.Error())
+	case ErrInvalidCompactRange:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
+
+func (service *AdminService) DropCollection(ctx context.Context, in *pb.DropCollectionRequest) (*pb.DropCollectionReply, error) {
+
+	err := service.dbMgr.DropCollection(in.Collection)
+	if err != nil {
+		return &pb.DropCollectionReply{}, adminError(err)
+	}
+
+	return &pb.DropCollectionReply{
+		Collection: in.Collection,
+	}, nil
+}
+
+func (service *AdminService) TruncateCollection(ctx context.Context, in *pb.TruncateCollectionRequest) (*pb.TruncateCollectionReply, error) {
+
+	seq, err := service.dbMgr.TruncateCollection(in.Collection)
+	if err != nil {
+		return &pb.TruncateCollectionReply{}, adminError(err)
+	}
+
+	return &pb.TruncateCollectionReply{
+		Collection: in.Collection,
+		Sequence:   seq,
+	}, nil
+}
+
+func (service *AdminService) RenameCollection(ctx context.Context, in *pb.RenameCollectionRequest) (*pb.RenameCollectionReply, error) {
+
+	if !validCollectionName(in.NewName) {
+		return &pb.RenameCollectionReply{}, status.Error(codes.InvalidArgument, "Invalid collection name")
+	}
+
+	err := service.dbMgr.RenameCollection(in.Collection, in.NewName)
+	if err != nil {
+		return &pb.RenameCollectionReply{}, adminError(err)
+	}
+
+	return &pb.RenameCollectionReply{
+		Collection: in.NewName,
+	}, nil
+}
+
+func (service *AdminService) FreezeCollection(ctx context.Context, in *pb.FreezeCollectionRequest) (*pb.FreezeCollectionReply, error) {
+
+	db := service.dbMgr.GetExistingDatabase(in.Collection)
+	if db == nil {
+		return &pb.FreezeCollectionReply{}, missingCollection(service.dbMgr, in.Collection)
+	}
+	defer db.Release()
+
+	// Rebuild unfreezes collection once it catches up
+	if db.isRebuilding() {
+		return &pb.FreezeCollectionReply{}, adminError(ErrCollectionRebuilding)
+	}
+
+	var err error
+	if in.Frozen {
+		err = db.Freeze()
+	} else {
+		err = db.Unfreeze()
+	}
+
+	if err != nil {
+		return &pb.FreezeCollectionReply{}, adminError(err)
+	}
+
+	pending, err := db.PendingCount()
+	if err != nil {
+		return &pb.FreezeCollectionReply{}, adminError(err)
+	}
+
+	return &pb.FreezeCollectionReply{
+		Collection:    in.Collection,
+		Frozen:        db.IsFrozen(),
+		PendingEvents: pending,
+	}, nil
+}
+
+func (service *AdminService) CompactCollection(ctx context.Context, in *pb.CompactCollectionRequest) (*pb.CompactCollectionReply, error) {
+
+	db := service.dbMgr.GetExistingDatabase(in.Collection)
+	if db == nil {
+		return &pb.CompactCollectionReply{}, missingCollection(service.dbMgr, in.Collection)
+	}
+	defer db.Release()
+
+	result, err := db.Compact(in.Start, in.Limit)
+	if err != nil {
+		return &pb.CompactCollectionReply{}, adminError(err)
+	}
+
+	return &pb.CompactCollectionReply{
+		Collection: in.Collection,
+		SizeBefore: result.SizeBefore,
+		SizeAfter:  result.SizeAfter,
+		Duration:   int64(result.Duration / time.Millisecond),
+	}, nil
+}
+
+func (service *AdminService) GetStorageReport(ctx context.Context, in *pb.GetStorageReportRequest) (*pb.GetStorageReportReply, error) {
+
+	db := service.dbMgr.GetExistingDatabase(in.Collection)
+	if db == nil {
+		return &pb.GetStorageReportReply{}, missingCollection(service.dbMgr, in.Collection)
+	}
+	defer db.Release()
+
+	report, err := db.StorageReport()
+	if err != nil {
+		return &pb.GetStorageReportReply{}, adminError(err)
+	}
+
+	reply := &pb.GetStorageReportReply{
+		Collection:         in.Collection,
+		Engine:             db.engine,
+		DiskSize:           report.DiskSize,
+		WriteAmplification: report.WriteAmplification,
+		OpenTables:         report.OpenTables,
+		Levels:             make([]*pb.StorageLevel, 0, len(report.Levels)),
+		Properties:         report.Properties,
+	}
+
+	for _, level := range report.Levels {
+		reply.Levels = append(reply.Levels, &pb.StorageLevel{
+			Level:  int32(level.Level),
+			Tables: level.Tables,
+			Size:   level.Size,
+		})
+	}
+
+	return reply, nil
+}
+
+func (service *AdminService) GetCollectionStatus(ctx context.Context, in *pb.GetCollectionStatusRequest) (*pb.GetCollectionStatusReply, error) {
+
+	reply := &pb.GetCollectionStatusReply{
+		Collection: in.Collection,
+	}
+
+	db := service.dbMgr.GetExistingDatabase(in.Collection)
+	if db == nil {
+		failure := service.dbMgr.GetFailure(in.Collection)
+		if failure == nil {
+			return &pb.GetCollectionStatusReply{}, adminError(ErrCollectionNotFound)
+		}
+
+		reply.State = stateFailed
+		reply.Error = failure.Error
+		reply.Quarantine = failure.Quarantine
+		reply.FailedAt = failure.FailedAt
+
+		return reply, nil
+	}
+	defer db.Release()
+
+	reply.State = db.State()
+
+	return reply, nil
+}
+
+func (service *AdminService) RebuildCollection(ctx context.Context, in *pb.RebuildCollectionRequest) (*pb.RebuildCollectionReply, error) {
+
+	err := service.dbMgr.RebuildCollection(in.Collection)
+	if err != nil {
+		return &pb.RebuildCollectionReply{}, adminError(err)
+	}
+
+	return &pb.RebuildCollectionReply{
+		Collection: in.Collection,
+	}, nil
+}
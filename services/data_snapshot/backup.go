@@ -0,0 +1,438 @@
+package data_snapshot
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var ErrInvalidArchive = errors.New("invalid backup archive")
+
+const (
+	archiveFormatVersion = 1
+	archiveMetadataFile  = "metadata.json"
+	archiveDataFile      = "data"
+	restoreChunkSize     = 1000
+)
+
+// ArchiveMetadata describes a collection in backup archive. Data of
+// collection is stored as key/value pairs just as they are in database, so
+// values of encrypted collection can only be restored with the same master
+// keys.
+type ArchiveMetadata struct {
+	Version     int    `json:"version"`
+	Collection  string `json:"collection"`
+	Sequence    uint64 `json:"seq"`
+	RecordCount uint64 `json:"records"`
+	Encrypted   bool   `json:"encrypted"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+// WriteBackup writes a consistent snapshot of database to archive, under
+// directory of collection in catalog.
+func (database *Database) WriteBackup(tw *tar.Writer, dir string) (*ArchiveMetadata, error) {
+
+	snapshot, err := database.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Release()
+
+	seq, err := getSequence(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &ArchiveMetadata{
+		Version:    archiveFormatVersion,
+		Collection: database.name,
+		Sequence:   seq,
+		Encrypted:  database.getKeyring() != nil,
+		CreatedAt:  time.Now().UnixNano(),
+	}
+
+	stats, err := database.readStats(snapshot)
+	if err == nil {
+		meta.RecordCount = stats.RecordCount
+	} else if err != store.ErrNotFound {
+		return nil, err
+	}
+
+	// Size of tar entry has to be known ahead, so data goes to a temporary
+	// file first
+	tmp, err := ioutil.TempFile("", "snapshot-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	w := bufio.NewWriter(tmp)
+	iter := snapshot.NewIterator(nil)
+	for iter.Next() {
+		err := writePair(w, iter.Key(), iter.Value())
+		if err != nil {
+			iter.Release()
+			return nil, err
+		}
+	}
+
+	err = iter.Error()
+	iter.Release()
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	metaData, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeArchiveFile(tw, dir+"/"+archiveMetadataFile, int64(len(metaData)), bytes.NewReader(metaData))
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tmp.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeArchiveFile(tw, dir+"/"+archiveDataFile, size, tmp)
+	if err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+func writeArchiveFile(tw *tar.Writer, name string, size int64, r io.Reader) error {
+
+	err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    size,
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.CopyN(tw, r, size)
+
+	return err
+}
+
+// Pairs are stored as length prefixed key followed by length prefixed value.
+func writePair(w io.Writer, key []byte, value []byte) error {
+
+	buf := make([]byte, 0, 2*binary.MaxVarintLen64+len(key)+len(value))
+	buf = appendUvarint(buf, uint64(len(key)))
+	buf = append(buf, key...)
+	buf = appendUvarint(buf, uint64(len(value)))
+	buf = append(buf, value...)
+
+	_, err := w.Write(buf)
+
+	return err
+}
+
+func appendUvarint(buf []byte, n uint64) []byte {
+
+	b := make([]byte, binary.MaxVarintLen64)
+	size := binary.PutUvarint(b, n)
+
+	return append(buf, b[:size]...)
+}
+
+func readPair(r *bufio.Reader) ([]byte, []byte, error) {
+
+	key, err := readBytes(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	value, err := readBytes(r)
+	if err == io.EOF {
+		return nil, nil, ErrInvalidArchive
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	return key, value, nil
+}
+
+func readBytes(r *bufio.Reader) ([]byte, error) {
+
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, size)
+	_, err = io.ReadFull(r, data)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, ErrInvalidArchive
+	}
+
+	return data, err
+}
+
+// CreateBackup writes collections to a gzipped tar archive in backup
+// directory, all collections in catalog are included if names is empty.
+func (dm *DatabaseManager) CreateBackup(names []string) (string, []*ArchiveMetadata, error) {
+
+	if len(names) == 0 {
+		names = dm.catalog.Names()
+	}
+
+	for _, name := range names {
+		if _, ok := dm.catalog.Lookup(name); !ok {
+			return "", nil, ErrCollectionNotFound
+		}
+	}
+
+	dir := viper.GetString("backup.dir")
+	if dir == "" {
+		dir = "./backups"
+	}
+
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", nil, err
+	}
+
+	filename := filepath.Join(dir, fmt.Sprintf("snapshot-%s.tar.gz", time.Now().UTC().Format("20060102T150405.000000000")))
+	tmpname := filename + ".tmp"
+
+	file, err := os.OpenFile(tmpname, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.Remove(tmpname)
+	defer file.Close()
+
+	gw := gzip.NewWriter(file)
+	tw := tar.NewWriter(gw)
+
+	results := make([]*ArchiveMetadata, 0, len(names))
+	for _, name := range names {
+
+		entry, ok := dm.catalog.Lookup(name)
+		db := dm.GetExistingDatabase(name)
+		if !ok || db == nil {
+			return "", nil, fmt.Errorf("failed to open collection \"%s\"", name)
+		}
+
+		meta, err := db.WriteBackup(tw, entry.Dir)
+		db.Release()
+		if err != nil {
+			return "", nil, err
+		}
+
+		log.WithFields(log.Fields{
+			"collection": name,
+			"seq":        meta.Sequence,
+			"records":    meta.RecordCount,
+		}).Info("Backed up collection")
+
+		results = append(results, meta)
+	}
+
+	err = tw.Close()
+	if err == nil {
+		err = gw.Close()
+	}
+
+	if err == nil {
+		err = file.Sync()
+	}
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	err = os.Rename(tmpname, filename)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return filename, results, nil
+}
+
+// RestoreArchive rebuilds collections of archive under database path with
+// their configured engines. Existing collections are never overwritten.
+func RestoreArchive(filename string) ([]*ArchiveMetadata, error) {
+
+	catalog, err := OpenCatalog(viper.GetString("database.dbpath"))
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	results := make([]*ArchiveMetadata, 0)
+	var meta *ArchiveMetadata
+	var dir string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		// Data of collection follows its metadata in the same directory
+		parts := strings.Split(header.Name, "/")
+		if len(parts) != 2 {
+			return nil, ErrInvalidArchive
+		}
+
+		switch parts[1] {
+		case archiveMetadataFile:
+			meta = &ArchiveMetadata{}
+			err := json.NewDecoder(tr).Decode(meta)
+			if err != nil {
+				return nil, err
+			}
+
+			if meta.Version != archiveFormatVersion || !validCollectionName(meta.Collection) {
+				return nil, ErrInvalidArchive
+			}
+
+			dir = parts[0]
+		case archiveDataFile:
+			if meta == nil || dir != parts[0] {
+				return nil, ErrInvalidArchive
+			}
+
+			err := restoreCollection(catalog, meta, tr)
+			if err != nil {
+				return nil, err
+			}
+
+			log.WithFields(log.Fields{
+				"collection": meta.Collection,
+				"seq":        meta.Sequence,
+				"records":    meta.RecordCount,
+			}).Info("Restored collection")
+
+			results = append(results, meta)
+			meta = nil
+		default:
+			return nil, ErrInvalidArchive
+		}
+	}
+
+	return results, nil
+}
+
+func restoreCollection(catalog *Catalog, meta *ArchiveMetadata, r io.Reader) error {
+
+	if _, ok := catalog.Lookup(meta.Collection); ok {
+		return fmt.Errorf("collection \"%s\" exists already", meta.Collection)
+	}
+
+	entry, err := catalog.Register(meta.Collection)
+	if err != nil {
+		return err
+	}
+
+	layout, err := getLayout()
+	if err != nil {
+		return err
+	}
+
+	db, err := catalog.openStore(entry, layout)
+	if err != nil {
+		return err
+	}
+
+	err = loadPairs(db, bufio.NewReader(r))
+	if err == nil {
+		var seq uint64
+		seq, err = getSequence(db)
+		if err == nil && seq != meta.Sequence {
+			err = ErrInvalidArchive
+		}
+	}
+
+	closeErr := db.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		catalog.removeData(entry, layout)
+		catalog.Remove(meta.Collection)
+		return err
+	}
+
+	return nil
+}
+
+func loadPairs(db store.Store, r *bufio.Reader) error {
+
+	batch := db.NewBatch()
+	for {
+		key, value, err := readPair(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		batch.Put(key, value)
+		if batch.Len() >= restoreChunkSize {
+			err := db.Write(batch)
+			if err != nil {
+				return err
+			}
+
+			batch = db.NewBatch()
+		}
+	}
+
+	if batch.Len() == 0 {
+		return nil
+	}
+
+	return db.Write(batch)
+}
@@ -0,0 +1,154 @@
+package data_snapshot
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+)
+
+func TestPairEncoding(t *testing.T) {
+
+	tests := []struct {
+		key   []byte
+		value []byte
+	}{
+		{[]byte("k"), []byte("v")},
+		{[]byte{}, []byte{}},
+		{[]byte{0x00, 0xff}, []byte{0x80, 0x00}},
+		{bytes.Repeat([]byte("k"), 300), bytes.Repeat([]byte("v"), 70000)},
+	}
+
+	var buf bytes.Buffer
+	for _, test := range tests {
+		if err := writePair(&buf, test.key, test.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := bufio.NewReader(bytes.NewReader(buf.Bytes()))
+	for _, test := range tests {
+
+		key, value, err := readPair(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(key, test.key) || !bytes.Equal(value, test.value) {
+			t.Errorf("readPair = %q, %q, want %q, %q", key, value, test.key, test.value)
+		}
+	}
+
+	if _, _, err := readPair(r); err != io.EOF {
+		t.Errorf("readPair at end = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestReadPairErrors(t *testing.T) {
+
+	var pair bytes.Buffer
+	writePair(&pair, []byte("key"), []byte("value"))
+	data := pair.Bytes()
+
+	tests := [][]byte{
+		data[:1],
+		data[:4],
+		data[:5],
+		data[:len(data)-1],
+	}
+
+	for _, test := range tests {
+		if _, _, err := readPair(bufio.NewReader(bytes.NewReader(test))); err != ErrInvalidArchive {
+			t.Errorf("readPair(%q) = %v, want %v", test, err, ErrInvalidArchive)
+		}
+	}
+}
+
+func TestWriteBackup(t *testing.T) {
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	for i := 0; i < 2*restoreChunkSize+10; i++ {
+		writeTestRecord(t, db, uint64(i+1), float64(i), Field{Name: "n", Value: float64(i)})
+	}
+
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	written, err := db.WriteBackup(tw, "t-dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if written.Collection != "t" || written.Sequence != 2*restoreChunkSize+10 || written.RecordCount != 2*restoreChunkSize+10 {
+		t.Errorf("WriteBackup = %+v", written)
+	}
+
+	restored, err := store.Open("memory", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+
+	// Metadata comes first and data of collection follows it
+	tr := tar.NewReader(&archive)
+	names := make([]string, 0)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		names = append(names, header.Name)
+		switch header.Name {
+		case "t-dir/" + archiveMetadataFile:
+			meta := &ArchiveMetadata{}
+			err := json.NewDecoder(tr).Decode(meta)
+			if err != nil || *meta != *written {
+				t.Errorf("metadata = %+v, %v, want %+v", meta, err, written)
+			}
+		case "t-dir/" + archiveDataFile:
+			err := loadPairs(restored, bufio.NewReader(tr))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if strings.Join(names, ",") != "t-dir/"+archiveMetadataFile+",t-dir/"+archiveDataFile {
+		t.Fatalf("archive has files %v", names)
+	}
+
+	// Restored store holds the same pairs
+	want := db.db.NewIterator(nil)
+	defer want.Release()
+	got := restored.NewIterator(nil)
+	defer got.Release()
+
+	for want.Next() {
+
+		if !got.Next() {
+			t.Fatalf("restored store ends before %q", want.Key())
+		}
+
+		if !bytes.Equal(got.Key(), want.Key()) || !bytes.Equal(got.Value(), want.Value()) {
+			t.Fatalf("restored pair %q differs from %q", got.Key(), want.Key())
+		}
+	}
+
+	if got.Next() {
+		t.Errorf("restored store has extra key %q", got.Key())
+	}
+}
@@ -0,0 +1,422 @@
+package data_snapshot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	catalogFile          = "catalog.json"
+	maxCollectionName    = 255
+	maxCollectionDirSlug = 32
+)
+
+// CatalogEntry maps collection to its directory under database path. Names
+// come from events and clients, so they never become part of a path as they
+// are. Engine is taken from config when collection is registered, since
+// config is looked up by name and may change later on. Sequence and stats
+// are of the last time collection was opened or closed, so they are known
+// without opening collection.
+type CatalogEntry struct {
+	Name      string           `json:"name"`
+	Dir       string           `json:"dir"`
+	Engine    string           `json:"engine"`
+	CreatedAt int64            `json:"created_at"`
+	Sequence  uint64           `json:"seq,omitempty"`
+	Stats     *CollectionStats `json:"stats,omitempty"`
+
+	// Failure is set while collection is quarantined
+	Failure *CollectionFailure `json:"failure,omitempty"`
+}
+
+// Catalog keeps track of known collections in catalog.json of database path.
+type Catalog struct {
+	mutex   sync.RWMutex
+	dbpath  string
+	entries map[string]*CatalogEntry
+}
+
+func validCollectionName(name string) bool {
+
+	if name == "" || len(name) > maxCollectionName || !utf8.ValidString(name) {
+		return false
+	}
+
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// OpenCatalog loads catalog of database path. Databases which were created
+// before catalog existed are registered with their directory names.
+func OpenCatalog(dbpath string) (*Catalog, error) {
+
+	catalog := &Catalog{
+		dbpath:  dbpath,
+		entries: make(map[string]*CatalogEntry),
+	}
+
+	err := os.MkdirAll(dbpath, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dbpath, catalogFile))
+	if err == nil {
+		entries := make([]*CatalogEntry, 0)
+		err := json.Unmarshal(data, &entries)
+		if err != nil {
+			return nil, err
+		}
+
+		missing := false
+		for _, entry := range entries {
+
+			// Entries from before engine was recorded
+			if entry.Engine == "" {
+				entry.Engine = configuredEngine(entry.Name)
+				missing = true
+			}
+
+			catalog.entries[entry.Name] = entry
+		}
+
+		if !missing {
+			return catalog, nil
+		}
+
+		return catalog, catalog.save()
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	dirs, err := ioutil.ReadDir(dbpath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dir := range dirs {
+		if !dir.IsDir() || dir.Name() == sharedStoreDir {
+			continue
+		}
+
+		catalog.entries[dir.Name()] = &CatalogEntry{
+			Name:      dir.Name(),
+			Dir:       dir.Name(),
+			Engine:    configuredEngine(dir.Name()),
+			CreatedAt: dir.ModTime().UnixNano(),
+		}
+
+		log.WithFields(log.Fields{
+			"collection": dir.Name(),
+		}).Info("Registered existing collection in catalog")
+	}
+
+	return catalog, catalog.save()
+}
+
+// save writes catalog to a temporary file first, so it is never left half
+// written.
+func (catalog *Catalog) save() error {
+
+	entries := make([]*CatalogEntry, 0, len(catalog.entries))
+	for _, entry := range catalog.entries {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	filename := filepath.Join(catalog.dbpath, catalogFile)
+	err = ioutil.WriteFile(filename+".tmp", data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(filename+".tmp", filename)
+}
+
+func (catalog *Catalog) Lookup(name string) (*CatalogEntry, bool) {
+
+	catalog.mutex.RLock()
+	defer catalog.mutex.RUnlock()
+
+	entry, ok := catalog.entries[name]
+
+	return entry, ok
+}
+
+// configuredEngine returns engine which config gives to new collection.
+func configuredEngine(name string) string {
+
+	layout, _ := getLayout()
+
+	return layoutEngine(name, layout)
+}
+
+// layoutEngine returns engine of collection in layout. All collections of
+// shared layout are in the store of database.engine.
+func layoutEngine(name string, layout string) string {
+
+	engine := viper.GetString("database.engine")
+	if layout != layoutShared {
+		engine = getCollectionString(name, "engine")
+	}
+
+	if engine == "" {
+		return store.DefaultEngine
+	}
+
+	return engine
+}
+
+// Path returns directory of collection.
+func (catalog *Catalog) Path(entry *CatalogEntry) string {
+	return filepath.Join(catalog.dbpath, entry.Dir)
+}
+
+func (catalog *Catalog) Names() []string {
+
+	catalog.mutex.RLock()
+	defer catalog.mutex.RUnlock()
+
+	names := make([]string, 0, len(catalog.entries))
+	for name := range catalog.entries {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Register adds collection with a new directory, or returns the existing
+// entry.
+func (catalog *Catalog) Register(name string) (*CatalogEntry, error) {
+
+	catalog.mutex.Lock()
+	defer catalog.mutex.Unlock()
+
+	if entry, ok := catalog.entries[name]; ok {
+		return entry, nil
+	}
+
+	dir, err := catalog.newDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &CatalogEntry{
+		Name:      name,
+		Dir:       dir,
+		Engine:    configuredEngine(name),
+		CreatedAt: time.Now().UnixNano(),
+	}
+
+	catalog.entries[name] = entry
+
+	err = catalog.save()
+	if err != nil {
+		delete(catalog.entries, name)
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// newDir makes directory name out of readable part of collection name and a
+// random suffix.
+func (catalog *Catalog) newDir(name string) (string, error) {
+
+	slug := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-') {
+			return r
+		}
+
+		return '_'
+	}, name)
+
+	if len(slug) > maxCollectionDirSlug {
+		slug = slug[:maxCollectionDirSlug]
+	}
+
+	used := make(map[string]bool, len(catalog.entries))
+	for _, entry := range catalog.entries {
+		used[entry.Dir] = true
+	}
+
+	for {
+		suffix := make([]byte, 4)
+		_, err := rand.Read(suffix)
+		if err != nil {
+			return "", err
+		}
+
+		dir := slug + "-" + hex.EncodeToString(suffix)
+		if used[dir] {
+			continue
+		}
+
+		_, err = os.Stat(filepath.Join(catalog.dbpath, dir))
+		if os.IsNotExist(err) {
+			return dir, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+}
+
+func (catalog *Catalog) Remove(name string) error {
+
+	catalog.mutex.Lock()
+	defer catalog.mutex.Unlock()
+
+	entry, ok := catalog.entries[name]
+	if !ok {
+		return nil
+	}
+
+	delete(catalog.entries, name)
+
+	err := catalog.save()
+	if err != nil {
+		catalog.entries[name] = entry
+		return err
+	}
+
+	return nil
+}
+
+// SetFailure marks collection as failed, nil clears failure.
+func (catalog *Catalog) SetFailure(name string, failure *CollectionFailure) error {
+
+	catalog.mutex.Lock()
+	defer catalog.mutex.Unlock()
+
+	entry, ok := catalog.entries[name]
+	if !ok {
+		return ErrCollectionNotFound
+	}
+
+	updated := *entry
+	updated.Failure = failure
+	catalog.entries[name] = &updated
+
+	err := catalog.save()
+	if err != nil {
+		catalog.entries[name] = entry
+		return err
+	}
+
+	return nil
+}
+
+// SetEngine records engine which collection was moved to.
+func (catalog *Catalog) SetEngine(name string, engine string) error {
+
+	catalog.mutex.Lock()
+	defer catalog.mutex.Unlock()
+
+	entry, ok := catalog.entries[name]
+	if !ok {
+		return ErrCollectionNotFound
+	}
+
+	updated := *entry
+	updated.Engine = engine
+	catalog.entries[name] = &updated
+
+	err := catalog.save()
+	if err != nil {
+		catalog.entries[name] = entry
+		return err
+	}
+
+	return nil
+}
+
+// SetStats records sequence and stats of collection, catalog is only saved
+// if they changed.
+func (catalog *Catalog) SetStats(name string, seq uint64, stats *CollectionStats) error {
+
+	catalog.mutex.Lock()
+	defer catalog.mutex.Unlock()
+
+	entry, ok := catalog.entries[name]
+	if !ok {
+		return ErrCollectionNotFound
+	}
+
+	if entry.Sequence == seq && entry.Stats != nil && *entry.Stats == *stats {
+		return nil
+	}
+
+	updated := *entry
+	updated.Sequence = seq
+	updated.Stats = stats
+	catalog.entries[name] = &updated
+
+	err := catalog.save()
+	if err != nil {
+		catalog.entries[name] = entry
+		return err
+	}
+
+	return nil
+}
+
+// Rename moves entry to a new name, directory stays the same.
+func (catalog *Catalog) Rename(name string, newName string) error {
+
+	catalog.mutex.Lock()
+	defer catalog.mutex.Unlock()
+
+	entry, ok := catalog.entries[name]
+	if !ok {
+		return ErrCollectionNotFound
+	}
+
+	if _, ok := catalog.entries[newName]; ok {
+		return ErrCollectionExists
+	}
+
+	renamed := *entry
+	renamed.Name = newName
+
+	delete(catalog.entries, name)
+	catalog.entries[newName] = &renamed
+
+	err := catalog.save()
+	if err != nil {
+		delete(catalog.entries, newName)
+		catalog.entries[name] = entry
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,355 @@
+package data_snapshot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrCheckpointNotFound        = errors.New("no such checkpoint")
+	ErrCheckpointExists          = errors.New("checkpoint exists already")
+	ErrCollectionNotInCheckpoint = errors.New("collection is not part of checkpoint")
+)
+
+const (
+	defaultCheckpointTTL    = 5 * time.Minute
+	defaultCheckpointMaxTTL = time.Hour
+	defaultStreamTTL        = time.Minute
+)
+
+// checkpointSnapshot holds snapshot of collection along with its database,
+// which stays open as long as checkpoint does.
+type checkpointSnapshot struct {
+	db       *Database
+	snapshot store.Snapshot
+	sequence uint64
+}
+
+// Checkpoint keeps snapshots of collections, so that reads across calls see
+// the same state. Snapshots are taken one after another, each at sequence of
+// its own which Sequences tells, so events which arrive meanwhile may be in
+// some collections only. It is released once its lease expires, it is
+// released by client, or any of its collections is being closed. Snapshots
+// are kept until the last read which uses them is over.
+type Checkpoint struct {
+	Name      string
+	ExpiresAt time.Time
+
+	snapshots map[string]*checkpointSnapshot
+	stream    bool
+	timer     *time.Timer
+	uses      int
+	released  bool
+	done      chan struct{}
+}
+
+// Sequences returns sequence of every collection in checkpoint.
+func (cp *Checkpoint) Sequences() map[string]uint64 {
+
+	sequences := make(map[string]uint64, len(cp.snapshots))
+	for name, cs := range cp.snapshots {
+		sequences[name] = cs.sequence
+	}
+
+	return sequences
+}
+
+type CheckpointManager struct {
+	mutex       sync.Mutex
+	dbMgr       *DatabaseManager
+	checkpoints map[string]*Checkpoint
+}
+
+func CreateCheckpointManager(dm *DatabaseManager) *CheckpointManager {
+	return &CheckpointManager{
+		dbMgr:       dm,
+		checkpoints: make(map[string]*Checkpoint),
+	}
+}
+
+// leaseOf returns lease which was asked for within checkpoint.max_ttl.
+func leaseOf(ttl time.Duration) time.Duration {
+
+	if ttl <= 0 {
+		ttl = viper.GetDuration("checkpoint.default_ttl")
+		if ttl <= 0 {
+			ttl = defaultCheckpointTTL
+		}
+	}
+
+	max := viper.GetDuration("checkpoint.max_ttl")
+	if max <= 0 {
+		max = defaultCheckpointMaxTTL
+	}
+
+	if ttl > max {
+		return max
+	}
+
+	return ttl
+}
+
+// streamLease returns lease of checkpoints of streams, which are only kept
+// for resuming streams that were cut short.
+func streamLease() time.Duration {
+
+	ttl := viper.GetDuration("checkpoint.stream_ttl")
+	if ttl <= 0 {
+		ttl = defaultStreamTTL
+	}
+
+	return leaseOf(ttl)
+}
+
+// Create takes snapshots of collections for checkpoint. Name is generated if
+// it is empty.
+func (cm *CheckpointManager) Create(name string, collections []string, ttl time.Duration) (*Checkpoint, error) {
+	return cm.create(name, collections, ttl, false)
+}
+
+// create takes snapshots without holding lock of manager, since collections
+// may have to be opened.
+func (cm *CheckpointManager) create(name string, collections []string, ttl time.Duration, stream bool) (*Checkpoint, error) {
+
+	if name == "" {
+		id := make([]byte, 16)
+		_, err := rand.Read(id)
+		if err != nil {
+			return nil, err
+		}
+
+		name = hex.EncodeToString(id)
+	}
+
+	if cm.exists(name) {
+		return nil, ErrCheckpointExists
+	}
+
+	cp := &Checkpoint{
+		Name:      name,
+		snapshots: make(map[string]*checkpointSnapshot, len(collections)),
+		stream:    stream,
+		done:      make(chan struct{}),
+	}
+
+	for _, collection := range collections {
+
+		if _, ok := cp.snapshots[collection]; ok {
+			continue
+		}
+
+		cs, err := cm.takeSnapshot(collection)
+		if err != nil {
+			cp.releaseSnapshots()
+			return nil, err
+		}
+
+		cp.snapshots[collection] = cs
+	}
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	// Name may have been taken meanwhile
+	if _, ok := cm.checkpoints[name]; ok {
+		cp.releaseSnapshots()
+		return nil, ErrCheckpointExists
+	}
+
+	lease := leaseOf(ttl)
+	cp.ExpiresAt = time.Now().Add(lease)
+	cp.timer = time.AfterFunc(lease, func() {
+		cm.release(cp)
+	})
+
+	cm.checkpoints[name] = cp
+
+	// Collections which are dropped or evicted can't wait for lease
+	for _, cs := range cp.snapshots {
+		go func(db *Database) {
+			select {
+			case <-db.draining.Done():
+				cm.release(cp)
+			case <-cp.done:
+			}
+		}(cs.db)
+	}
+
+	log.WithFields(log.Fields{
+		"checkpoint":  name,
+		"collections": len(cp.snapshots),
+		"lease":       lease,
+	}).Info("Created checkpoint")
+
+	return cp, nil
+}
+
+func (cm *CheckpointManager) exists(name string) bool {
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	_, ok := cm.checkpoints[name]
+
+	return ok
+}
+
+func (cm *CheckpointManager) takeSnapshot(collection string) (*checkpointSnapshot, error) {
+
+	db := cm.dbMgr.GetExistingDatabase(collection)
+	if db == nil {
+		return nil, ErrCollectionNotFound
+	}
+
+	snapshot, err := db.db.GetSnapshot()
+	if err != nil {
+		db.Release()
+		return nil, err
+	}
+
+	seq, err := getSequence(snapshot)
+	if err != nil {
+		snapshot.Release()
+		db.Release()
+		return nil, err
+	}
+
+	return &checkpointSnapshot{
+		db:       db,
+		snapshot: snapshot,
+		sequence: seq,
+	}, nil
+}
+
+// CreateForStream creates checkpoint of collection for a resumable snapshot
+// stream, which releases it once stream is over. Its lease is renewed
+// whenever stream resumes or breaks.
+func (cm *CheckpointManager) CreateForStream(collection string) (*Checkpoint, error) {
+
+	return cm.create("", []string{collection}, streamLease(), true)
+}
+
+// IsStream tells whether checkpoint was created for snapshot stream.
+func (cm *CheckpointManager) IsStream(name string) bool {
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cp, ok := cm.checkpoints[name]
+
+	return ok && cp.stream
+}
+
+// Renew extends lease of checkpoint from now on.
+func (cm *CheckpointManager) Renew(name string, ttl time.Duration) (*Checkpoint, error) {
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cp, ok := cm.checkpoints[name]
+	if !ok {
+		return nil, ErrCheckpointNotFound
+	}
+
+	lease := leaseOf(ttl)
+	cp.ExpiresAt = time.Now().Add(lease)
+	cp.timer.Reset(lease)
+
+	return cp, nil
+}
+
+// Release gives up checkpoint before its lease expires.
+func (cm *CheckpointManager) Release(name string) error {
+
+	cm.mutex.Lock()
+	cp, ok := cm.checkpoints[name]
+	cm.mutex.Unlock()
+
+	if !ok {
+		return ErrCheckpointNotFound
+	}
+
+	cm.release(cp)
+
+	return nil
+}
+
+// release removes checkpoint, its snapshots are released along with the last
+// read which is using them.
+func (cm *CheckpointManager) release(cp *Checkpoint) {
+
+	cm.mutex.Lock()
+	if cp.released {
+		cm.mutex.Unlock()
+		return
+	}
+
+	cp.released = true
+	cp.timer.Stop()
+	close(cp.done)
+	if cm.checkpoints[cp.Name] == cp {
+		delete(cm.checkpoints, cp.Name)
+	}
+	unused := cp.uses == 0
+	cm.mutex.Unlock()
+
+	if unused {
+		cp.releaseSnapshots()
+	}
+
+	log.WithFields(log.Fields{
+		"checkpoint": cp.Name,
+	}).Info("Released checkpoint")
+}
+
+func (cp *Checkpoint) releaseSnapshots() {
+
+	for _, cs := range cp.snapshots {
+		cs.snapshot.Release()
+		cs.db.Release()
+	}
+}
+
+// Use returns snapshot of collection in checkpoint, done has to be called
+// once reading is over.
+func (cm *CheckpointManager) Use(name string, collection string) (*Database, store.Snapshot, func(), error) {
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cp, ok := cm.checkpoints[name]
+	if !ok {
+		return nil, nil, nil, ErrCheckpointNotFound
+	}
+
+	cs, ok := cp.snapshots[collection]
+	if !ok {
+		return nil, nil, nil, ErrCollectionNotInCheckpoint
+	}
+
+	cp.uses++
+
+	return cs.db, cs.snapshot, func() {
+		cm.endUse(cp)
+	}, nil
+}
+
+func (cm *CheckpointManager) endUse(cp *Checkpoint) {
+
+	cm.mutex.Lock()
+	cp.uses--
+	last := cp.released && cp.uses == 0
+	cm.mutex.Unlock()
+
+	if last {
+		cp.releaseSnapshots()
+	}
+}
@@ -0,0 +1,282 @@
+package data_snapshot
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// openTestCollection creates collection of manager with records up to seq.
+func openTestCollection(t *testing.T, dm *DatabaseManager, name string, seq uint64) {
+
+	db := dm.GetDatabase(name)
+	if db == nil {
+		t.Fatalf("GetDatabase(%s) = nil", name)
+	}
+	defer db.Release()
+
+	for i := uint64(1); i <= seq; i++ {
+		writeTestRecord(t, db, i, float64(i))
+	}
+}
+
+// isReleased tells whether nobody holds a reference to collection, which
+// stays open.
+func isReleased(t *testing.T, dm *DatabaseManager, name string) bool {
+
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+
+	db, ok := dm.databases[name]
+	if !ok {
+		t.Fatalf("collection %s isn't open", name)
+	}
+
+	return atomic.LoadInt32(&db.active) == 0
+}
+
+func TestCheckpointSnapshot(t *testing.T) {
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	cm := CreateCheckpointManager(dm)
+	openTestCollection(t, dm, "a", 3)
+
+	cp, err := cm.Create("", []string{"a", "a"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cm.Release(cp.Name)
+
+	if seq := cp.Sequences()["a"]; seq != 3 || len(cp.Sequences()) != 1 {
+		t.Errorf("Sequences = %v, want a at 3", cp.Sequences())
+	}
+
+	// Later writes are not seen by checkpoint
+	openTestCollection(t, dm, "a", 5)
+
+	_, snapshot, end, err := cm.Use(cp.Name, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer end()
+
+	if seq, err := getSequence(snapshot); err != nil || seq != 3 {
+		t.Errorf("sequence of snapshot = %d, %v, want 3", seq, err)
+	}
+
+	if _, _, _, err := cm.Use(cp.Name, "b"); err != ErrCollectionNotInCheckpoint {
+		t.Errorf("Use of other collection = %v, want %v", err, ErrCollectionNotInCheckpoint)
+	}
+
+	if _, err := cm.Create(cp.Name, []string{"a"}, 0); err != ErrCheckpointExists {
+		t.Errorf("Create of existing name = %v, want %v", err, ErrCheckpointExists)
+	}
+
+	if _, err := cm.Create("", []string{"missing"}, 0); err != ErrCollectionNotFound {
+		t.Errorf("Create of missing collection = %v, want %v", err, ErrCollectionNotFound)
+	}
+}
+
+func TestCheckpointLeaseExpiry(t *testing.T) {
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	cm := CreateCheckpointManager(dm)
+	openTestCollection(t, dm, "a", 1)
+
+	cp, err := cm.Create("", []string{"a"}, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Renewed lease starts over
+	time.Sleep(30 * time.Millisecond)
+	if _, err := cm.Renew(cp.Name, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, _, end, err := cm.Use(cp.Name, "a"); err != nil {
+		t.Fatalf("Use within renewed lease = %v", err)
+	} else {
+		end()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, _, _, err := cm.Use(cp.Name, "a"); err != ErrCheckpointNotFound {
+		t.Errorf("Use after lease = %v, want %v", err, ErrCheckpointNotFound)
+	}
+
+	if !isReleased(t, dm, "a") {
+		t.Error("collection is still referenced by expired checkpoint")
+	}
+
+	if _, err := cm.Renew(cp.Name, 0); err != ErrCheckpointNotFound {
+		t.Errorf("Renew after lease = %v, want %v", err, ErrCheckpointNotFound)
+	}
+}
+
+func TestCheckpointReleasedOnClose(t *testing.T) {
+
+	viper.Set("collections.a.close_timeout", "10s")
+	defer viper.Set("collections.a.close_timeout", "")
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	cm := CreateCheckpointManager(dm)
+	openTestCollection(t, dm, "a", 1)
+
+	cp, err := cm.Create("", []string{"a"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Drop doesn't wait for lease of checkpoint, nor for close_timeout
+	started := time.Now()
+	if err := dm.DropCollection("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("DropCollection took %v", elapsed)
+	}
+
+	if _, _, _, err := cm.Use(cp.Name, "a"); err != ErrCheckpointNotFound {
+		t.Errorf("Use after drop = %v, want %v", err, ErrCheckpointNotFound)
+	}
+}
+
+func TestCheckpointReleaseWhileUsed(t *testing.T) {
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	cm := CreateCheckpointManager(dm)
+	openTestCollection(t, dm, "a", 2)
+
+	cp, err := cm.Create("", []string{"a"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, snapshot, end, err := cm.Use(cp.Name, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Release doesn't wait for read, which keeps its snapshot
+	released := make(chan error, 1)
+	go func() {
+		released <- cm.Release(cp.Name)
+	}()
+
+	select {
+	case err := <-released:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Release waits for read")
+	}
+
+	if seq, err := getSequence(snapshot); err != nil || seq != 2 {
+		t.Errorf("sequence of snapshot in use = %d, %v, want 2", seq, err)
+	}
+
+	if isReleased(t, dm, "a") {
+		t.Error("collection is released while snapshot is in use")
+	}
+
+	end()
+	if !isReleased(t, dm, "a") {
+		t.Error("collection is referenced after the last read")
+	}
+
+	if err := cm.Release(cp.Name); err != ErrCheckpointNotFound {
+		t.Errorf("second Release = %v, want %v", err, ErrCheckpointNotFound)
+	}
+}
+
+func TestCheckpointConcurrentUse(t *testing.T) {
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	cm := CreateCheckpointManager(dm)
+	openTestCollection(t, dm, "a", 1)
+
+	cp, err := cm.Create("", []string{"a"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				_, snapshot, end, err := cm.Use(cp.Name, "a")
+				if err == ErrCheckpointNotFound {
+					return
+				} else if err != nil {
+					t.Error(err)
+					return
+				}
+
+				// Snapshot stays readable until read is over
+				if _, err := getSequence(snapshot); err != nil {
+					t.Error(err)
+				}
+				end()
+			}
+		}()
+	}
+
+	time.Sleep(time.Millisecond)
+	cm.Release(cp.Name)
+	wg.Wait()
+
+	if !isReleased(t, dm, "a") {
+		t.Error("collection is referenced after every read is over")
+	}
+}
+
+func TestCheckpointStreamLease(t *testing.T) {
+
+	viper.Set("checkpoint.stream_ttl", "50ms")
+	defer viper.Set("checkpoint.stream_ttl", "")
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	cm := CreateCheckpointManager(dm)
+	openTestCollection(t, dm, "a", 1)
+
+	// Checkpoint of stream which was dropped doesn't wait for default lease
+	cp, err := cm.CreateForStream("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cm.IsStream(cp.Name) || time.Until(cp.ExpiresAt) > 50*time.Millisecond {
+		t.Fatalf("checkpoint of stream expires at %v", cp.ExpiresAt)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, _, _, err := cm.Use(cp.Name, "a"); err != ErrCheckpointNotFound {
+		t.Errorf("Use after lease of stream = %v, want %v", err, ErrCheckpointNotFound)
+	}
+
+	if !isReleased(t, dm, "a") {
+		t.Error("collection is still referenced by checkpoint of stream")
+	}
+}
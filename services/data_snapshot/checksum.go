@@ -0,0 +1,451 @@
+package data_snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Hash tree of records. Record belongs to the leaf which the first bits of
+// SHA-256 of its primary key choose, so records of a leaf are found by their
+// keys, and they are hashed by SHA-256 of primary key along with data as
+// clients receive it. Every node holds sum of hashes modulo 2^256 and count
+// of records under it, so changes of records are applied to the nodes on
+// their paths only. Sum keeps records which are alike from cancelling each
+// other out like XOR would, but it is no proof against records which were
+// made up to collide; it tells which parts of collections differ.
+const (
+	checksumFanout = 16
+	checksumDepth  = 3
+
+	checksumHashSize = sha256.Size
+)
+
+var (
+	checksumPrefix = []byte("mkl-")
+
+	ErrChecksumLevel = errors.New("level is deeper than hash tree")
+	ErrChecksumLeaf  = errors.New("no such leaf in hash tree")
+)
+
+type ChecksumNode struct {
+	Level uint32
+	Index uint32
+	Hash  [checksumHashSize]byte
+	Count uint64
+}
+
+func checksumKey(level uint32, index uint32) []byte {
+
+	key := make([]byte, len(checksumPrefix)+5)
+	copy(key, checksumPrefix)
+	key[len(checksumPrefix)] = byte(level)
+	binary.BigEndian.PutUint32(key[len(checksumPrefix)+1:], index)
+
+	return key
+}
+
+func nodesOfLevel(level uint32) uint32 {
+
+	n := uint32(1)
+	for i := uint32(0); i < level; i++ {
+		n *= checksumFanout
+	}
+
+	return n
+}
+
+// leafOf returns leaf of primary key, the first 4 bits of its hash choose
+// node of every level.
+func leafOf(pk []byte) uint32 {
+
+	hash := sha256.Sum256(pk)
+
+	return binary.BigEndian.Uint32(hash[:4]) >> (32 - 4*checksumDepth)
+}
+
+// recordHash hashes primary key of record along with its data.
+func recordHash(pk []byte, data []byte) [checksumHashSize]byte {
+
+	h := sha256.New()
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(pk)))
+	h.Write(size)
+	h.Write(pk)
+	h.Write(data)
+
+	var hash [checksumHashSize]byte
+	copy(hash[:], h.Sum(nil))
+
+	return hash
+}
+
+// addHash adds hash to sum modulo 2^256, hashes are big-endian numbers.
+func addHash(sum *[checksumHashSize]byte, hash *[checksumHashSize]byte) {
+
+	carry := uint16(0)
+	for i := checksumHashSize - 1; i >= 0; i-- {
+		total := uint16(sum[i]) + uint16(hash[i]) + carry
+		sum[i] = byte(total)
+		carry = total >> 8
+	}
+}
+
+// negateHash returns hash which takes hash away from sum when it is added.
+func negateHash(hash [checksumHashSize]byte) [checksumHashSize]byte {
+
+	for i := range hash {
+		hash[i] = ^hash[i]
+	}
+
+	one := [checksumHashSize]byte{checksumHashSize - 1: 1}
+	addHash(&hash, &one)
+
+	return hash
+}
+
+func encodeChecksumNode(node *ChecksumNode) []byte {
+
+	value := make([]byte, checksumHashSize+8)
+	copy(value, node.Hash[:])
+	binary.BigEndian.PutUint64(value[checksumHashSize:], node.Count)
+
+	return value
+}
+
+func decodeChecksumNode(level uint32, index uint32, value []byte) (*ChecksumNode, error) {
+
+	if len(value) != checksumHashSize+8 {
+		return nil, errors.New("invalid node of hash tree")
+	}
+
+	node := &ChecksumNode{
+		Level: level,
+		Index: index,
+		Count: binary.BigEndian.Uint64(value[checksumHashSize:]),
+	}
+
+	copy(node.Hash[:], value)
+
+	return node, nil
+}
+
+// checksumTree collects changes of nodes, which are applied to nodes in
+// store or make up a whole tree.
+type checksumTree struct {
+	nodes map[uint64]*checksumDelta
+}
+
+type checksumDelta struct {
+	hash  [checksumHashSize]byte
+	count int64
+}
+
+func newChecksumTree() *checksumTree {
+	return &checksumTree{
+		nodes: make(map[uint64]*checksumDelta),
+	}
+}
+
+func (tree *checksumTree) apply(pk []byte, data []byte, count int64) {
+
+	hash := recordHash(pk, data)
+	if count < 0 {
+		hash = negateHash(hash)
+	}
+
+	leaf := leafOf(pk)
+	for level := uint32(0); level <= checksumDepth; level++ {
+
+		index := leaf >> (4 * (checksumDepth - level))
+		id := uint64(level)<<32 | uint64(index)
+
+		delta, ok := tree.nodes[id]
+		if !ok {
+			delta = &checksumDelta{}
+			tree.nodes[id] = delta
+		}
+
+		addHash(&delta.hash, &hash)
+		delta.count += count
+	}
+}
+
+func (tree *checksumTree) add(pk []byte, data []byte) {
+	tree.apply(pk, data, 1)
+}
+
+func (tree *checksumTree) remove(pk []byte, data []byte) {
+	tree.apply(pk, data, -1)
+}
+
+// node returns node of tree which was built from scratch.
+func (tree *checksumTree) node(level uint32, index uint32) *ChecksumNode {
+
+	node := &ChecksumNode{
+		Level: level,
+		Index: index,
+	}
+
+	if delta, ok := tree.nodes[uint64(level)<<32|uint64(index)]; ok {
+		node.Hash = delta.hash
+		node.Count = uint64(delta.count)
+	}
+
+	return node
+}
+
+// putChecksum applies changes of records to nodes in batch. Root is always
+// kept, so its presence tells that tree exists.
+func (database *Database) putChecksum(batch store.Batch, tree *checksumTree) error {
+
+	for id, delta := range tree.nodes {
+
+		level := uint32(id >> 32)
+		index := uint32(id)
+		key := checksumKey(level, index)
+
+		node, err := readChecksumNode(database.db, level, index)
+		if err != nil {
+			return err
+		}
+
+		addHash(&node.Hash, &delta.hash)
+		node.Count = uint64(int64(node.Count) + delta.count)
+
+		if node.Count == 0 && level > 0 {
+			batch.Delete(key)
+			continue
+		}
+
+		batch.Put(key, encodeChecksumNode(node))
+	}
+
+	return nil
+}
+
+// initChecksum builds hash tree of database which was created before tree
+// existed.
+func (database *Database) initChecksum() error {
+
+	_, err := database.db.Get(checksumKey(0, 0))
+	if err == nil {
+		return nil
+	} else if err != store.ErrNotFound {
+		return err
+	}
+
+	tree := newChecksumTree()
+
+	iter := database.db.NewIterator(recordPrefix)
+	for iter.Next() {
+
+		data, err := database.decodeValue(iter.Key(), iter.Value())
+		if err != nil {
+			iter.Release()
+			return err
+		}
+
+		tree.add(primaryKeyOf(iter.Key()), data)
+	}
+
+	err = iter.Error()
+	iter.Release()
+	if err != nil {
+		return err
+	}
+
+	// Root of empty collection is written too
+	batch := database.db.NewBatch()
+	batch.Put(checksumKey(0, 0), encodeChecksumNode(&ChecksumNode{}))
+	err = database.putChecksum(batch, tree)
+	if err != nil {
+		return err
+	}
+
+	if count := tree.node(0, 0).Count; count > 0 {
+		log.WithFields(log.Fields{
+			"collection": database.name,
+			"count":      count,
+		}).Info("Built hash tree of collection")
+	}
+
+	return database.db.Write(batch)
+}
+
+// Checksum is root of hash tree along with nodes of a level at sequence.
+type Checksum struct {
+	Sequence uint64
+	Root     *ChecksumNode
+	Nodes    []*ChecksumNode
+}
+
+// GetChecksum returns nodes of level of hash tree at the current sequence,
+// all nodes of level if indexes is empty.
+func (database *Database) GetChecksum(level uint32, indexes []uint32) (*Checksum, error) {
+
+	snapshot, err := database.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Release()
+
+	return database.GetChecksumFrom(snapshot, level, indexes)
+}
+
+// GetChecksumFrom is like GetChecksum, with snapshot which was taken earlier,
+// e.g. by checkpoint.
+func (database *Database) GetChecksumFrom(snapshot store.Snapshot, level uint32, indexes []uint32) (*Checksum, error) {
+
+	if level > checksumDepth {
+		return nil, ErrChecksumLevel
+	}
+
+	seq, err := getSequence(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := readChecksumNode(snapshot, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := &Checksum{
+		Sequence: seq,
+		Root:     root,
+	}
+
+	for _, index := range nodeIndexes(level, indexes) {
+
+		node, err := readChecksumNode(snapshot, level, index)
+		if err != nil {
+			return nil, err
+		}
+
+		checksum.Nodes = append(checksum.Nodes, node)
+	}
+
+	return checksum, nil
+}
+
+// GetChecksumAt is like GetChecksum, with tree built out of history as of
+// sequence.
+func (database *Database) GetChecksumAt(sequence uint64, level uint32, indexes []uint32) (*Checksum, error) {
+
+	if level > checksumDepth {
+		return nil, ErrChecksumLevel
+	}
+
+	tree := newChecksumTree()
+	err := database.scanRecordsAt(sequence, func(pk []byte, version *RecordVersion) error {
+		tree.add(pk, version.Data)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := &Checksum{
+		Sequence: sequence,
+		Root:     tree.node(0, 0),
+	}
+
+	for _, index := range nodeIndexes(level, indexes) {
+		checksum.Nodes = append(checksum.Nodes, tree.node(level, index))
+	}
+
+	return checksum, nil
+}
+
+// FetchChecksumLeaf writes records under leaf of hash tree from snapshot, so
+// records of leaves which differ are compared. Leaf has no range of keys of
+// its own, so every record is read to find them.
+func (database *Database) FetchChecksumLeaf(snapshot store.Snapshot, stream packetSender, leaf uint32) error {
+
+	if leaf >= nodesOfLevel(checksumDepth) {
+		return ErrChecksumLeaf
+	}
+
+	seq, err := getSequence(snapshot)
+	if err != nil {
+		return err
+	}
+
+	writer := database.newPacketWriter(stream, seq)
+	enc := database.newEntryEncoder(&SnapshotOptions{})
+
+	iter := snapshot.NewIterator(recordPrefix)
+	defer iter.Release()
+
+	for iter.Next() {
+
+		if leafOf(primaryKeyOf(iter.Key())) != leaf {
+			continue
+		}
+
+		entry, err := enc.encode(iter.Key(), iter.Value())
+		if err != nil {
+			return err
+		}
+
+		if entry == nil {
+			continue
+		}
+
+		err = writer.Write(entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = iter.Error()
+	if err != nil {
+		return err
+	}
+
+	return writer.Flush()
+}
+
+// readChecksumNode reads node, which is empty if it doesn't exist.
+func readChecksumNode(reader store.Reader, level uint32, index uint32) (*ChecksumNode, error) {
+
+	value, err := reader.Get(checksumKey(level, index))
+	if err == store.ErrNotFound {
+		return &ChecksumNode{
+			Level: level,
+			Index: index,
+		}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return decodeChecksumNode(level, index, value)
+}
+
+// nodeIndexes returns indexes which exist in level, or all of them.
+func nodeIndexes(level uint32, indexes []uint32) []uint32 {
+
+	count := nodesOfLevel(level)
+	if len(indexes) == 0 {
+		indexes = make([]uint32, count)
+		for i := range indexes {
+			indexes[i] = uint32(i)
+		}
+
+		return indexes
+	}
+
+	valid := make([]uint32, 0, len(indexes))
+	for _, index := range indexes {
+		if index < count {
+			valid = append(valid, index)
+		}
+	}
+
+	return valid
+}
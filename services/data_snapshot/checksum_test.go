@@ -0,0 +1,166 @@
+package data_snapshot
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestChecksumHashSum(t *testing.T) {
+
+	a := recordHash([]byte("1"), []byte("data"))
+	b := recordHash([]byte("2"), []byte("data"))
+
+	if a == b {
+		t.Fatal("records with the same data have the same hash")
+	}
+
+	// Records which are alike don't cancel each other out, and removal
+	// takes record away
+	var sum [checksumHashSize]byte
+	addHash(&sum, &a)
+	addHash(&sum, &a)
+	if sum == ([checksumHashSize]byte{}) {
+		t.Error("sum of a record twice is empty")
+	}
+
+	negated := negateHash(a)
+	addHash(&sum, &negated)
+	if sum != a {
+		t.Errorf("sum after removal = %x, want %x", sum, a)
+	}
+}
+
+func TestChecksumIncremental(t *testing.T) {
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	for i := 0; i < 20; i++ {
+		writeTestRecord(t, db, uint64(i+1), float64(i))
+	}
+	writeTestRecord(t, db, 21, 3, Field{Name: "n", Value: float64(1)})
+	deleteTestRecord(t, db, 22, 5)
+
+	checksum, err := db.GetChecksum(checksumDepth, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tree which was kept along with changes matches one which is built
+	// over records
+	tree := newChecksumTree()
+	iter := db.db.NewIterator(recordPrefix)
+	for iter.Next() {
+		data, err := db.decodeValue(iter.Key(), iter.Value())
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree.add(primaryKeyOf(iter.Key()), data)
+	}
+	iter.Release()
+
+	root := tree.node(0, 0)
+	if checksum.Sequence != 22 || checksum.Root.Hash != root.Hash || checksum.Root.Count != 19 {
+		t.Errorf("root = %x with %d records at %d, want %x with 19 at 22", checksum.Root.Hash, checksum.Root.Count, checksum.Sequence, root.Hash)
+	}
+
+	for _, node := range checksum.Nodes {
+		if built := tree.node(node.Level, node.Index); node.Hash != built.Hash || node.Count != built.Count {
+			t.Errorf("leaf %d differs from built tree", node.Index)
+		}
+	}
+}
+
+func TestChecksumLeaf(t *testing.T) {
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	for i := 0; i < 10; i++ {
+		writeTestRecord(t, db, uint64(i+1), float64(i))
+	}
+
+	checksum, err := db.GetChecksum(checksumDepth, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := db.db.GetSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Release()
+
+	// Every record is streamed by the leaf which counts it
+	total := 0
+	for _, node := range checksum.Nodes {
+
+		stream := &testStream{}
+		err := db.FetchChecksumLeaf(snapshot, stream, node.Index)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if entries := stream.entries(); uint64(len(entries)) != node.Count {
+			t.Errorf("leaf %d streams %d records, counts %d", node.Index, len(entries), node.Count)
+		}
+
+		for _, packet := range stream.packets {
+			if packet.Sequence != 10 {
+				t.Errorf("packet of leaf %d is at %d, want 10", node.Index, packet.Sequence)
+			}
+		}
+
+		total += len(stream.entries())
+	}
+
+	if total != 10 {
+		t.Errorf("leaves stream %d records, want 10", total)
+	}
+
+	if err := db.FetchChecksumLeaf(snapshot, &testStream{}, nodesOfLevel(checksumDepth)); err != ErrChecksumLeaf {
+		t.Errorf("FetchChecksumLeaf out of tree = %v, want %v", err, ErrChecksumLeaf)
+	}
+}
+
+// countedLeaves returns indexes of nodes which count records.
+func countedLeaves(checksum *Checksum) []uint32 {
+
+	leaves := make([]uint32, 0)
+	for _, node := range checksum.Nodes {
+		if node.Count > 0 {
+			leaves = append(leaves, node.Index)
+		}
+	}
+
+	return leaves
+}
+
+func TestChecksumLeafOfKey(t *testing.T) {
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	writeTestRecord(t, db, 1, 1, Field{Name: "data", Value: "a"})
+
+	// Leaf stays with key when data changes
+	before, err := db.GetChecksum(checksumDepth, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestRecord(t, db, 2, 1, Field{Name: "data", Value: "b"})
+
+	after, err := db.GetChecksum(checksumDepth, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if leaf, other := countedLeaves(before), countedLeaves(after); len(leaf) != 1 || len(other) != 1 || leaf[0] != other[0] {
+		t.Fatalf("record moved between leaves %v and %v", leaf, other)
+	}
+
+	if bytes.Equal(before.Root.Hash[:], after.Root.Hash[:]) {
+		t.Error("root didn't change along with data")
+	}
+}
@@ -0,0 +1,129 @@
+package data_snapshot
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrCompactionUnsupported = errors.New("engine of collection doesn't support compaction")
+	ErrCompactionRunning     = errors.New("collection is being compacted already")
+	ErrReportUnsupported     = errors.New("engine of collection doesn't report storage")
+	ErrInvalidCompactRange   = errors.New("start of compaction range is not before its limit")
+)
+
+// CompactionResult tells how much disk space compaction gave back. Sizes are
+// zero if engine doesn't report them.
+type CompactionResult struct {
+	SizeBefore int64
+	SizeAfter  int64
+	Duration   time.Duration
+}
+
+// Compact compacts collection, so that space of deleted and overwritten keys
+// is given back. Whole collection is compacted if both start and limit are
+// empty, otherwise records whose primary keys are within them. Writes go on
+// meanwhile.
+func (database *Database) Compact(start []byte, limit []byte) (*CompactionResult, error) {
+
+	compactor, ok := database.db.(store.Compactor)
+	if !ok {
+		return nil, ErrCompactionUnsupported
+	}
+
+	if len(start) > 0 && len(limit) > 0 && bytes.Compare(start, limit) >= 0 {
+		return nil, ErrInvalidCompactRange
+	}
+
+	if !atomic.CompareAndSwapInt32(&database.compacting, 0, 1) {
+		return nil, ErrCompactionRunning
+	}
+	defer atomic.StoreInt32(&database.compacting, 0)
+
+	result := &CompactionResult{
+		SizeBefore: database.diskSize(),
+	}
+
+	var from, to []byte
+	if len(start) > 0 || len(limit) > 0 {
+		from = recordPrefix
+		if len(start) > 0 {
+			from = recordKey(start)
+		}
+
+		to = store.PrefixEnd(recordPrefix)
+		if len(limit) > 0 {
+			to = recordKey(limit)
+		}
+	}
+
+	began := time.Now()
+	err := compactor.Compact(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Duration = time.Since(began)
+	result.SizeAfter = database.diskSize()
+
+	compactionsCounter.WithLabelValues(database.name).Inc()
+
+	log.WithFields(log.Fields{
+		"collection": database.name,
+		"before":     result.SizeBefore,
+		"after":      result.SizeAfter,
+		"duration":   result.Duration,
+	}).Info("Compacted collection")
+
+	return result, nil
+}
+
+// StorageReport returns state of storage of collection.
+func (database *Database) StorageReport() (*store.Report, error) {
+
+	reporter, ok := database.db.(store.Reporter)
+	if !ok {
+		return nil, ErrReportUnsupported
+	}
+
+	report, err := reporter.Report()
+	if err != nil {
+		return nil, err
+	}
+
+	storageBytesGauge.WithLabelValues(database.name).Set(float64(report.DiskSize))
+
+	return report, nil
+}
+
+func (database *Database) diskSize() int64 {
+
+	report, err := database.StorageReport()
+	if err != nil {
+		return 0
+	}
+
+	return report.DiskSize
+}
+
+func (database *Database) runCompactor(interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for database.tick(ticker) {
+
+		_, err := database.Compact(nil, nil)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"collection": database.name,
+			}).Error(err)
+		}
+	}
+}
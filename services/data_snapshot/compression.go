@@ -0,0 +1,418 @@
+package data_snapshot
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+	log "github.com/sirupsen/logrus"
+)
+
+var dictionaryPrefix = []byte("zdict-")
+
+const (
+	frameCompressed = 'Z'
+
+	codecNone   byte = 0
+	codecSnappy byte = 's'
+	codecZstd   byte = 'z'
+
+	// Dictionary IDs below 32768 are reserved by zstd
+	firstDictionaryID       = 32768
+	dictionarySampleCount   = 1000
+	dictionaryMaxSize       = 64 << 10
+	dictionaryCheckInterval = time.Minute
+)
+
+var codecNames = map[byte]string{
+	codecSnappy: "snappy",
+	codecZstd:   "zstd",
+}
+
+// compressor holds codec of collection and zstd dictionaries. New records
+// are compressed with the latest dictionary, older ones are kept for records
+// written before it. Codecs are closed once compressor is replaced or database
+// is shut down, after users of compressor release it.
+type compressor struct {
+	codec        byte
+	encoder      *zstd.Encoder
+	decoder      *zstd.Decoder
+	dictionaries [][]byte
+	dictionaryID uint32
+
+	users  sync.RWMutex
+	closed bool
+}
+
+func getCompressionCodec(collection string) (byte, error) {
+
+	name := getCollectionString(collection, "compression")
+	switch name {
+	case "", "none":
+		return codecNone, nil
+	case "snappy":
+		return codecSnappy, nil
+	case "zstd":
+		return codecZstd, nil
+	}
+
+	return codecNone, fmt.Errorf("unknown compression \"%s\"", name)
+}
+
+func newCompressor(codec byte, dictionaries [][]byte) (*compressor, error) {
+
+	c := &compressor{
+		codec:        codec,
+		dictionaries: dictionaries,
+	}
+
+	// Decoder is always there, records may have been compressed with zstd
+	// before codec was changed
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderDicts(dictionaries...))
+	if err != nil {
+		return nil, err
+	}
+
+	c.decoder = decoder
+
+	if codec != codecZstd {
+		return c, nil
+	}
+
+	options := make([]zstd.EOption, 0)
+	if len(dictionaries) > 0 {
+		latest := dictionaries[len(dictionaries)-1]
+		info, err := zstd.InspectDictionary(latest)
+		if err != nil {
+			return nil, err
+		}
+
+		c.dictionaryID = info.ID()
+		options = append(options, zstd.WithEncoderDict(latest))
+	}
+
+	encoder, err := zstd.NewWriter(nil, options...)
+	if err != nil {
+		decoder.Close()
+		return nil, err
+	}
+
+	c.encoder = encoder
+
+	return c, nil
+}
+
+func (database *Database) getCompressor() *compressor {
+
+	c, _ := database.compressor.Load().(*compressor)
+
+	return c
+}
+
+// setCompressor replaces compressor and closes the previous one.
+func (database *Database) setCompressor(c *compressor) {
+
+	previous := database.getCompressor()
+	database.compressor.Store(c)
+
+	if previous != nil {
+		previous.close()
+	}
+}
+
+// acquireCompressor returns compressor which stays open until it is released.
+// Compressor of database which was shut down is returned as it is, so its
+// codecs fail instead.
+func (database *Database) acquireCompressor() *compressor {
+
+	for {
+		c := database.getCompressor()
+		c.users.RLock()
+		if !c.closed || database.getCompressor() == c {
+			return c
+		}
+
+		// Replaced meanwhile
+		c.users.RUnlock()
+	}
+}
+
+func (c *compressor) release() {
+	c.users.RUnlock()
+}
+
+// close waits for users of compressor and closes its codecs.
+func (c *compressor) close() {
+
+	c.users.Lock()
+	defer c.users.Unlock()
+
+	if c.closed {
+		return
+	}
+
+	c.closed = true
+	if c.encoder != nil {
+		c.encoder.Close()
+	}
+
+	c.decoder.Close()
+}
+
+func dictionaryKey(id uint32) []byte {
+
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, id)
+
+	return prefixedKey(dictionaryPrefix, b)
+}
+
+// initCompression loads dictionaries of collection, which are encrypted like
+// records since they are made of record content.
+func (database *Database) initCompression() error {
+
+	codec, err := getCompressionCodec(database.name)
+	if err != nil {
+		return err
+	}
+
+	dictionaries := make([][]byte, 0)
+	iter := database.db.NewIterator(dictionaryPrefix)
+	for iter.Next() {
+
+		data, err := database.decryptValue(iter.Key(), iter.Value())
+		if err != nil {
+			iter.Release()
+			return err
+		}
+
+		dictionaries = append(dictionaries, cloneBytes(data))
+	}
+
+	err = iter.Error()
+	iter.Release()
+	if err != nil {
+		return err
+	}
+
+	c, err := newCompressor(codec, dictionaries)
+	if err != nil {
+		return err
+	}
+
+	database.setCompressor(c)
+
+	return nil
+}
+
+// needsDictionary tells whether a dictionary should be trained once
+// collection has enough records.
+func (database *Database) needsDictionary() bool {
+
+	c := database.getCompressor()
+
+	return c.codec == codecZstd && len(c.dictionaries) == 0 && getCollectionBool(database.name, "compression_dictionary")
+}
+
+// compressValue compresses record data with codec of collection. Data is
+// kept as it is if compression doesn't make it smaller.
+func (database *Database) compressValue(data []byte) []byte {
+
+	c := database.acquireCompressor()
+	defer c.release()
+
+	if c.codec == codecNone {
+		return data
+	}
+
+	frame := []byte{frameMarker, frameCompressed, c.codec}
+
+	var compressed []byte
+	switch c.codec {
+	case codecSnappy:
+		compressed = append(frame, snappy.Encode(nil, data)...)
+	case codecZstd:
+		compressed = c.encoder.EncodeAll(data, frame)
+	}
+
+	if len(compressed) >= len(data) {
+		return data
+	}
+
+	return compressed
+}
+
+// splitCompressed returns codec and compressed payload of value.
+func splitCompressed(data []byte) (byte, []byte, bool) {
+
+	if len(data) < 3 || data[0] != frameMarker || data[1] != frameCompressed {
+		return codecNone, nil, false
+	}
+
+	return data[2], data[3:], true
+}
+
+func (database *Database) decompressValue(data []byte) ([]byte, error) {
+
+	codec, payload, ok := splitCompressed(data)
+	if !ok {
+		return data, nil
+	}
+
+	switch codec {
+	case codecSnappy:
+		return snappy.Decode(nil, payload)
+	case codecZstd:
+		c := database.acquireCompressor()
+		defer c.release()
+
+		return c.decoder.DecodeAll(payload, nil)
+	}
+
+	return nil, fmt.Errorf("unknown compression codec %d", codec)
+}
+
+// decodeValue decrypts and decompresses value which was read from database.
+func (database *Database) decodeValue(key []byte, data []byte) ([]byte, error) {
+
+	plain, err := database.decryptValue(key, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return database.decompressValue(plain)
+}
+
+// getValue reads and decodes value of key.
+func (database *Database) getValue(reader store.Reader, key []byte) ([]byte, error) {
+
+	data, err := reader.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return database.decodeValue(key, data)
+}
+
+// TrainDictionary builds a zstd dictionary from records of collection. It is
+// used for records written from now on, existing records stay as they are.
+func (database *Database) TrainDictionary() error {
+
+	samples, err := database.sampleRecords(dictionarySampleCount)
+	if err != nil {
+		return err
+	}
+
+	if len(samples) == 0 {
+		return fmt.Errorf("collection has no records to train dictionary")
+	}
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	current := database.getCompressor()
+	id := uint32(firstDictionaryID)
+	if current.dictionaryID >= firstDictionaryID {
+		id = current.dictionaryID + 1
+	}
+
+	dictionary, err := dict.BuildZstdDict(samples, dict.Options{
+		MaxDictSize: dictionaryMaxSize,
+		HashBytes:   6,
+		ZstdDictID:  id,
+	})
+	if err != nil {
+		return err
+	}
+
+	dictionaries := append(append([][]byte{}, current.dictionaries...), dictionary)
+	c, err := newCompressor(current.codec, dictionaries)
+	if err != nil {
+		return err
+	}
+
+	value, err := database.encryptValue(dictionaryKey(id), dictionary)
+	if err != nil {
+		return err
+	}
+
+	err = database.db.Put(dictionaryKey(id), value)
+	if err != nil {
+		return err
+	}
+
+	database.setCompressor(c)
+
+	log.WithFields(log.Fields{
+		"collection": database.name,
+		"dictionary": id,
+		"size":       len(dictionary),
+		"samples":    len(samples),
+	}).Info("Trained compression dictionary")
+
+	return nil
+}
+
+func (database *Database) sampleRecords(limit int) ([][]byte, error) {
+
+	snapshot, err := database.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Release()
+
+	samples := make([][]byte, 0, limit)
+	iter := snapshot.NewIterator(recordPrefix)
+	defer iter.Release()
+
+	for len(samples) < limit && iter.Next() {
+
+		data, err := database.decodeValue(iter.Key(), iter.Value())
+		if err != nil {
+			return nil, err
+		}
+
+		samples = append(samples, cloneBytes(data))
+	}
+
+	return samples, iter.Error()
+}
+
+// runDictionaryTrainer waits until collection has enough records to train the
+// first dictionary.
+func (database *Database) runDictionaryTrainer() {
+
+	ticker := time.NewTicker(dictionaryCheckInterval)
+	defer ticker.Stop()
+
+	for database.tick(ticker) {
+
+		_, stats, err := database.GetStats()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"collection": database.name,
+			}).Error(err)
+			continue
+		}
+
+		if stats.RecordCount < dictionarySampleCount {
+			continue
+		}
+
+		err = database.TrainDictionary()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"collection": database.name,
+			}).Error(err)
+			continue
+		}
+
+		return
+	}
+}
@@ -0,0 +1,114 @@
+package data_snapshot
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func testCompressor(t *testing.T, codec byte) *compressor {
+
+	c, err := newCompressor(codec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestCompressValueFraming(t *testing.T) {
+
+	compressible := bytes.Repeat([]byte(`{"name":"value"}`), 64)
+	short := []byte(`{"a":1}`)
+
+	tests := []struct {
+		codec  byte
+		data   []byte
+		framed bool
+	}{
+		{codecNone, compressible, false},
+		{codecSnappy, compressible, true},
+		{codecSnappy, short, false},
+		{codecZstd, compressible, true},
+		{codecZstd, short, false},
+	}
+
+	for _, test := range tests {
+
+		database := &Database{}
+		database.setCompressor(testCompressor(t, test.codec))
+
+		value := database.compressValue(test.data)
+
+		codec, _, framed := splitCompressed(value)
+		if framed != test.framed || framed && codec != test.codec {
+			t.Errorf("compressValue with codec %q is framed %v with codec %q, want %v", test.codec, framed, codec, test.framed)
+		}
+
+		if !framed && !bytes.Equal(value, test.data) {
+			t.Errorf("compressValue with codec %q changed data it didn't compress", test.codec)
+		}
+
+		// Values stay readable once codec of collection is changed
+		database.setCompressor(testCompressor(t, codecNone))
+
+		data, err := database.decompressValue(value)
+		if err != nil || !bytes.Equal(data, test.data) {
+			t.Errorf("decompressValue with codec %q = %q, %v", test.codec, data, err)
+		}
+
+		database.getCompressor().close()
+	}
+}
+
+func TestDecompressValueUnknownCodec(t *testing.T) {
+
+	database := &Database{}
+	database.setCompressor(testCompressor(t, codecNone))
+	defer database.getCompressor().close()
+
+	_, err := database.decompressValue([]byte{frameMarker, frameCompressed, 'x', 1, 2})
+	if err == nil {
+		t.Error("decompressValue of unknown codec succeeded")
+	}
+}
+
+func TestCompressorSwap(t *testing.T) {
+
+	database := &Database{}
+	database.setCompressor(testCompressor(t, codecZstd))
+
+	data := bytes.Repeat([]byte(`{"name":"value"}`), 64)
+
+	// Compressors are replaced while values are compressed and read
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 200; j++ {
+
+				value, err := database.decompressValue(database.compressValue(data))
+				if err != nil || !bytes.Equal(value, data) {
+					t.Errorf("value changed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		database.setCompressor(testCompressor(t, codecZstd))
+	}
+
+	wg.Wait()
+
+	previous := database.getCompressor()
+	database.setCompressor(testCompressor(t, codecZstd))
+	if !previous.closed {
+		t.Error("replaced compressor isn't closed")
+	}
+
+	database.getCompressor().close()
+}
@@ -0,0 +1,42 @@
+package data_snapshot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Settings of specific collection are placed in [collections.<name>] and fall
+// back to the same key in [database] when the collection doesn't set it.
+func collectionConfigKey(collection string, key string) string {
+
+	name := fmt.Sprintf("collections.%s.%s", collection, key)
+	if viper.IsSet(name) {
+		return name
+	}
+
+	return fmt.Sprintf("database.%s", key)
+}
+
+func getCollectionString(collection string, key string) string {
+	return viper.GetString(collectionConfigKey(collection, key))
+}
+
+func getCollectionDuration(collection string, key string, defaultValue time.Duration) time.Duration {
+
+	value := viper.GetDuration(collectionConfigKey(collection, key))
+	if value <= 0 {
+		return defaultValue
+	}
+
+	return value
+}
+
+func getCollectionInt(collection string, key string) int {
+	return viper.GetInt(collectionConfigKey(collection, key))
+}
+
+func getCollectionBool(collection string, key string) bool {
+	return viper.GetBool(collectionConfigKey(collection, key))
+}
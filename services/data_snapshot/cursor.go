@@ -0,0 +1,87 @@
+package data_snapshot
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	ErrInvalidCursor  = errors.New("invalid cursor")
+	ErrCursorMismatch = errors.New("cursor doesn't match request")
+)
+
+const (
+	cursorVersion = 3
+	cursorFields  = 4
+)
+
+// SnapshotCursor tells where stream of snapshot stopped. Checkpoint holds the
+// view which stream reads, so only streams of checkpoints have cursors, and
+// collection is the one which stream read. Last key is the last record key
+// sent, or the last entry of index if filter of stream is served by one.
+// Digest is of filter, fields and partition of stream, which the resumed
+// stream has to request again. Clients only pass cursors back as they are.
+type SnapshotCursor struct {
+	Checkpoint string
+	Collection string
+	Index      string
+	Digest     uint64
+	LastKey    []byte
+}
+
+func (cursor *SnapshotCursor) Encode() []byte {
+
+	size := 9 + cursorFields*binary.MaxVarintLen64 + len(cursor.Checkpoint) + len(cursor.Collection) + len(cursor.Index) + len(cursor.LastKey)
+	buf := make([]byte, 0, size)
+	buf = append(buf, cursorVersion)
+	buf = buf[:9]
+	binary.BigEndian.PutUint64(buf[1:9], cursor.Digest)
+	buf = appendCursorField(buf, []byte(cursor.Checkpoint))
+	buf = appendCursorField(buf, []byte(cursor.Collection))
+	buf = appendCursorField(buf, []byte(cursor.Index))
+
+	return appendCursorField(buf, cursor.LastKey)
+}
+
+func appendCursorField(buf []byte, data []byte) []byte {
+
+	buf = appendUvarint(buf, uint64(len(data)))
+
+	return append(buf, data...)
+}
+
+// DecodeCursor decodes cursor which client passed back, cursors without
+// checkpoint or collection are rejected.
+func DecodeCursor(data []byte) (*SnapshotCursor, error) {
+
+	if len(data) < 9 || data[0] != cursorVersion {
+		return nil, ErrInvalidCursor
+	}
+
+	digest := binary.BigEndian.Uint64(data[1:9])
+
+	fields := make([][]byte, 0, cursorFields)
+	data = data[9:]
+	for i := 0; i < cursorFields; i++ {
+
+		n, size := binary.Uvarint(data)
+		if size <= 0 || uint64(len(data)-size) < n {
+			return nil, ErrInvalidCursor
+		}
+
+		fields = append(fields, data[size:size+int(n)])
+		data = data[size+int(n):]
+	}
+
+	if len(data) > 0 || len(fields[0]) == 0 || len(fields[1]) == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &SnapshotCursor{
+		Checkpoint: string(fields[0]),
+		Collection: string(fields[1]),
+		Index:      string(fields[2]),
+		Digest:     digest,
+		LastKey:    cloneBytes(fields[3]),
+	}, nil
+}
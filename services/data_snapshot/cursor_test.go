@@ -0,0 +1,211 @@
+package data_snapshot
+
+import (
+	"bytes"
+	"testing"
+
+	pb "gravity-data-snapshot/pb"
+)
+
+func TestCursorEncoding(t *testing.T) {
+
+	tests := []*SnapshotCursor{
+		{Checkpoint: "cp", Collection: "t"},
+		{Checkpoint: "cp", Collection: "t", Digest: 1, LastKey: []byte("record:a")},
+		{Checkpoint: "cp", Collection: "t", Index: "by_g", Digest: ^uint64(0), LastKey: []byte("index:by_g:\x00\x01")},
+		{Checkpoint: "stream-1", Collection: "orders", Index: "by_g", Digest: 42},
+	}
+
+	for _, cursor := range tests {
+
+		decoded, err := DecodeCursor(cursor.Encode())
+		if err != nil {
+			t.Fatalf("DecodeCursor(%+v): %v", cursor, err)
+		}
+
+		if decoded.Checkpoint != cursor.Checkpoint || decoded.Collection != cursor.Collection || decoded.Index != cursor.Index ||
+			decoded.Digest != cursor.Digest || !bytes.Equal(decoded.LastKey, cursor.LastKey) {
+			t.Errorf("DecodeCursor(%+v) = %+v", cursor, decoded)
+		}
+	}
+}
+
+func TestDecodeCursorErrors(t *testing.T) {
+
+	valid := (&SnapshotCursor{Checkpoint: "cp", Collection: "t", LastKey: []byte("record:a")}).Encode()
+
+	tests := [][]byte{
+		(&SnapshotCursor{Collection: "t", LastKey: []byte("record:a")}).Encode(),
+		(&SnapshotCursor{Checkpoint: "cp", LastKey: []byte("record:a")}).Encode(),
+		nil,
+		[]byte("junk"),
+		append([]byte{1}, valid[1:]...),
+		valid[:9],
+		valid[:len(valid)-1],
+		append(append([]byte{}, valid...), 0),
+	}
+
+	for _, data := range tests {
+		if _, err := DecodeCursor(data); err != ErrInvalidCursor {
+			t.Errorf("DecodeCursor(%q) = %v, want %v", data, err, ErrInvalidCursor)
+		}
+	}
+}
+
+func TestSnapshotOptionsDigest(t *testing.T) {
+
+	filter := func(source string) *Filter {
+		f, err := ParseFilter(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	mask := func(include []string, exclude []string) *FieldMask {
+		m, err := NewFieldMask(include, exclude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	tests := []struct {
+		a    *SnapshotOptions
+		b    *SnapshotOptions
+		same bool
+	}{
+		{&SnapshotOptions{}, &SnapshotOptions{Compressed: true, Checkpoint: "cp"}, true},
+		{&SnapshotOptions{Filter: filter("g = 1")}, &SnapshotOptions{Filter: filter("g = 1")}, true},
+		{&SnapshotOptions{Filter: filter("g = 1")}, &SnapshotOptions{Filter: filter("g = 2")}, false},
+		{&SnapshotOptions{Filter: filter("g = 1")}, &SnapshotOptions{}, false},
+		{&SnapshotOptions{Fields: mask([]string{"b", "a.x"}, nil)}, &SnapshotOptions{Fields: mask([]string{"a.x", "b"}, nil)}, true},
+		{&SnapshotOptions{Fields: mask([]string{"a"}, nil)}, &SnapshotOptions{Fields: mask(nil, []string{"a"})}, false},
+		{&SnapshotOptions{Fields: mask([]string{"a.b"}, nil)}, &SnapshotOptions{Fields: mask([]string{"a", "b"}, nil)}, false},
+		{&SnapshotOptions{Start: []byte("a")}, &SnapshotOptions{End: []byte("a")}, false},
+		{&SnapshotOptions{Start: []byte("a"), End: []byte("b")}, &SnapshotOptions{Start: []byte("a"), End: []byte("b")}, true},
+		{&SnapshotOptions{Start: []byte("ab")}, &SnapshotOptions{Start: []byte("a"), End: []byte("b")}, false},
+	}
+
+	for i, test := range tests {
+		if (test.a.digest() == test.b.digest()) != test.same {
+			t.Errorf("test %d: digests equal is %v, want %v", i, !test.same, test.same)
+		}
+	}
+}
+
+func TestFetchSnapshotResume(t *testing.T) {
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	for i := 0; i < 3*packetSize+10; i++ {
+		writeTestRecord(t, db, uint64(i+1), float64(i), Field{Name: "g", Value: float64(i % 3)})
+	}
+
+	filter, err := ParseFilter("g != 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make([][]byte, 0)
+	iter := db.db.NewIterator(recordPrefix)
+	for iter.Next() {
+		keys = append(keys, cloneBytes(iter.Key()))
+	}
+	iter.Release()
+
+	tests := []struct {
+		name string
+		opts *SnapshotOptions
+	}{
+		{"all", &SnapshotOptions{}},
+		{"filter", &SnapshotOptions{Filter: filter}},
+		{"partition", &SnapshotOptions{Start: keys[100], End: keys[250]}},
+	}
+
+	snapshot, err := db.db.GetSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Release()
+
+	for _, test := range tests {
+
+		full := &testStream{}
+		err := db.FetchSnapshotFrom(snapshot, full, test.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		// Stream of the current records has nothing to resume on
+		for _, packet := range full.packets {
+			if len(packet.Cursor) > 0 {
+				t.Fatalf("%s: packet of stream without checkpoint has cursor", test.name)
+			}
+		}
+
+		// Resume after every broken stream until stream is over
+		resumed := make([]*pb.SnapshotEntry, 0)
+		opts := *test.opts
+		opts.Checkpoint = "cp"
+		for {
+			stream := &testStream{limit: 1}
+			err := db.FetchSnapshotFrom(snapshot, stream, &opts)
+			for _, packet := range stream.packets {
+				if len(packet.Cursor) == 0 {
+					t.Fatalf("%s: packet without cursor", test.name)
+				}
+
+				cursor, decodeErr := DecodeCursor(packet.Cursor)
+				if decodeErr != nil {
+					t.Fatalf("%s: %v", test.name, decodeErr)
+				}
+
+				opts.Cursor = cursor
+			}
+
+			resumed = append(resumed, stream.entries()...)
+			if err != errStreamBroken {
+				break
+			}
+		}
+
+		if len(resumed) != len(full.entries()) {
+			t.Fatalf("%s: resumed %d entries, want %d", test.name, len(resumed), len(full.entries()))
+		}
+
+		for i, entry := range full.entries() {
+			if !bytes.Equal(resumed[i].Data, entry.Data) {
+				t.Errorf("%s: entry %d is %s, want %s", test.name, i, resumed[i].Data, entry.Data)
+			}
+		}
+
+		// Cursor only goes with options, checkpoint and collection of its
+		// stream
+		other := *test.opts
+		other.Checkpoint = "cp"
+		other.Cursor = opts.Cursor
+		other.Fields = &FieldMask{}
+		err = db.FetchSnapshotFrom(snapshot, &testStream{}, &other)
+		if err != ErrCursorMismatch {
+			t.Errorf("%s: FetchSnapshot with other options = %v, want %v", test.name, err, ErrCursorMismatch)
+		}
+
+		other = opts
+		other.Checkpoint = "other"
+		err = db.FetchSnapshotFrom(snapshot, &testStream{}, &other)
+		if err != ErrCursorMismatch {
+			t.Errorf("%s: FetchSnapshot of other checkpoint = %v, want %v", test.name, err, ErrCursorMismatch)
+		}
+
+		cursor := *opts.Cursor
+		cursor.Collection = "other"
+		other = opts
+		other.Cursor = &cursor
+		err = db.FetchSnapshotFrom(snapshot, &testStream{}, &other)
+		if err != ErrCursorMismatch {
+			t.Errorf("%s: FetchSnapshot with cursor of other collection = %v, want %v", test.name, err, ErrCursorMismatch)
+		}
+	}
+}
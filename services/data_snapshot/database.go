@@ -2,38 +2,151 @@ package data_snapshot
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/gob"
 	"encoding/json"
-	"fmt"
+	"sync"
+	"sync/atomic"
+
 	pb "gravity-data-snapshot/pb"
+	"gravity-data-snapshot/services/data_snapshot/store"
 
 	log "github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
+const deleteChunkSize = 1000
+
 type Database struct {
-	name string
-	db   *leveldb.DB
+	name    string
+	engine  string
+	db      store.Store
+	mutex   sync.Mutex
+	indexes []*Index
+	stats   *CollectionStats
+	schema  *CollectionSchema
+
+	expiration     *ExpirationPolicy
+	expiredHandler ExpiredHandler
+	history        *HistoryPolicy
+	historyHorizon uint64
+
+	quota             *QuotaPolicy
+	rejectedHandler   RejectedHandler
+	hasRejected       int32
+	notifyingRejected int32
+	keyring           atomic.Value
+	compressor        atomic.Value
+
+	// Lifecycle of handle, see lifecycle.go
+	ctx      context.Context
+	cancel   context.CancelFunc
+	draining context.Context
+	drain    context.CancelFunc
+	refs     sync.WaitGroup
+	active   int32
+	lastUsed int64
+	workers  sync.WaitGroup
+	frozen   bool
+
+	compacting int32
+	rebuilding int32
 }
 
-func OpenDatabase(dbname string) *Database {
-
-	dbpath := fmt.Sprintf("%s/%s", viper.GetString("database.dbpath"), dbname)
+// OpenDatabase opens database of collection in dbpath with engine, both of
+// which come from catalog.
+func OpenDatabase(dbname string, engine string, dbpath string) (*Database, error) {
 
 	// Open database
-	db, err := leveldb.OpenFile(dbpath, nil)
+	db, err := store.Open(engine, dbpath)
 	if err != nil {
-		log.Error(err)
-		return nil
+		return nil, err
+	}
+
+	return initDatabase(dbname, engine, db)
+}
+
+// RecoverDatabase opens database whose storage was found corrupted, with
+// recovery of its engine.
+func RecoverDatabase(dbname string, engine string, dbpath string) (*Database, error) {
+
+	db, err := store.Recover(engine, dbpath)
+	if err != nil {
+		return nil, err
+	}
+
+	return initDatabase(dbname, engine, db)
+}
+
+func initDatabase(dbname string, engine string, db store.Store) (*Database, error) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	draining, drain := context.WithCancel(ctx)
+	database := &Database{
+		name:       dbname,
+		engine:     engine,
+		db:         db,
+		expiration: getExpirationPolicy(dbname),
+		history:    getHistoryPolicy(dbname),
+		ctx:        ctx,
+		cancel:     cancel,
+		draining:   draining,
+		drain:      drain,
+	}
+
+	initializers := []func() error{
+		database.initEncryption,
+		database.initCompression,
+		database.initDeltaHorizon,
+		database.initStats,
+		database.initSchema,
+		database.initChecksum,
+		database.initQuota,
+		database.initIndexes,
+		database.initFrozenState,
+		database.initRebuildState,
+		database.initHistory,
+	}
+
+	for _, initialize := range initializers {
+		err := initialize()
+		if err != nil {
+			database.shutdown()
+			db.Close()
+			return nil, err
+		}
+	}
+
+	database.startWorker(database.runTombstoneCollector)
+
+	rotation := getCollectionDuration(dbname, "data_key_rotation", 0)
+	if rotation > 0 && database.getKeyring() != nil {
+		database.startWorker(func() {
+			database.runKeyRotation(rotation)
+		})
+	}
+
+	if database.expiration != nil {
+		database.startWorker(database.runExpirationSweeper)
+	}
+
+	if database.history != nil && database.history.Retention > 0 {
+		database.startWorker(database.runHistoryCollector)
+	}
+
+	compaction := getCollectionDuration(dbname, "compaction_interval", 0)
+	if compaction > 0 {
+		database.startWorker(func() {
+			database.runCompactor(compaction)
+		})
 	}
 
-	return &Database{
-		name: dbname,
-		db:   db,
+	if database.needsDictionary() {
+		database.startWorker(database.runDictionaryTrainer)
 	}
+
+	return database, nil
 }
 
 func GetBytes(key interface{}) ([]byte, error) {
@@ -46,6 +159,26 @@ func GetBytes(key interface{}) ([]byte, error) {
 	return buf.Bytes(), nil
 
 }
+
+// primaryKeyOfValue returns primary key of record whose primary field has
+// value, the same way as events are keyed.
+func (database *Database) primaryKeyOfValue(value interface{}) ([]byte, error) {
+
+	key, err := GetBytes(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return database.hashPrimaryKey(key), nil
+}
+
+func cloneBytes(data []byte) []byte {
+	buf := make([]byte, len(data))
+	copy(buf, data)
+
+	return buf
+}
+
 func Uint64ToBytes(n uint64) []byte {
 	b := make([]byte, 8)
 	binary.LittleEndian.PutUint64(b, uint64(n))
@@ -57,8 +190,35 @@ func BytesToUint64(data []byte) uint64 {
 	return uint64(binary.LittleEndian.Uint64(data))
 }
 
+// ProcessData applies event to collection, or queues it while collection is
+// frozen.
 func (database *Database) ProcessData(sequence uint64, projection *Projection) error {
 
+	err := database.processData(sequence, projection)
+	database.notifyRejected()
+
+	return err
+}
+
+func (database *Database) processData(sequence uint64, projection *Projection) error {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if database.frozen {
+		return database.queueEvent(sequence, projection)
+	}
+
+	err := database.applyEvent(sequence, projection)
+	if err == ErrQuotaExceeded {
+		return database.stopIngestion(sequence, projection)
+	}
+
+	return err
+}
+
+func (database *Database) applyEvent(sequence uint64, projection *Projection) error {
+
 	// Get primary key
 	var primaryKey []byte
 	hasPrimary := false
@@ -76,18 +236,32 @@ func (database *Database) ProcessData(sequence uint64, projection *Projection) e
 	}
 
 	// Add prefix
-	primaryKey = bytes.Join([][]byte{[]byte("key"), primaryKey}, []byte("-"))
+	primaryKey = bytes.Join([][]byte{[]byte("key"), database.hashPrimaryKey(primaryKey)}, []byte("-"))
+
+	// Skip events which were applied already, e.g. replayed after restore
+	seqData, err := database.db.Get(sequenceKey)
+	if err == nil && BytesToUint64(seqData) >= sequence {
+		return nil
+	} else if err != nil && err != store.ErrNotFound {
+		return err
+	}
 
 	if projection.Method == "delete" {
-		return database.DeleteRecord(sequence, primaryKey)
+
+		keyData, err := getPrimaryKeyData(projection)
+		if err != nil {
+			return err
+		}
+
+		return database.DeleteRecord(sequence, primaryKey, keyData)
 	}
 
 	// Update existing record
 	if hasPrimary == true {
-		data, err := database.db.Get(primaryKey, nil)
+		data, err := database.getValue(database.db, primaryKey)
 		if err != nil {
 
-			if err == leveldb.ErrNotFound {
+			if err == store.ErrNotFound {
 				// New record
 				return database.UpdateRecord(sequence, primaryKey, nil, projection)
 			}
@@ -102,9 +276,25 @@ func (database *Database) ProcessData(sequence uint64, projection *Projection) e
 	return database.UpdateRecord(sequence, primaryKey, nil, projection)
 }
 
+// getPrimaryKeyData returns primary field of projection in JSON, which is how
+// deleted and expired records are identified to clients.
+func getPrimaryKeyData(projection *Projection) ([]byte, error) {
+
+	for _, field := range projection.Fields {
+		if field.Primary == true {
+			return json.Marshal(map[string]interface{}{
+				field.Name: field.Value,
+			})
+		}
+	}
+
+	return nil, nil
+}
+
 func (database *Database) UpdateRecord(sequence uint64, key []byte, origData []byte, updates *Projection) error {
 
 	orig := make(map[string]interface{})
+	var prev map[string]interface{}
 
 	if origData != nil {
 		// Parsing original data
@@ -112,6 +302,8 @@ func (database *Database) UpdateRecord(sequence uint64, key []byte, origData []b
 		if err != nil {
 			return err
 		}
+
+		prev = copyDocument(orig)
 	}
 
 	for _, field := range updates.Fields {
@@ -124,39 +316,183 @@ func (database *Database) UpdateRecord(sequence uint64, key []byte, origData []b
 		return err
 	}
 
+	countDelta := int64(0)
+	if origData == nil {
+		countDelta = 1
+	}
+
+	bytesDelta := int64(len(data) - len(origData))
+	if violation := database.checkQuota(countDelta, bytesDelta, len(data)); violation != nil {
+		apply, err := database.enforceQuota(sequence, updates, violation)
+		if !apply {
+			return err
+		}
+	}
+
 	// Write to database
-	batch := new(leveldb.Batch)
-	batch.Put([]byte("seq"), Uint64ToBytes(sequence))
-	batch.Put(key, data)
+	value, err := database.encryptValue(key, database.compressValue(data))
+	if err != nil {
+		return err
+	}
+
+	pk := primaryKeyOf(key)
+	batch := database.db.NewBatch()
+	batch.Put(sequenceKey, Uint64ToBytes(sequence))
+	batch.Put(key, value)
+	batch.Delete(tombstoneKey(pk))
+	database.updateIndexes(batch, pk, prev, orig)
+
+	err = database.trackChange(batch, sequence, pk)
+	if err != nil {
+		return err
+	}
+
+	err = database.trackVersion(batch, sequence, pk, data, false)
+	if err != nil {
+		return err
+	}
+
+	keyData, err := getPrimaryKeyData(updates)
+	if err != nil {
+		return err
+	}
+
+	err = database.updateExpiration(batch, pk, orig, keyData)
+	if err != nil {
+		return err
+	}
 
-	return database.db.Write(batch, nil)
+	schema, err := database.observeSchema(batch, sequence, updates.Fields)
+	if err != nil {
+		return err
+	}
+
+	tree := newChecksumTree()
+	if origData != nil {
+		tree.remove(pk, origData)
+	}
+	tree.add(pk, data)
+
+	err = database.putChecksum(batch, tree)
+	if err != nil {
+		return err
+	}
+
+	err = database.writeRecords(batch, sequence, countDelta, bytesDelta)
+	if err != nil {
+		return err
+	}
+
+	database.schema = schema
+
+	return nil
 }
 
-func (database *Database) DeleteRecord(sequence uint64, key []byte) error {
+// DeleteRecord removes record and leaves a tombstone with primary key data
+// behind if record exists.
+func (database *Database) DeleteRecord(sequence uint64, key []byte, keyData []byte) error {
 
 	// Write to database
-	batch := new(leveldb.Batch)
-	batch.Put([]byte("seq"), Uint64ToBytes(sequence))
-	batch.Delete(key)
+	batch := database.db.NewBatch()
+	batch.Put(sequenceKey, Uint64ToBytes(sequence))
+
+	origData, err := database.getValue(database.db, key)
+	if err == nil {
+		pk := primaryKeyOf(key)
+
+		orig := make(map[string]interface{})
+		err = json.Unmarshal(origData, &orig)
+		if err != nil {
+			return err
+		}
+
+		err = database.putTombstone(batch, sequence, pk, keyData)
+		if err != nil {
+			return err
+		}
+
+		err = database.trackVersion(batch, sequence, pk, keyData, true)
+		if err != nil {
+			return err
+		}
+
+		batch.Delete(key)
+		database.updateIndexes(batch, pk, orig, nil)
+
+		err = database.updateExpiration(batch, pk, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		tree := newChecksumTree()
+		tree.remove(pk, origData)
 
-	return database.db.Write(batch, nil)
+		err = database.putChecksum(batch, tree)
+		if err != nil {
+			return err
+		}
+
+		return database.writeRecords(batch, sequence, -1, -int64(len(origData)))
+	} else if err != store.ErrNotFound {
+		return err
+	}
+
+	return database.db.Write(batch)
 }
 
-func (database *Database) GetSequence() (uint64, error) {
+// deletePrefix removes all keys with prefix in chunks, so that writers are
+// not blocked for long.
+func (database *Database) deletePrefix(prefix []byte) error {
 
-	// Getting create snapshot
-	snapshot, err := database.db.GetSnapshot()
+	for {
+		count, err := database.deletePrefixChunk(prefix, deleteChunkSize)
+		if err != nil {
+			return err
+		}
+
+		if count < deleteChunkSize {
+			return nil
+		}
+	}
+}
+
+func (database *Database) deletePrefixChunk(prefix []byte, size int) (int, error) {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	batch := database.db.NewBatch()
+	count := 0
+
+	iter := database.db.NewIterator(prefix)
+	for count < size && iter.Next() {
+		batch.Delete(iter.Key())
+		count++
+	}
+
+	err := iter.Error()
+	iter.Release()
 	if err != nil {
 		return 0, err
 	}
-	defer snapshot.Release()
+
+	if count == 0 {
+		return 0, nil
+	}
+
+	return count, database.db.Write(batch)
+}
+
+func getSequence(reader store.Reader) (uint64, error) {
 
 	// Getting current sequence number of event
 	var seq uint64
-	seqData, err := snapshot.Get([]byte("seq"), nil)
-	if err != nil {
+	seqData, err := reader.Get(sequenceKey)
+	if err == store.ErrNotFound {
 		log.Warn("Not found seq in database, it will be set zero by default.")
 		seq = 0
+	} else if err != nil {
+		return 0, err
 	} else {
 		seq = BytesToUint64(seqData)
 	}
@@ -164,55 +500,189 @@ func (database *Database) GetSequence() (uint64, error) {
 	return seq, nil
 }
 
-func (database *Database) FetchSnapshot(stream pb.DataSnapshot_GetSnapshotServer) error {
+func (database *Database) GetSequence() (uint64, error) {
+
+	// Getting create snapshot
+	snapshot, err := database.db.GetSnapshot()
+	if err != nil {
+		return 0, err
+	}
+	defer snapshot.Release()
+
+	return getSequence(snapshot)
+}
+
+// SnapshotOptions tells which records are sent and how. Records are sent
+// in their compressed form if client accepts it, along with dictionaries in
+// the first packet, unless fields are masked. Filter and fields are optional.
+// Packets of streams of checkpoints carry cursors, and stream resumes after
+// cursor if it is set, as long as collection, checkpoint, filter, fields and
+// partition are the same as of the stream which cursor came from. Records are limited to partition of keys from start to
+// end if they are set, indexes aren't used then.
+type SnapshotOptions struct {
+	Compressed bool
+	Filter     *Filter
+	Fields     *FieldMask
+	Checkpoint string
+	Cursor     *SnapshotCursor
+	Start      []byte
+	End        []byte
+}
+
+// digest hashes filter, fields and partition of options, which cursors are
+// only taken back along with.
+func (opts *SnapshotOptions) digest() uint64 {
+
+	h := sha256.New()
+	write := func(data []byte) {
+		h.Write(appendUvarint(nil, uint64(len(data))))
+		h.Write(data)
+	}
+
+	if opts.Filter != nil {
+		write([]byte(opts.Filter.String()))
+	} else {
+		write(nil)
+	}
+
+	if opts.Fields != nil {
+		write([]byte(opts.Fields.String()))
+	} else {
+		write(nil)
+	}
+
+	write(opts.Start)
+	write(opts.End)
+
+	return binary.BigEndian.Uint64(h.Sum(nil))
+}
+
+// FetchSnapshot streams records of the current sequence.
+func (database *Database) FetchSnapshot(stream pb.DataSnapshot_GetSnapshotServer, opts *SnapshotOptions) error {
 
 	// Getting create snapshot
 	snapshot, err := database.db.GetSnapshot()
 	if err != nil {
 		return err
 	}
+	defer snapshot.Release()
+
+	return database.FetchSnapshotFrom(snapshot, stream, opts)
+}
+
+// FetchSnapshotFrom streams records of snapshot which was taken earlier,
+// e.g. by checkpoint.
+func (database *Database) FetchSnapshotFrom(snapshot store.Snapshot, stream pb.DataSnapshot_GetSnapshotServer, opts *SnapshotOptions) error {
 
 	// Getting current sequence number of event
-	var seq uint64
-	seqData, err := snapshot.Get([]byte("seq"), nil)
+	seq, err := getSequence(snapshot)
 	if err != nil {
-		log.Warn("Not found seq in database, it will be set zero by default.")
-		seq = 0
-	} else {
-		seq = BytesToUint64(seqData)
+		return err
 	}
 
-	log.WithFields(log.Fields{
+	fields := log.Fields{
 		"collection": database.name,
 		"seq":        seq,
-	}).Info("Client requests data")
+	}
+
+	partitioned := opts.Start != nil || opts.End != nil
+	if partitioned {
+		fields["partitioned"] = true
+	}
+
+	var scan *indexScan
+	index := ""
+	if opts.Filter != nil {
+		fields["filter"] = opts.Filter.String()
+	}
+
+	if opts.Filter != nil && !partitioned {
+		scan = database.planIndexScan(snapshot, opts.Filter)
+		if scan != nil {
+			index = scan.index.Name
+			fields["index"] = index
+		}
+	}
+
+	// Resumed stream has to walk the same keys
+	var after []byte
+	digest := opts.digest()
+	if opts.Cursor != nil {
+		if opts.Cursor.Collection != database.name || opts.Cursor.Checkpoint != opts.Checkpoint ||
+			opts.Cursor.Digest != digest || opts.Cursor.Index != index {
+			return ErrCursorMismatch
+		}
 
-	iter := snapshot.NewIterator(util.BytesPrefix([]byte("key-")), nil)
+		keyPrefix := recordPrefix
+		if scan != nil {
+			keyPrefix = scan.index.prefix()
+		}
+
+		if !bytes.HasPrefix(opts.Cursor.LastKey, keyPrefix) {
+			return ErrInvalidCursor
+		}
+
+		after = opts.Cursor.LastKey
+		fields["resumed"] = true
+	}
+
+	log.WithFields(fields).Info("Client requests data")
 
 	// Prepare packet
-	packet := &pb.SnapshotPacket{
-		Collection: database.name,
-		Sequence:   seq,
-		Entries:    make([]*pb.SnapshotEntry, 0),
+	writer := database.newPacketWriter(stream, seq)
+	if opts.Compressed && opts.Fields == nil {
+		writer.SetDictionaries(database.getCompressor().dictionaries)
 	}
 
-	for iter.Next() {
+	// Stream of the current records can't be resumed on the same view
+	if opts.Checkpoint != "" {
+		writer.SetCursor(opts.Checkpoint, index, digest)
+	}
 
-		entry := &pb.SnapshotEntry{
-			Data: iter.Value(),
+	enc := database.newEntryEncoder(opts)
+	if scan != nil {
+		err = database.fetchIndexScan(snapshot, scan, after, writer, enc)
+		if err != nil {
+			return err
 		}
 
-		packet.Entries = append(packet.Entries, entry)
+		return writer.Flush()
+	}
+
+	iter := snapshot.NewIterator(recordPrefix)
+	defer iter.Release()
 
-		if len(packet.Entries) >= 100 {
-			stream.Send(packet)
-			packet.Entries = make([]*pb.SnapshotEntry, 0)
+	var ok bool
+	if after != nil && bytes.Compare(after, opts.Start) >= 0 {
+		ok = iter.Seek(after)
+		if ok && bytes.Equal(iter.Key(), after) {
+			ok = iter.Next()
 		}
+	} else if opts.Start != nil {
+		ok = iter.Seek(opts.Start)
+	} else {
+		ok = iter.Next()
 	}
 
-	// Send entries if buffer has data still
-	if len(packet.Entries) > 0 {
-		stream.Send(packet)
+	for ; ok; ok = iter.Next() {
+
+		if opts.End != nil && bytes.Compare(iter.Key(), opts.End) >= 0 {
+			break
+		}
+
+		entry, err := enc.encode(iter.Key(), iter.Value())
+		if err != nil {
+			return err
+		}
+
+		if entry == nil {
+			continue
+		}
+
+		err = writer.WriteKey(iter.Key(), entry)
+		if err != nil {
+			return err
+		}
 	}
 
 	err = iter.Error()
@@ -220,8 +690,156 @@ func (database *Database) FetchSnapshot(stream pb.DataSnapshot_GetSnapshotServer
 		return err
 	}
 
-	iter.Release()
-	snapshot.Release()
+	// Send entries if buffer has data still
+	return writer.Flush()
+}
 
-	return nil
+// fetchIndexScan writes records of index entries in ranges of scan which
+// match filter, after index entry if it is set.
+func (database *Database) fetchIndexScan(snapshot store.Snapshot, scan *indexScan, after []byte, writer *packetWriter, enc *entryEncoder) error {
+
+	iter := snapshot.NewIterator(scan.index.prefix())
+	defer iter.Release()
+
+	for _, r := range scan.ranges {
+
+		start := r[0]
+		if after != nil {
+			if r[1] != nil && bytes.Compare(r[1], after) <= 0 {
+				continue
+			}
+
+			if bytes.Compare(after, start) >= 0 {
+				start = after
+			}
+		}
+
+		ok := iter.Seek(start)
+		if ok && after != nil && bytes.Equal(iter.Key(), after) {
+			ok = iter.Next()
+		}
+
+		for ; ok; ok = iter.Next() {
+
+			if r[1] != nil && bytes.Compare(iter.Key(), r[1]) >= 0 {
+				break
+			}
+
+			pk, err := scan.index.primaryKeyOfEntry(iter.Key())
+			if err != nil {
+				return err
+			}
+
+			key := recordKey(pk)
+			value, err := snapshot.Get(key)
+			if err == store.ErrNotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			entry, err := enc.encode(key, value)
+			if err != nil {
+				return err
+			}
+
+			if entry == nil {
+				continue
+			}
+
+			err = writer.WriteKey(iter.Key(), entry)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return iter.Error()
+}
+
+// entryEncoder makes entries of stored records with options of request.
+type entryEncoder struct {
+	database *Database
+	opts     *SnapshotOptions
+	primary  []string
+}
+
+func (database *Database) newEntryEncoder(opts *SnapshotOptions) *entryEncoder {
+
+	enc := &entryEncoder{
+		database: database,
+		opts:     opts,
+	}
+
+	if opts.Fields != nil {
+		enc.primary = database.primaryFields()
+	}
+
+	return enc
+}
+
+// encode makes entry of stored record, or returns nil if record doesn't
+// match filter.
+func (enc *entryEncoder) encode(key []byte, value []byte) (*pb.SnapshotEntry, error) {
+
+	data, err := enc.database.decryptValue(key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Masked records are encoded anew, so they are never sent compressed
+	codec, payload, ok := splitCompressed(data)
+	sendCompressed := ok && enc.opts.Compressed && enc.opts.Fields == nil
+	if sendCompressed && enc.opts.Filter == nil {
+		return &pb.SnapshotEntry{
+			Data:        cloneBytes(payload),
+			Compression: codecNames[codec],
+		}, nil
+	}
+
+	data, err = enc.database.decompressValue(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if enc.opts.Filter != nil {
+		match, err := enc.opts.Filter.Match(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if !match {
+			return nil, nil
+		}
+	}
+
+	if enc.opts.Fields != nil {
+		data, err = enc.opts.Fields.Apply(data, enc.primary)
+		if err != nil {
+			return nil, err
+		}
+
+		return &pb.SnapshotEntry{
+			Data: data,
+		}, nil
+	}
+
+	if sendCompressed {
+		return &pb.SnapshotEntry{
+			Data:        cloneBytes(payload),
+			Compression: codecNames[codec],
+		}, nil
+	}
+
+	return &pb.SnapshotEntry{
+		Data: cloneBytes(data),
+	}, nil
+}
+
+func (database *Database) isEmpty() bool {
+
+	iter := database.db.NewIterator(recordPrefix)
+	defer iter.Release()
+
+	return !iter.Next()
 }
@@ -1,28 +1,707 @@
 package data_snapshot
 
+import (
+	"errors"
+	"path"
+	"sync"
+	"sync/atomic"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrCollectionNotFound = errors.New("no such collection")
+	ErrCollectionExists   = errors.New("collection exists already")
+	ErrCollectionBusy     = errors.New("collection is being changed by another operation")
+	ErrCollectionInMemory = errors.New("collection of memory engine can't be renamed")
+)
+
 type DatabaseManager struct {
-	databases map[string]*Database
+	mutex           sync.Mutex
+	catalog         *Catalog
+	databases       map[string]*Database
+	busy            map[string]bool
+	opening         map[string]chan struct{}
+	closing         map[string]chan struct{}
+	failures        map[string]*CollectionFailure
+	replayer        Replayer
+	expiredHandler  ExpiredHandler
+	rejectedHandler RejectedHandler
 }
 
 func CreateDatabaseManager() *DatabaseManager {
+
+	configureStores()
+
+	layout, err := getLayout()
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+
+	catalog, err := OpenCatalog(viper.GetString("database.dbpath"))
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+
+	if layout == layoutShared {
+		err := checkSharedEngine(catalog)
+		if err != nil {
+			log.Error(err)
+			return nil
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"layout": layout,
+	}).Info("Opened catalog of collections")
+
 	return &DatabaseManager{
+		catalog:   catalog,
 		databases: make(map[string]*Database),
+		busy:      make(map[string]bool),
+		opening:   make(map[string]chan struct{}),
+		closing:   make(map[string]chan struct{}),
+		failures:  make(map[string]*CollectionFailure),
 	}
 }
 
+// configureStores applies settings of engines which are shared by all
+// stores.
+func configureStores() {
+
+	size := viper.GetSizeInBytes("database.bolt_mmap_size")
+	if size > 0 {
+		store.BoltMmapSize = int(size)
+	}
+}
+
+// GetDatabase returns database of collection and creates collection if it
+// is not in catalog yet, so it is only meant for ingestion. Caller has to
+// release database when done. It returns nil while collection is being
+// dropped, truncated or renamed.
 func (dm *DatabaseManager) GetDatabase(dbname string) *Database {
+	return dm.getDatabase(dbname, true)
+}
+
+// GetExistingDatabase is like GetDatabase but never creates collection.
+func (dm *DatabaseManager) GetExistingDatabase(dbname string) *Database {
+	return dm.getDatabase(dbname, false)
+}
+
+func (dm *DatabaseManager) getDatabase(dbname string, create bool) *Database {
+
+	for {
+		found := dm.lookupDatabase(dbname, create)
+		dm.closeEvicted(found.evicted)
+		if found.open != nil {
+			return dm.openDatabase(dbname, found.open)
+		}
+
+		if found.wait == nil {
+			return found.db
+		}
 
-	if db, ok := dm.databases[dbname]; ok {
-		return db
+		// Collection is being opened by another caller, or it was evicted
+		// and is still being closed
+		<-found.wait
 	}
+}
 
-	db := OpenDatabase(dbname)
-	if db == nil {
-		return nil
+// databaseLookup is what lookupDatabase found out about collection.
+type databaseLookup struct {
+	db      *Database
+	evicted []*Database
 
+	// Collection is being opened or closed, so caller waits and looks again
+	wait chan struct{}
+
+	// Collection was marked as opening and caller has to open it
+	open *CatalogEntry
+}
+
+// lookupDatabase returns database of collection along with databases which
+// were evicted to make room for it. Collections which are closed are marked
+// as opening instead, so they are opened without holding lock of manager.
+func (dm *DatabaseManager) lookupDatabase(dbname string, create bool) *databaseLookup {
+
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+
+	if wait := dm.transition(dbname); wait != nil {
+		return &databaseLookup{wait: wait}
+	}
+
+	if !validCollectionName(dbname) || dm.busy[dbname] {
+		return &databaseLookup{}
+	}
+
+	db, ok := dm.databases[dbname]
+	if !ok {
+		entry, ok := dm.catalog.Lookup(dbname)
+		if !ok && !create {
+			return &databaseLookup{}
+		}
+
+		if ok && entry.Failure != nil {
+			return &databaseLookup{}
+		}
+
+		if !ok {
+			var err error
+			entry, err = dm.catalog.Register(dbname)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"collection": dbname,
+				}).Error(err)
+				return &databaseLookup{}
+			}
+
+			log.WithFields(log.Fields{
+				"collection": dbname,
+				"dir":        entry.Dir,
+			}).Info("Created collection")
+		}
+
+		evicted := dm.evict()
+		dm.opening[dbname] = make(chan struct{})
+
+		return &databaseLookup{
+			evicted: evicted,
+			open:    entry,
+		}
 	}
 
-	dm.databases[dbname] = db
+	db.acquire()
+
+	return &databaseLookup{db: db}
+}
+
+// openDatabase opens collection which was marked as opening by
+// lookupDatabase. Recovery of collection may take long, so callers which look
+// it up meanwhile wait instead of holding lock of manager.
+func (dm *DatabaseManager) openDatabase(name string, entry *CatalogEntry) *Database {
+
+	db, err := dm.open(name, entry)
+	if err == nil {
+		dm.recordStats(db)
+	}
+
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+
+	close(dm.opening[name])
+	delete(dm.opening, name)
+
+	if err != nil {
+		return nil
+	}
+
+	dm.register(name, db)
+	db.acquire()
 
 	return db
 }
+
+// transition returns channel which is closed once collection is opened or
+// closed, nil if neither is going on. Caller holds lock of manager.
+func (dm *DatabaseManager) transition(name string) chan struct{} {
+
+	if opening, ok := dm.opening[name]; ok {
+		return opening
+	}
+
+	return dm.closing[name]
+}
+
+// register adds database which was opened to manager. Caller holds lock of
+// manager.
+func (dm *DatabaseManager) register(name string, db *Database) {
+
+	db.SetExpiredHandler(dm.expiredHandler)
+	db.SetRejectedHandler(dm.rejectedHandler)
+	dm.databases[name] = db
+	collectionOpensCounter.Inc()
+	openCollectionsGauge.Set(float64(len(dm.databases)))
+}
+
+// evict closes least recently used idle databases to make room for one more
+// within database.max_open, collections which are being opened count as open.
+// Databases which are in use are never closed, so the limit may be exceeded
+// for a while.
+func (dm *DatabaseManager) evict() []*Database {
+
+	limit := viper.GetInt("database.max_open")
+	if limit <= 0 {
+		return nil
+	}
+
+	evicted := make([]*Database, 0)
+	for len(dm.databases)+len(dm.opening) >= limit {
+
+		var victim *Database
+		for _, db := range dm.databases {
+			if !db.isIdle() {
+				continue
+			}
+
+			if victim == nil || atomic.LoadInt64(&db.lastUsed) < atomic.LoadInt64(&victim.lastUsed) {
+				victim = db
+			}
+		}
+
+		if victim == nil {
+			log.WithFields(log.Fields{
+				"open":  len(dm.databases),
+				"limit": limit,
+			}).Warn("All open collections are in use, exceeding max_open")
+			break
+		}
+
+		delete(dm.databases, victim.name)
+		dm.closing[victim.name] = make(chan struct{})
+		evicted = append(evicted, victim)
+		collectionEvictionsCounter.Inc()
+		openCollectionsGauge.Set(float64(len(dm.databases)))
+	}
+
+	return evicted
+}
+
+// closeEvicted closes databases which were evicted, without holding lock of
+// manager since it waits for their workers.
+func (dm *DatabaseManager) closeEvicted(evicted []*Database) {
+
+	for _, db := range evicted {
+
+		err := dm.closeDatabase(db)
+		if err == ErrShutdownTimeout {
+			continue
+		} else if err != nil {
+			log.WithFields(log.Fields{
+				"collection": db.name,
+			}).Error(err)
+		} else {
+			log.WithFields(log.Fields{
+				"collection": db.name,
+			}).Info("Closed idle collection")
+		}
+
+		dm.mutex.Lock()
+		close(dm.closing[db.name])
+		delete(dm.closing, db.name)
+		dm.mutex.Unlock()
+	}
+}
+
+// closeDatabase closes database which was taken away from manager. Database
+// whose requests don't stop in time is closed after them, and collection
+// stays closing until then, so it is not opened twice.
+func (dm *DatabaseManager) closeDatabase(db *Database) error {
+
+	err := db.shutdown()
+	if err == ErrShutdownTimeout {
+		dm.closeLater(db)
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	dm.recordStats(db)
+
+	return db.db.Close()
+}
+
+// recordStats keeps sequence and stats of collection in catalog, so they are
+// listed while collection is closed.
+func (dm *DatabaseManager) recordStats(db *Database) {
+
+	seq, stats, err := db.GetStats()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"collection": db.name,
+		}).Warn("Failed to read stats of collection: ", err)
+		return
+	}
+
+	dm.setStats(db.name, seq, stats)
+}
+
+func (dm *DatabaseManager) setStats(name string, seq uint64, stats *CollectionStats) {
+
+	err := dm.catalog.SetStats(name, seq, stats)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"collection": name,
+		}).Warn("Failed to record stats of collection: ", err)
+	}
+}
+
+func (dm *DatabaseManager) closeLater(db *Database) {
+
+	dm.mutex.Lock()
+	closing, ok := dm.closing[db.name]
+	if !ok {
+		closing = make(chan struct{})
+		dm.closing[db.name] = closing
+	}
+	dm.mutex.Unlock()
+
+	log.WithFields(log.Fields{
+		"collection": db.name,
+	}).Error("Collection is closed once its in-flight requests return")
+
+	go func() {
+		err := db.closeLater()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"collection": db.name,
+			}).Error(err)
+		}
+
+		dm.mutex.Lock()
+		close(closing)
+		delete(dm.closing, db.name)
+		dm.mutex.Unlock()
+	}()
+}
+
+func (dm *DatabaseManager) SetExpiredHandler(handler ExpiredHandler) {
+	dm.expiredHandler = handler
+}
+
+func (dm *DatabaseManager) SetRejectedHandler(handler RejectedHandler) {
+	dm.rejectedHandler = handler
+}
+
+// checkOut takes collections away from manager for an exclusive operation.
+// Databases which were open are returned and have to be closed by caller.
+func (dm *DatabaseManager) checkOut(names ...string) ([]*Database, error) {
+
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+
+	// Wait for collections which are being opened or closed
+	for dm.inTransition(names) {
+		dm.mutex.Unlock()
+		dm.waitTransition(names)
+		dm.mutex.Lock()
+	}
+
+	for _, name := range names {
+		if !validCollectionName(name) {
+			return nil, ErrCollectionNotFound
+		}
+
+		if dm.busy[name] {
+			return nil, ErrCollectionBusy
+		}
+	}
+
+	dbs := make([]*Database, len(names))
+	for i, name := range names {
+		dm.busy[name] = true
+		dbs[i] = dm.databases[name]
+		delete(dm.databases, name)
+	}
+
+	openCollectionsGauge.Set(float64(len(dm.databases)))
+
+	return dbs, nil
+}
+
+// inTransition tells whether any of collections is being opened or closed.
+// Caller holds lock of manager.
+func (dm *DatabaseManager) inTransition(names []string) bool {
+
+	for _, name := range names {
+		if dm.transition(name) != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (dm *DatabaseManager) waitTransition(names []string) {
+
+	for _, name := range names {
+
+		dm.mutex.Lock()
+		wait := dm.transition(name)
+		dm.mutex.Unlock()
+
+		if wait != nil {
+			<-wait
+		}
+	}
+}
+
+func (dm *DatabaseManager) checkIn(names ...string) {
+
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+
+	for _, name := range names {
+		delete(dm.busy, name)
+	}
+}
+
+// DropCollection closes database of collection and removes it from disk.
+func (dm *DatabaseManager) DropCollection(name string) error {
+
+	dbs, err := dm.checkOut(name)
+	if err != nil {
+		return err
+	}
+	defer dm.checkIn(name)
+
+	entry, ok := dm.catalog.Lookup(name)
+	if !ok {
+		return ErrCollectionNotFound
+	}
+
+	if db := dbs[0]; db != nil {
+		err := dm.closeDatabase(db)
+		if err != nil {
+			return err
+		}
+	}
+
+	layout, _ := getLayout()
+	err = dm.catalog.removeData(entry, layout)
+	if err != nil {
+		return err
+	}
+
+	err = dm.catalog.Remove(name)
+	if err != nil {
+		return err
+	}
+
+	deleteStatsMetrics(name)
+
+	log.WithFields(log.Fields{
+		"collection": name,
+	}).Info("Dropped collection")
+
+	return nil
+}
+
+// TruncateCollection removes all records of collection but keeps its
+// sequence, so ingestion carries on.
+func (dm *DatabaseManager) TruncateCollection(name string) (uint64, error) {
+
+	dbs, err := dm.checkOut(name)
+	if err != nil {
+		return 0, err
+	}
+	defer dm.checkIn(name)
+
+	entry, ok := dm.catalog.Lookup(name)
+	if !ok {
+		return 0, ErrCollectionNotFound
+	}
+
+	db := dbs[0]
+	if db == nil {
+		db, err = openCollection(dm.catalog, entry)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = db.shutdown()
+	if err != nil {
+		dm.closeLater(db)
+		return 0, err
+	}
+
+	seq, err := db.truncate()
+	if err == nil {
+		dm.setStats(name, seq, &CollectionStats{})
+	}
+
+	closeErr := db.db.Close()
+	if err != nil {
+		return 0, err
+	} else if closeErr != nil {
+		return 0, closeErr
+	}
+
+	log.WithFields(log.Fields{
+		"collection": name,
+		"seq":        seq,
+	}).Info("Truncated collection")
+
+	return seq, nil
+}
+
+// RenameCollection moves collection to a new name in catalog, directory of
+// collection stays the same. Settings in config are not moved, and events
+// which still carry the old name create it again.
+func (dm *DatabaseManager) RenameCollection(name string, newName string) error {
+
+	dbs, err := dm.checkOut(name, newName)
+	if err != nil {
+		return err
+	}
+	defer dm.checkIn(name, newName)
+
+	if _, ok := dm.catalog.Lookup(newName); ok {
+		dm.restore(newName, dbs[1])
+		dm.restore(name, dbs[0])
+		return ErrCollectionExists
+	}
+
+	if _, ok := dm.catalog.Lookup(name); !ok {
+		return ErrCollectionNotFound
+	}
+
+	// Collections of memory engine would lose their data
+	db := dbs[0]
+	if db != nil && db.engine == "memory" {
+		dm.restore(name, db)
+		return ErrCollectionInMemory
+	}
+
+	if db != nil {
+		err := dm.closeDatabase(db)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = dm.catalog.Rename(name, newName)
+	if err != nil {
+		return err
+	}
+
+	deleteStatsMetrics(name)
+
+	log.WithFields(log.Fields{
+		"collection": name,
+		"name":       newName,
+	}).Info("Renamed collection")
+
+	return nil
+}
+
+// restore gives database back to manager after an operation was cancelled.
+func (dm *DatabaseManager) restore(name string, db *Database) {
+
+	if db == nil {
+		return
+	}
+
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+
+	dm.databases[name] = db
+	openCollectionsGauge.Set(float64(len(dm.databases)))
+}
+
+// CollectionInfo describes collection in catalog. Sequence and stats of
+// collection which isn't open are the ones catalog recorded when it was last
+// opened or closed.
+type CollectionInfo struct {
+	Name      string
+	CreatedAt int64
+	State     string
+	Error     string
+	Sequence  uint64
+	Stats     *CollectionStats
+}
+
+// ListCollections describes collections in catalog whose names match glob
+// pattern, all of them if pattern is empty. Collections which are closed
+// are not opened for listing.
+func (dm *DatabaseManager) ListCollections(pattern string) ([]*CollectionInfo, error) {
+
+	if pattern != "" {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	collections := make([]*CollectionInfo, 0)
+	for _, name := range dm.catalog.Names() {
+
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, name); !ok {
+				continue
+			}
+		}
+
+		entry, ok := dm.catalog.Lookup(name)
+		if !ok {
+			continue
+		}
+
+		collections = append(collections, dm.describe(entry))
+	}
+
+	return collections, nil
+}
+
+// peekDatabase returns database of collection only if it is open already,
+// state tells why it is not otherwise. Caller has to release database.
+func (dm *DatabaseManager) peekDatabase(name string) (*Database, string) {
+
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+
+	if dm.transition(name) != nil || dm.busy[name] {
+		return nil, stateBusy
+	}
+
+	db, ok := dm.databases[name]
+	if !ok {
+		return nil, stateClosed
+	}
+
+	db.acquire()
+
+	return db, ""
+}
+
+// describe reports collection, one whose stats can't be read is listed in
+// error state along with the others.
+func (dm *DatabaseManager) describe(entry *CatalogEntry) *CollectionInfo {
+
+	info := &CollectionInfo{
+		Name:      entry.Name,
+		CreatedAt: entry.CreatedAt,
+		Sequence:  entry.Sequence,
+		Stats:     entry.Stats,
+	}
+
+	db, state := dm.peekDatabase(entry.Name)
+	if db == nil {
+		info.State = state
+		if failure := dm.GetFailure(entry.Name); failure != nil {
+			info.State = stateFailed
+			info.Error = failure.Error
+		}
+
+		return info
+	}
+	defer db.Release()
+
+	seq, stats, err := db.GetStats()
+	if err != nil {
+		info.State = stateError
+		info.Error = err.Error()
+		return info
+	}
+
+	info.State = db.State()
+	info.Sequence = seq
+	info.Stats = stats
+
+	return info
+}
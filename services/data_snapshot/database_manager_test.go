@@ -0,0 +1,306 @@
+package data_snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// openTestManager creates manager of collections of leveldb engine in a
+// temporary directory, which is removed by the returned function along with
+// databases which are still open.
+func openTestManager(t *testing.T) (*DatabaseManager, func()) {
+
+	dir, err := ioutil.TempDir("", "data-snapshot-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Set("database.dbpath", dir)
+	viper.Set("database.engine", "leveldb")
+
+	dm := CreateDatabaseManager()
+	if dm == nil {
+		os.RemoveAll(dir)
+		t.Fatal("failed to create manager")
+	}
+
+	return dm, func() {
+
+		for _, db := range dm.databases {
+			db.Close()
+		}
+
+		viper.Set("database.dbpath", "")
+		viper.Set("database.engine", "")
+		viper.Set("database.max_open", 0)
+		os.RemoveAll(dir)
+	}
+}
+
+// openNames returns names of collections which manager has open.
+func openNames(dm *DatabaseManager) string {
+
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+
+	names := make([]string, 0, len(dm.databases))
+	for name := range dm.databases {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return strings.Join(names, ",")
+}
+
+func TestEvictLeastRecentlyUsed(t *testing.T) {
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	viper.Set("database.max_open", 2)
+
+	for _, name := range []string{"a", "b", "a", "c"} {
+
+		db := dm.GetDatabase(name)
+		if db == nil {
+			t.Fatalf("GetDatabase(%s) = nil", name)
+		}
+		db.Release()
+
+		// Collections differ in time of last use
+		time.Sleep(time.Millisecond)
+	}
+
+	if names := openNames(dm); names != "a,c" {
+		t.Errorf("open collections = %s, want a,c", names)
+	}
+
+	// Evicted collection is opened again with its data
+	db := dm.GetDatabase("b")
+	writeTestRecord(t, db, 1, 1)
+	db.Release()
+
+	for _, name := range []string{"a", "c", "b"} {
+		db := dm.GetDatabase(name)
+		db.Release()
+	}
+
+	db = dm.GetExistingDatabase("b")
+	defer db.Release()
+
+	if seq, err := db.GetSequence(); err != nil || seq != 1 {
+		t.Errorf("sequence of reopened collection = %d, %v, want 1", seq, err)
+	}
+}
+
+func TestEvictSkipsCollectionsInUse(t *testing.T) {
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	viper.Set("database.max_open", 1)
+
+	a := dm.GetDatabase("a")
+	b := dm.GetDatabase("b")
+	if a == nil || b == nil {
+		t.Fatal("collection isn't opened")
+	}
+
+	// Limit is exceeded while both are in use
+	if names := openNames(dm); names != "a,b" {
+		t.Errorf("open collections = %s, want a,b", names)
+	}
+
+	a.Release()
+	b.Release()
+
+	c := dm.GetDatabase("c")
+	c.Release()
+
+	if names := openNames(dm); names != "c" {
+		t.Errorf("open collections = %s, want c", names)
+	}
+}
+
+func TestGetDatabaseConcurrently(t *testing.T) {
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	const callers = 20
+
+	dbs := make([]*Database, callers)
+	var wg sync.WaitGroup
+	for i := range dbs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			dbs[i] = dm.GetDatabase("a")
+		}(i)
+	}
+	wg.Wait()
+
+	// Collection is opened once and every caller holds a reference
+	for _, db := range dbs {
+		if db == nil || db != dbs[0] {
+			t.Fatalf("callers got different databases")
+		}
+	}
+
+	if active := atomic.LoadInt32(&dbs[0].active); active != callers {
+		t.Errorf("active references = %d, want %d", active, callers)
+	}
+
+	for _, db := range dbs {
+		db.Release()
+	}
+
+	if !dbs[0].isIdle() {
+		t.Error("collection isn't idle after every reference is released")
+	}
+}
+
+func TestDropWaitsForReferences(t *testing.T) {
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	db := dm.GetDatabase("a")
+
+	dropped := make(chan error, 1)
+	go func() {
+		dropped <- dm.DropCollection("a")
+	}()
+
+	select {
+	case err := <-dropped:
+		t.Fatalf("DropCollection returned while collection is in use: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Collection can't be looked up while it is being dropped
+	if other := dm.GetExistingDatabase("a"); other != nil {
+		other.Release()
+		t.Error("collection which is being dropped is returned")
+	}
+
+	db.Release()
+	if err := <-dropped; err != nil {
+		t.Fatalf("DropCollection = %v", err)
+	}
+
+	if _, ok := dm.catalog.Lookup("a"); ok {
+		t.Error("dropped collection is in catalog")
+	}
+}
+
+func TestGetDatabaseWhileOpening(t *testing.T) {
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	entry, err := dm.catalog.Register("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Collection is being opened by another caller for a long time
+	dm.mutex.Lock()
+	dm.opening["a"] = make(chan struct{})
+	dm.mutex.Unlock()
+
+	waiting := make(chan *Database, 1)
+	go func() {
+		waiting <- dm.GetDatabase("a")
+	}()
+
+	// Other collections are not held up meanwhile
+	b := dm.GetDatabase("b")
+	if b == nil {
+		t.Fatal("GetDatabase(b) = nil")
+	}
+	b.Release()
+
+	select {
+	case <-waiting:
+		t.Fatal("collection is returned before it is opened")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	a := dm.openDatabase("a", entry)
+	if a == nil {
+		t.Fatal("openDatabase = nil")
+	}
+	a.Release()
+
+	if db := <-waiting; db != a {
+		t.Errorf("waiting caller got %p, want %p", db, a)
+	} else {
+		db.Release()
+	}
+}
+
+func TestListCollectionsOfClosed(t *testing.T) {
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	viper.Set("database.max_open", 1)
+
+	openTestCollection(t, dm, "a", 3)
+	openTestCollection(t, dm, "b", 1)
+
+	// Closed collection is listed with what catalog recorded on close
+	collections, err := dm.ListCollections("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := collections[0]
+	if a.Name != "a" || a.State != stateClosed || a.Sequence != 3 || a.Stats == nil || a.Stats.RecordCount != 3 {
+		t.Errorf("closed collection is listed as %+v", a)
+	}
+
+	b := collections[1]
+	if b.Name != "b" || b.State != stateOK || b.Sequence != 1 {
+		t.Errorf("open collection is listed as %+v", b)
+	}
+}
+
+func TestListCollectionsWithError(t *testing.T) {
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	openTestCollection(t, dm, "a", 1)
+	openTestCollection(t, dm, "b", 1)
+
+	// Collection whose stats can't be read doesn't fail listing
+	dm.databases["a"].db.Close()
+
+	collections, err := dm.ListCollections("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(collections) != 2 {
+		t.Fatalf("%d collections are listed, want 2", len(collections))
+	}
+
+	if a := collections[0]; a.State != stateError || a.Error == "" {
+		t.Errorf("collection which can't be read is listed as %+v", a)
+	}
+
+	if b := collections[1]; b.State != stateOK || b.Sequence != 1 {
+		t.Errorf("collection is listed as %+v", b)
+	}
+}
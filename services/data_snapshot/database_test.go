@@ -0,0 +1,79 @@
+package data_snapshot
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "gravity-data-snapshot/pb"
+)
+
+var errStreamBroken = errors.New("stream broken")
+
+// testStream keeps packets which were sent, and breaks once limit of packets
+// is reached if it is set.
+type testStream struct {
+	pb.DataSnapshot_GetSnapshotServer
+	packets []*pb.SnapshotPacket
+	limit   int
+}
+
+func (s *testStream) Send(packet *pb.SnapshotPacket) error {
+
+	if s.limit > 0 && len(s.packets) >= s.limit {
+		return errStreamBroken
+	}
+
+	copied := *packet
+	copied.Entries = append([]*pb.SnapshotEntry{}, packet.Entries...)
+	s.packets = append(s.packets, &copied)
+
+	return nil
+}
+
+func (s *testStream) entries() []*pb.SnapshotEntry {
+
+	entries := make([]*pb.SnapshotEntry, 0)
+	for _, packet := range s.packets {
+		entries = append(entries, packet.Entries...)
+	}
+
+	return entries
+}
+
+// openTestDatabase opens database of engine in a temporary directory, which
+// is removed by the returned function along with database.
+func openTestDatabase(t *testing.T, engine string) (*Database, func()) {
+
+	dir, err := ioutil.TempDir("", "data-snapshot-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := OpenDatabase("t", engine, filepath.Join(dir, "t"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func writeTestRecord(t *testing.T, db *Database, seq uint64, id float64, fields ...Field) {
+
+	projection := &Projection{
+		Collection: "t",
+		Method:     "insert",
+		Fields:     append([]Field{{Name: "id", Value: id, Primary: true}}, fields...),
+	}
+
+	err := db.ProcessData(seq, projection)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
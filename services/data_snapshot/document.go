@@ -0,0 +1,36 @@
+package data_snapshot
+
+import (
+	"strings"
+)
+
+// lookupField returns value of field in document, nested fields are
+// addressed by paths separated with dots.
+func lookupField(doc map[string]interface{}, path string) (interface{}, bool) {
+
+	var current interface{} = doc
+	for _, name := range strings.Split(path, ".") {
+
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		current, ok = obj[name]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+func copyDocument(doc map[string]interface{}) map[string]interface{} {
+
+	clone := make(map[string]interface{}, len(doc))
+	for name, value := range doc {
+		clone[name] = value
+	}
+
+	return clone
+}
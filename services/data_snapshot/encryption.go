@@ -0,0 +1,670 @@
+package data_snapshot
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrNoMasterKey       = errors.New("master key is not available")
+	ErrInvalidCiphertext = errors.New("invalid encrypted value")
+)
+
+var (
+	dataKeyPrefix   = []byte("dek-")
+	dataKeyStateKey = []byte("dekstate")
+	hashKeyKey      = []byte("hashkey")
+)
+
+// Values under these prefixes are encrypted, stats and schema are single
+// keys.
+var encryptedPrefixes = [][]byte{
+	recordPrefix,
+	tombstonePrefix,
+	ttlPrefix,
+	historyPrefix,
+	pendingPrefix,
+	rejectedPrefix,
+	dictionaryPrefix,
+	statsKey,
+	schemaKey,
+}
+
+const (
+	frameMarker    = 0x00
+	frameEncrypted = 'E'
+
+	keyRotationCheckInterval = time.Hour
+)
+
+var masterKeys struct {
+	once   sync.Once
+	keys   map[string][]byte
+	active string
+	err    error
+}
+
+// Data keys of collection are wrapped by master key and stored in database.
+type wrappedKey struct {
+	MasterKeyID string `json:"master_key"`
+	Wrapped     []byte `json:"wrapped"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+type dataKeyState struct {
+	Active uint32 `json:"active"`
+}
+
+// keyring holds data keys of collection. Values are always encrypted with
+// the active key, older keys are kept for values written before rotation.
+type keyring struct {
+	keys          map[uint32]cipher.AEAD
+	active        uint32
+	activeCreated int64
+	hashKey       []byte
+}
+
+// loadMasterKeys reads keyfile which has one "<id>:<base64 key>" per line.
+// The key named by encryption.master_key_id is used for wrapping, or the last
+// one if it is not set.
+func loadMasterKeys() (map[string][]byte, string, error) {
+
+	masterKeys.once.Do(func() {
+
+		filename := viper.GetString("encryption.keyfile")
+		if filename == "" {
+			masterKeys.err = ErrNoMasterKey
+			return
+		}
+
+		file, err := os.Open(filename)
+		if err != nil {
+			masterKeys.err = err
+			return
+		}
+		defer file.Close()
+
+		keys := make(map[string][]byte)
+		active := ""
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			parts := strings.SplitN(line, ":", 2)
+			if len(parts) != 2 {
+				masterKeys.err = fmt.Errorf("invalid line in keyfile %s", filename)
+				return
+			}
+
+			key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(parts[1]))
+			if err != nil || len(key) != 32 {
+				masterKeys.err = fmt.Errorf("master key \"%s\" must be 32 bytes in base64", parts[0])
+				return
+			}
+
+			keys[parts[0]] = key
+			active = parts[0]
+		}
+
+		if err := scanner.Err(); err != nil {
+			masterKeys.err = err
+			return
+		}
+
+		if id := viper.GetString("encryption.master_key_id"); id != "" {
+			active = id
+		}
+
+		if _, ok := keys[active]; !ok {
+			masterKeys.err = ErrNoMasterKey
+			return
+		}
+
+		masterKeys.keys = keys
+		masterKeys.active = active
+	})
+
+	return masterKeys.keys, masterKeys.active, masterKeys.err
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func sealData(aead cipher.AEAD, plaintext []byte, aad []byte) ([]byte, error) {
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func openData(aead cipher.AEAD, data []byte, aad []byte) ([]byte, error) {
+
+	if len(data) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], aad)
+}
+
+func wrapKey(key []byte) (*wrappedKey, error) {
+
+	keys, active, err := loadMasterKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(keys[active])
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := sealData(aead, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &wrappedKey{
+		MasterKeyID: active,
+		Wrapped:     wrapped,
+		CreatedAt:   time.Now().UnixNano(),
+	}, nil
+}
+
+func unwrapKey(wk *wrappedKey) ([]byte, error) {
+
+	keys, _, err := loadMasterKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	masterKey, ok := keys[wk.MasterKeyID]
+	if !ok {
+		return nil, fmt.Errorf("master key \"%s\" is not in keyfile", wk.MasterKeyID)
+	}
+
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return openData(aead, wk.Wrapped, nil)
+}
+
+// Keyring is replaced as a whole on rotation, so readers which don't hold
+// mutex of database always see a complete one.
+func (database *Database) getKeyring() *keyring {
+
+	kr, _ := database.keyring.Load().(*keyring)
+
+	return kr
+}
+
+func (database *Database) setKeyring(kr *keyring) {
+	database.keyring.Store(kr)
+}
+
+func generateKey() ([]byte, error) {
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func dataKeyKey(version uint32) []byte {
+
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, version)
+
+	return prefixedKey(dataKeyPrefix, b)
+}
+
+// initEncryption loads keyring of collection. Data keys wrapped by a master
+// key which is no longer active are wrapped again with the active one.
+func (database *Database) initEncryption() error {
+
+	enabled := getCollectionBool(database.name, "encrypt")
+
+	stateData, err := database.db.Get(dataKeyStateKey)
+	if err == store.ErrNotFound {
+		if !enabled {
+			return nil
+		}
+
+		return database.createEncryption()
+	} else if err != nil {
+		return err
+	}
+
+	var state dataKeyState
+	err = json.Unmarshal(stateData, &state)
+	if err != nil {
+		return err
+	}
+
+	_, activeMasterKey, err := loadMasterKeys()
+	if err != nil {
+		return err
+	}
+
+	kr := &keyring{
+		keys:   make(map[uint32]cipher.AEAD),
+		active: state.Active,
+	}
+
+	batch := database.db.NewBatch()
+	iter := database.db.NewIterator(dataKeyPrefix)
+	for iter.Next() {
+
+		var wk wrappedKey
+		err := json.Unmarshal(iter.Value(), &wk)
+		if err != nil {
+			iter.Release()
+			return err
+		}
+
+		key, err := unwrapKey(&wk)
+		if err != nil {
+			iter.Release()
+			return err
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			iter.Release()
+			return err
+		}
+
+		version := binary.BigEndian.Uint32(iter.Key()[len(dataKeyPrefix):])
+		kr.keys[version] = aead
+		if version == kr.active {
+			kr.activeCreated = wk.CreatedAt
+		}
+
+		if wk.MasterKeyID != activeMasterKey {
+			rewrapped, err := wrapKey(key)
+			if err != nil {
+				iter.Release()
+				return err
+			}
+
+			rewrapped.CreatedAt = wk.CreatedAt
+			data, err := json.Marshal(rewrapped)
+			if err != nil {
+				iter.Release()
+				return err
+			}
+
+			batch.Put(dataKeyKey(version), data)
+		}
+	}
+
+	err = iter.Error()
+	iter.Release()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := kr.keys[kr.active]; !ok {
+		return fmt.Errorf("active data key %d is missing", kr.active)
+	}
+
+	hashKeyData, err := database.db.Get(hashKeyKey)
+	if err == nil {
+		var wk wrappedKey
+		err := json.Unmarshal(hashKeyData, &wk)
+		if err != nil {
+			return err
+		}
+
+		kr.hashKey, err = unwrapKey(&wk)
+		if err != nil {
+			return err
+		}
+
+		if wk.MasterKeyID != activeMasterKey {
+			rewrapped, err := wrapKey(kr.hashKey)
+			if err != nil {
+				return err
+			}
+
+			data, err := json.Marshal(rewrapped)
+			if err != nil {
+				return err
+			}
+
+			batch.Put(hashKeyKey, data)
+		}
+	} else if err != store.ErrNotFound {
+		return err
+	} else if getCollectionBool(database.name, "encrypt_keys") {
+		log.WithFields(log.Fields{
+			"collection": database.name,
+		}).Warn("Key encryption can only be enabled for new collection")
+	}
+
+	if batch.Len() > 0 {
+		log.WithFields(log.Fields{
+			"collection": database.name,
+			"master_key": activeMasterKey,
+		}).Info("Wrapped data keys with new master key")
+
+		err = database.db.Write(batch)
+		if err != nil {
+			return err
+		}
+	}
+
+	database.setKeyring(kr)
+
+	return nil
+}
+
+// createEncryption generates the first data key of collection. Record keys
+// can only be hashed if collection has no records yet, since existing keys
+// would be no longer found.
+func (database *Database) createEncryption() error {
+
+	kr := &keyring{
+		keys: make(map[uint32]cipher.AEAD),
+	}
+
+	batch := database.db.NewBatch()
+	if getCollectionBool(database.name, "encrypt_keys") && database.isEmpty() {
+
+		key, err := generateKey()
+		if err != nil {
+			return err
+		}
+
+		wk, err := wrapKey(key)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(wk)
+		if err != nil {
+			return err
+		}
+
+		batch.Put(hashKeyKey, data)
+		kr.hashKey = key
+	} else if getCollectionBool(database.name, "encrypt_keys") {
+		log.WithFields(log.Fields{
+			"collection": database.name,
+		}).Warn("Key encryption can only be enabled for new collection")
+	}
+
+	err := database.addDataKey(batch, kr, 1)
+	if err != nil {
+		return err
+	}
+
+	err = database.db.Write(batch)
+	if err != nil {
+		return err
+	}
+
+	database.setKeyring(kr)
+
+	count, err := database.encryptExisting()
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		log.WithFields(log.Fields{
+			"collection": database.name,
+			"count":      count,
+		}).Info("Encrypted values written before encryption was enabled")
+	}
+
+	return nil
+}
+
+// encryptExisting rewrites values which are still in plain text. Engines may
+// keep old values in their files until they are compacted.
+func (database *Database) encryptExisting() (int, error) {
+
+	count := 0
+	for _, prefix := range encryptedPrefixes {
+
+		batch := database.db.NewBatch()
+		iter := database.db.NewIterator(prefix)
+		for iter.Next() {
+
+			if isEncrypted(iter.Value()) {
+				continue
+			}
+
+			value, err := database.encryptValue(iter.Key(), iter.Value())
+			if err != nil {
+				iter.Release()
+				return 0, err
+			}
+
+			batch.Put(cloneBytes(iter.Key()), value)
+			count++
+
+			if batch.Len() >= deleteChunkSize {
+				err := database.db.Write(batch)
+				if err != nil {
+					iter.Release()
+					return 0, err
+				}
+
+				batch = database.db.NewBatch()
+			}
+		}
+
+		err := iter.Error()
+		iter.Release()
+		if err != nil {
+			return 0, err
+		}
+
+		err = database.db.Write(batch)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
+
+func (database *Database) addDataKey(batch store.Batch, kr *keyring, version uint32) error {
+
+	key, err := generateKey()
+	if err != nil {
+		return err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	wk, err := wrapKey(key)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(wk)
+	if err != nil {
+		return err
+	}
+
+	stateData, err := json.Marshal(&dataKeyState{
+		Active: version,
+	})
+	if err != nil {
+		return err
+	}
+
+	batch.Put(dataKeyKey(version), data)
+	batch.Put(dataKeyStateKey, stateData)
+
+	kr.keys[version] = aead
+	kr.active = version
+	kr.activeCreated = wk.CreatedAt
+
+	return nil
+}
+
+// RotateDataKey generates a new data key for values written from now on.
+func (database *Database) RotateDataKey() error {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	current := database.getKeyring()
+	if current == nil {
+		return fmt.Errorf("collection is not encrypted")
+	}
+
+	kr := &keyring{
+		keys:    make(map[uint32]cipher.AEAD),
+		hashKey: current.hashKey,
+	}
+
+	for version, aead := range current.keys {
+		kr.keys[version] = aead
+	}
+
+	batch := database.db.NewBatch()
+	err := database.addDataKey(batch, kr, current.active+1)
+	if err != nil {
+		return err
+	}
+
+	err = database.db.Write(batch)
+	if err != nil {
+		return err
+	}
+
+	database.setKeyring(kr)
+
+	log.WithFields(log.Fields{
+		"collection": database.name,
+		"version":    kr.active,
+	}).Info("Rotated data key")
+
+	return nil
+}
+
+func (database *Database) runKeyRotation(period time.Duration) {
+
+	ticker := time.NewTicker(keyRotationCheckInterval)
+	defer ticker.Stop()
+
+	for database.tick(ticker) {
+
+		created := database.getKeyring().activeCreated
+		if time.Since(time.Unix(0, created)) < period {
+			continue
+		}
+
+		err := database.RotateDataKey()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"collection": database.name,
+			}).Error(err)
+		}
+	}
+}
+
+// hashPrimaryKey hides primary key values if key encryption is enabled.
+func (database *Database) hashPrimaryKey(pk []byte) []byte {
+
+	kr := database.getKeyring()
+	if kr == nil || kr.hashKey == nil {
+		return pk
+	}
+
+	mac := hmac.New(sha256.New, kr.hashKey)
+	mac.Write(pk)
+
+	return mac.Sum(nil)
+}
+
+// encryptValue encrypts value with the active data key, key of value is used
+// as additional data so that values can't be moved to other keys.
+func (database *Database) encryptValue(key []byte, data []byte) ([]byte, error) {
+
+	kr := database.getKeyring()
+	if kr == nil {
+		return data, nil
+	}
+
+	ciphertext, err := sealData(kr.keys[kr.active], data, key)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := make([]byte, 6, 6+len(ciphertext))
+	frame[0] = frameMarker
+	frame[1] = frameEncrypted
+	binary.BigEndian.PutUint32(frame[2:6], kr.active)
+
+	return append(frame, ciphertext...), nil
+}
+
+// decryptValue returns plain value, values written without encryption are
+// returned as they are.
+func (database *Database) decryptValue(key []byte, data []byte) ([]byte, error) {
+
+	if !isEncrypted(data) {
+		return data, nil
+	}
+
+	kr := database.getKeyring()
+	if kr == nil || len(data) < 6 {
+		return nil, ErrInvalidCiphertext
+	}
+
+	aead, ok := kr.keys[binary.BigEndian.Uint32(data[2:6])]
+	if !ok {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return openData(aead, data[6:], key)
+}
+
+func isEncrypted(data []byte) bool {
+	return len(data) >= 2 && data[0] == frameMarker && data[1] == frameEncrypted
+}
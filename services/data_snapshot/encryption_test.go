@@ -0,0 +1,274 @@
+package data_snapshot
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func testKeyring(t *testing.T, active uint32, versions ...uint32) *keyring {
+
+	kr := &keyring{
+		keys:   make(map[uint32]cipher.AEAD),
+		active: active,
+	}
+
+	for _, version := range versions {
+
+		aead, err := newAEAD(bytes.Repeat([]byte{byte(version)}, 32))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		kr.keys[version] = aead
+	}
+
+	return kr
+}
+
+func TestEncryptValueFraming(t *testing.T) {
+
+	database := &Database{}
+	database.setKeyring(testKeyring(t, 1, 1))
+
+	key := recordKey([]byte("a"))
+	value := []byte(`{"id":"a"}`)
+
+	encrypted, err := database.encryptValue(key, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !isEncrypted(encrypted) || bytes.Contains(encrypted, value) {
+		t.Fatalf("encryptValue returned %q", encrypted)
+	}
+
+	tampered := append([]byte{}, encrypted...)
+	tampered[len(tampered)-1] ^= 1
+
+	unknown := append([]byte{}, encrypted...)
+	unknown[5] = 9
+
+	tests := []struct {
+		name  string
+		key   []byte
+		data  []byte
+		want  []byte
+		valid bool
+	}{
+		{"encrypted", key, encrypted, value, true},
+		{"plain", key, value, value, true},
+		{"empty", key, []byte{}, []byte{}, true},
+		{"other key", recordKey([]byte("b")), encrypted, nil, false},
+		{"tampered", key, tampered, nil, false},
+		{"unknown version", key, unknown, nil, false},
+		{"truncated", key, encrypted[:4], nil, false},
+	}
+
+	for _, test := range tests {
+
+		data, err := database.decryptValue(test.key, test.data)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: decryptValue error = %v", test.name, err)
+			continue
+		}
+
+		if test.valid && !bytes.Equal(data, test.want) {
+			t.Errorf("%s: decryptValue = %q, want %q", test.name, data, test.want)
+		}
+	}
+}
+
+func TestDecryptValueAfterRotation(t *testing.T) {
+
+	database := &Database{}
+	database.setKeyring(testKeyring(t, 1, 1))
+
+	key := recordKey([]byte("a"))
+	old, err := database.encryptValue(key, []byte("old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Values of older keys are read after rotation
+	database.setKeyring(testKeyring(t, 2, 1, 2))
+	current, err := database.encryptValue(key, []byte("current"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		data []byte
+		want string
+	}{
+		{old, "old"},
+		{current, "current"},
+	}
+
+	for _, test := range tests {
+
+		data, err := database.decryptValue(key, test.data)
+		if err != nil || string(data) != test.want {
+			t.Errorf("decryptValue = %q, %v, want %q", data, err, test.want)
+		}
+	}
+
+	// Without keyring encrypted values can't be read
+	database.setKeyring(nil)
+	if _, err := database.decryptValue(key, current); err != ErrInvalidCiphertext {
+		t.Errorf("decryptValue without keyring = %v, want %v", err, ErrInvalidCiphertext)
+	}
+}
+
+func TestEncryptExisting(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "data-snapshot-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyfile := filepath.Join(dir, "keys")
+	err = ioutil.WriteFile(keyfile, []byte("k1:"+base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))+"\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Index which was built before encryption is left out afterwards
+	viper.Set("collections.t.indexes", []map[string]interface{}{
+		{"name": "secret", "fields": []string{"secret"}},
+	})
+	defer viper.Set("collections.t.indexes", nil)
+
+	dbpath := filepath.Join(dir, "t")
+	db, err := OpenDatabase("t", "leveldb", dbpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		writeTestRecord(t, db, uint64(i+1), float64(i), Field{Name: "secret", Value: "plaintext"})
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); !db.getIndex("secret").IsReady(); {
+		if time.Now().After(deadline) {
+			t.Fatal("index isn't built")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	db.Close()
+
+	// Encryption is enabled on collection which has data already
+	viper.Set("encryption.keyfile", keyfile)
+	viper.Set("collections.t.encrypt", true)
+	masterKeys.once = sync.Once{}
+	defer func() {
+		viper.Set("encryption.keyfile", "")
+		viper.Set("collections.t.encrypt", false)
+		masterKeys.once = sync.Once{}
+	}()
+
+	reopened, err := OpenDatabase("t", "leveldb", dbpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	if len(reopened.indexes) != 0 {
+		t.Errorf("encrypted collection has %d indexes", len(reopened.indexes))
+	}
+
+	// Entries of the earlier index are removed in background
+	for deadline := time.Now().Add(5 * time.Second); ; {
+
+		iter := reopened.db.NewIterator(indexPrefix)
+		kept := iter.Next()
+		iter.Release()
+
+		if !kept {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("entries of index are kept in plaintext")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, prefix := range encryptedPrefixes {
+
+		iter := reopened.db.NewIterator(prefix)
+		for iter.Next() {
+			if !isEncrypted(iter.Value()) {
+				t.Errorf("value of %q isn't encrypted", iter.Key())
+			}
+		}
+		iter.Release()
+	}
+
+	stream := &testStream{}
+	err = reopened.FetchSnapshot(stream, &SnapshotOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stream.entries()) != 10 {
+		t.Errorf("snapshot has %d entries, want 10", len(stream.entries()))
+	}
+
+	_, stats, err := reopened.GetStats()
+	if err != nil || stats.RecordCount != 10 {
+		t.Errorf("GetStats = %+v, %v", stats, err)
+	}
+}
+
+func TestHashPrimaryKey(t *testing.T) {
+
+	plain := &Database{}
+	if pk := plain.hashPrimaryKey([]byte("a")); string(pk) != "a" {
+		t.Errorf("hashPrimaryKey without keyring = %q", pk)
+	}
+
+	database := &Database{}
+	kr := testKeyring(t, 1, 1)
+	kr.hashKey = bytes.Repeat([]byte{7}, 32)
+	database.setKeyring(kr)
+
+	other := &Database{}
+	otherKr := testKeyring(t, 1, 1)
+	otherKr.hashKey = bytes.Repeat([]byte{8}, 32)
+	other.setKeyring(otherKr)
+
+	tests := []struct {
+		a    []byte
+		b    []byte
+		db   *Database
+		same bool
+	}{
+		{[]byte("account-1"), []byte("account-1"), database, true},
+		{[]byte("account-1"), []byte("account-2"), database, false},
+		{[]byte("account-1"), []byte("account-1"), other, false},
+	}
+
+	for _, test := range tests {
+
+		a := database.hashPrimaryKey(test.a)
+		b := test.db.hashPrimaryKey(test.b)
+		if len(a) != 32 || bytes.Contains(a, test.a) {
+			t.Errorf("hashPrimaryKey(%q) = %x", test.a, a)
+		}
+
+		if bytes.Equal(a, b) != test.same {
+			t.Errorf("hashes of %q and %q are equal is %v, want %v", test.a, test.b, !test.same, test.same)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package data_snapshot
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultExpirationInterval = time.Minute
+	expirationChunkSize       = 1000
+)
+
+// ExpirationPolicy decides when records of collection expire. Records expire
+// after TTL since their last update, or at the time in Field plus TTL if the
+// field is set. Values of field are RFC 3339 strings or unix time in seconds.
+type ExpirationPolicy struct {
+	TTL   time.Duration
+	Field string
+}
+
+type ExpiredRecord struct {
+	Sequence  uint64          `json:"seq"`
+	Key       json.RawMessage `json:"key"`
+	Data      json.RawMessage `json:"data"`
+	ExpiredAt int64           `json:"expired_at"`
+}
+
+type ExpiredHandler func(database *Database, records []*ExpiredRecord)
+
+func getExpirationPolicy(collection string) *ExpirationPolicy {
+
+	policy := &ExpirationPolicy{
+		TTL:   getCollectionDuration(collection, "ttl", 0),
+		Field: getCollectionString(collection, "ttl_field"),
+	}
+
+	if !policy.IsEnabled() {
+		return nil
+	}
+
+	return policy
+}
+
+func (policy *ExpirationPolicy) IsEnabled() bool {
+	return policy.TTL > 0 || policy.Field != ""
+}
+
+// expiryOf returns expiration time of document in unix nanoseconds.
+func (policy *ExpirationPolicy) expiryOf(doc map[string]interface{}, now time.Time) (uint64, bool) {
+
+	base := now
+	if policy.Field != "" {
+		value, ok := lookupField(doc, policy.Field)
+		if !ok {
+			return 0, false
+		}
+
+		switch v := value.(type) {
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return 0, false
+			}
+
+			base = t
+		case float64:
+			sec, frac := math.Modf(v)
+			base = time.Unix(int64(sec), int64(frac*1e9))
+		default:
+			return 0, false
+		}
+	}
+
+	expiry := base.Add(policy.TTL).UnixNano()
+	if expiry < 0 {
+		expiry = 0
+	}
+
+	return uint64(expiry), true
+}
+
+func (database *Database) SetExpiredHandler(handler ExpiredHandler) {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	database.expiredHandler = handler
+}
+
+// updateExpiration moves record to its new place in expiration queue, next
+// is nil when record is deleted.
+func (database *Database) updateExpiration(batch store.Batch, pk []byte, next map[string]interface{}, keyData []byte) error {
+
+	data, err := database.getValue(database.db, ttlKey(pk))
+	if err == nil && len(data) >= 8 {
+		batch.Delete(expirationKey(SortableBytesToUint64(data[:8]), pk))
+		batch.Delete(ttlKey(pk))
+	} else if err != nil && err != store.ErrNotFound {
+		return err
+	}
+
+	if next == nil || database.expiration == nil {
+		return nil
+	}
+
+	expiry, ok := database.expiration.expiryOf(next, time.Now())
+	if !ok {
+		return nil
+	}
+
+	value, err := database.encryptValue(ttlKey(pk), append(Uint64ToSortableBytes(expiry), keyData...))
+	if err != nil {
+		return err
+	}
+
+	batch.Put(expirationKey(expiry, pk), []byte{})
+	batch.Put(ttlKey(pk), value)
+
+	return nil
+}
+
+// ExpireRecords deletes records which expired before now. No event caused the
+// change, so tombstones and versions of expired records are put at the
+// sequence after current one without moving current sequence, which belongs
+// to event store. History as of current sequence stays the same, and the
+// next event takes in expiry along with its own change. Frozen collections
+// are left alone until ingestion resumes.
+func (database *Database) ExpireRecords(now time.Time, limit int) ([]*ExpiredRecord, error) {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if database.frozen {
+		return nil, nil
+	}
+
+	seq, err := getSequence(database.db)
+	if err != nil {
+		return nil, err
+	}
+
+	expirySeq := seq + 1
+
+	deadline := uint64(now.UnixNano())
+	pks := make([][]byte, 0)
+
+	iter := database.db.NewIterator(expirationPrefix)
+	for len(pks) < limit && iter.Next() {
+
+		expiry, pk := parseOrderedKey(expirationPrefix, iter.Key())
+		if expiry > deadline {
+			break
+		}
+
+		pks = append(pks, cloneBytes(pk))
+	}
+
+	err = iter.Error()
+	iter.Release()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pks) == 0 {
+		return nil, nil
+	}
+
+	batch := database.db.NewBatch()
+	tree := newChecksumTree()
+	records := make([]*ExpiredRecord, 0, len(pks))
+	bytesDelta := int64(0)
+	for _, pk := range pks {
+
+		ttlData, err := database.getValue(database.db, ttlKey(pk))
+		if err != nil {
+			return nil, err
+		}
+
+		keyData := ttlData[8:]
+		err = database.updateExpiration(batch, pk, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := database.getValue(database.db, recordKey(pk))
+		if err == store.ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		doc := make(map[string]interface{})
+		err = json.Unmarshal(data, &doc)
+		if err != nil {
+			return nil, err
+		}
+
+		err = database.putTombstone(batch, expirySeq, pk, keyData)
+		if err != nil {
+			return nil, err
+		}
+
+		err = database.trackVersion(batch, expirySeq, pk, keyData, true)
+		if err != nil {
+			return nil, err
+		}
+
+		batch.Delete(recordKey(pk))
+		database.updateIndexes(batch, pk, doc, nil)
+		tree.remove(pk, data)
+		bytesDelta -= int64(len(data))
+
+		records = append(records, &ExpiredRecord{
+			Sequence:  expirySeq,
+			Key:       json.RawMessage(nullIfEmpty(keyData)),
+			Data:      json.RawMessage(data),
+			ExpiredAt: now.UnixNano(),
+		})
+	}
+
+	if len(records) == 0 {
+		return nil, database.db.Write(batch)
+	}
+
+	err = database.putChecksum(batch, tree)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.writeRecords(batch, seq, -int64(len(records)), bytesDelta)
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (database *Database) runExpirationSweeper() {
+
+	interval := getCollectionDuration(database.name, "ttl_sweep_interval", defaultExpirationInterval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Collection may have been closed for a while, so sweep right away
+	for ok := true; ok; ok = database.tick(ticker) {
+
+		for {
+			records, err := database.ExpireRecords(time.Now(), expirationChunkSize)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"collection": database.name,
+				}).Error(err)
+				break
+			}
+
+			if len(records) == 0 {
+				break
+			}
+
+			log.WithFields(log.Fields{
+				"collection": database.name,
+				"count":      len(records),
+			}).Info("Expired records")
+
+			database.mutex.Lock()
+			handler := database.expiredHandler
+			database.mutex.Unlock()
+
+			if handler != nil {
+				handler(database, records)
+			}
+
+			if len(records) < expirationChunkSize {
+				break
+			}
+		}
+	}
+}
+
+func nullIfEmpty(data []byte) []byte {
+
+	if len(data) == 0 {
+		return []byte("null")
+	}
+
+	return data
+}
@@ -0,0 +1,200 @@
+package data_snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FieldMask picks fields of records which are sent to clients. Paths are
+// dotted like fields of indexes and filters. Included fields are picked
+// first, all fields if none are, then excluded fields are removed. Primary
+// key fields are kept along with included fields, so records can still be
+// matched with deltas.
+type FieldMask struct {
+	include fieldTree
+	exclude fieldTree
+}
+
+// fieldTree holds paths by their names, a name without children stands for
+// the whole field.
+type fieldTree map[string]fieldTree
+
+func NewFieldMask(include []string, exclude []string) (*FieldMask, error) {
+
+	mask := &FieldMask{}
+
+	if len(include) > 0 {
+		tree, err := buildFieldTree(include)
+		if err != nil {
+			return nil, err
+		}
+
+		mask.include = tree
+	}
+
+	if len(exclude) > 0 {
+		tree, err := buildFieldTree(exclude)
+		if err != nil {
+			return nil, err
+		}
+
+		mask.exclude = tree
+	}
+
+	return mask, nil
+}
+
+func buildFieldTree(paths []string) (fieldTree, error) {
+
+	tree := make(fieldTree)
+	for _, path := range paths {
+
+		names := strings.Split(path, ".")
+		node := tree
+		for i, name := range names {
+
+			if name == "" {
+				return nil, fmt.Errorf("invalid field path \"%s\"", path)
+			}
+
+			child, ok := node[name]
+			if ok && len(child) == 0 {
+				// Parent is taken as a whole already
+				break
+			}
+
+			if i == len(names)-1 {
+				node[name] = fieldTree{}
+				break
+			}
+
+			if !ok {
+				child = make(fieldTree)
+				node[name] = child
+			}
+
+			node = child
+		}
+	}
+
+	return tree, nil
+}
+
+// String returns included and excluded paths of mask in order, so masks of
+// the same fields read the same.
+func (mask *FieldMask) String() string {
+	return "+" + strings.Join(mask.include.paths(""), ",") + " -" + strings.Join(mask.exclude.paths(""), ",")
+}
+
+func (tree fieldTree) paths(prefix string) []string {
+
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	paths := make([]string, 0, len(tree))
+	for _, name := range names {
+
+		if len(tree[name]) == 0 {
+			paths = append(paths, prefix+name)
+			continue
+		}
+
+		paths = append(paths, tree[name].paths(prefix+name+".")...)
+	}
+
+	return paths
+}
+
+// Apply re-encodes record data with fields of mask.
+func (mask *FieldMask) Apply(data []byte, primary []string) ([]byte, error) {
+
+	doc := make(map[string]interface{})
+
+	// Numbers are kept as they are
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if mask.include != nil {
+		picked := pickFields(doc, mask.include)
+		for _, name := range primary {
+			if value, ok := doc[name]; ok {
+				picked[name] = value
+			}
+		}
+
+		doc = picked
+	}
+
+	if mask.exclude != nil {
+		doc = dropFields(doc, mask.exclude)
+	}
+
+	return json.Marshal(doc)
+}
+
+func pickFields(doc map[string]interface{}, tree fieldTree) map[string]interface{} {
+
+	picked := make(map[string]interface{}, len(tree))
+	for name, children := range tree {
+
+		value, ok := doc[name]
+		if !ok {
+			continue
+		}
+
+		if len(children) == 0 {
+			picked[name] = value
+			continue
+		}
+
+		if obj, ok := value.(map[string]interface{}); ok {
+			picked[name] = pickFields(obj, children)
+		}
+	}
+
+	return picked
+}
+
+// dropFields returns document without fields of tree, objects are copied
+// before they are changed.
+func dropFields(doc map[string]interface{}, tree fieldTree) map[string]interface{} {
+
+	doc = copyDocument(doc)
+	for name, children := range tree {
+
+		if len(children) == 0 {
+			delete(doc, name)
+			continue
+		}
+
+		if obj, ok := doc[name].(map[string]interface{}); ok {
+			doc[name] = dropFields(obj, children)
+		}
+	}
+
+	return doc
+}
+
+// primaryFields returns names of primary key fields which are known from
+// schema.
+func (database *Database) primaryFields() []string {
+
+	names := make([]string, 0, 1)
+	for _, field := range database.GetSchema().Fields {
+		if field.Primary {
+			names = append(names, field.Name)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,62 @@
+package data_snapshot
+
+import (
+	"testing"
+)
+
+func TestFieldMaskApply(t *testing.T) {
+
+	record := []byte(`{"id":7,"name":"n","big":12345678901234567890,"addr":{"city":"Oslo","zip":"0150","geo":{"lat":1.5,"lon":2.5}},"tags":["a","b"]}`)
+
+	tests := []struct {
+		include []string
+		exclude []string
+		want    string
+	}{
+		{[]string{"name"}, nil, `{"id":7,"name":"n"}`},
+		{[]string{"big"}, nil, `{"big":12345678901234567890,"id":7}`},
+		{[]string{"addr.city", "addr.geo.lat"}, nil, `{"addr":{"city":"Oslo","geo":{"lat":1.5}},"id":7}`},
+		{[]string{"addr", "addr.city"}, nil, `{"addr":{"city":"Oslo","geo":{"lat":1.5,"lon":2.5},"zip":"0150"},"id":7}`},
+		{[]string{"name.first", "missing"}, nil, `{"id":7}`},
+		{nil, []string{"addr.geo", "tags", "nope.x"}, `{"addr":{"city":"Oslo","zip":"0150"},"big":12345678901234567890,"id":7,"name":"n"}`},
+		{[]string{"addr"}, []string{"addr.zip", "id"}, `{"addr":{"city":"Oslo","geo":{"lat":1.5,"lon":2.5}}}`},
+	}
+
+	for _, test := range tests {
+
+		mask, err := NewFieldMask(test.include, test.exclude)
+		if err != nil {
+			t.Fatalf("NewFieldMask(%v, %v): %v", test.include, test.exclude, err)
+		}
+
+		data, err := mask.Apply(record, []string{"id"})
+		if err != nil {
+			t.Fatalf("Apply(%v, %v): %v", test.include, test.exclude, err)
+		}
+
+		if string(data) != test.want {
+			t.Errorf("Apply(%v, %v) = %s, want %s", test.include, test.exclude, data, test.want)
+		}
+	}
+}
+
+func TestNewFieldMaskErrors(t *testing.T) {
+
+	tests := [][]string{
+		{""},
+		{"a..b"},
+		{".a"},
+		{"a."},
+	}
+
+	for _, paths := range tests {
+
+		if _, err := NewFieldMask(paths, nil); err == nil {
+			t.Errorf("NewFieldMask(%v, nil) succeeded", paths)
+		}
+
+		if _, err := NewFieldMask(nil, paths); err == nil {
+			t.Errorf("NewFieldMask(nil, %v) succeeded", paths)
+		}
+	}
+}
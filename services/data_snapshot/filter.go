@@ -0,0 +1,764 @@
+package data_snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+)
+
+// Filter selects records by expression over their fields, such as
+//
+//	tenant = "acme" AND (status IN ("active", "trial") OR score >= 10)
+//
+// Fields are dotted paths into records as in indexes, in backquotes if they
+// aren't plain identifiers, and missing fields are null. Values are JSON
+// literals, strings may be in single quotes as well. Operators are =, !=,
+// <, <=, >, >=, IN, NOT IN, STARTS WITH, IS NULL and IS NOT NULL, combined
+// with AND, OR, NOT and parentheses. Order is only compared between numbers
+// or between strings, other comparisons don't match.
+type Filter struct {
+	source string
+	expr   filterExpr
+}
+
+// Deepest nesting of filter expression.
+const maxFilterDepth = 32
+
+type filterExpr interface {
+	match(doc map[string]interface{}) bool
+}
+
+type filterAnd struct {
+	terms []filterExpr
+}
+
+type filterOr struct {
+	terms []filterExpr
+}
+
+type filterNot struct {
+	term filterExpr
+}
+
+type filterCompare struct {
+	field string
+	op    string
+	value interface{}
+}
+
+type filterIn struct {
+	field  string
+	values []interface{}
+}
+
+type filterPrefix struct {
+	field  string
+	prefix string
+}
+
+type filterNull struct {
+	field string
+}
+
+// ParseFilter parses filter expression.
+func ParseFilter(source string) (*Filter, error) {
+
+	tokens, err := scanFilter(source)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &filterParser{
+		tokens: tokens,
+	}
+
+	expr, err := parser.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := parser.peek(); tok.kind != tokenEnd {
+		return nil, parser.errorAt(tok, "unexpected \"%s\"", tok.text)
+	}
+
+	return &Filter{
+		source: source,
+		expr:   expr,
+	}, nil
+}
+
+func (filter *Filter) String() string {
+	return filter.source
+}
+
+// Match tells whether record data matches filter.
+func (filter *Filter) Match(data []byte) (bool, error) {
+
+	doc := make(map[string]interface{})
+	err := json.Unmarshal(data, &doc)
+	if err != nil {
+		return false, err
+	}
+
+	return filter.expr.match(doc), nil
+}
+
+func (expr *filterAnd) match(doc map[string]interface{}) bool {
+
+	for _, term := range expr.terms {
+		if !term.match(doc) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (expr *filterOr) match(doc map[string]interface{}) bool {
+
+	for _, term := range expr.terms {
+		if term.match(doc) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (expr *filterNot) match(doc map[string]interface{}) bool {
+	return !expr.term.match(doc)
+}
+
+func (expr *filterCompare) match(doc map[string]interface{}) bool {
+
+	value, _ := lookupField(doc, expr.field)
+
+	switch expr.op {
+	case "=":
+		return equalValues(value, expr.value)
+	case "!=":
+		return !equalValues(value, expr.value)
+	}
+
+	c, ok := compareValues(value, expr.value)
+	if !ok {
+		return false
+	}
+
+	switch expr.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+
+	return c >= 0
+}
+
+func (expr *filterIn) match(doc map[string]interface{}) bool {
+
+	value, _ := lookupField(doc, expr.field)
+	for _, v := range expr.values {
+		if equalValues(value, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (expr *filterPrefix) match(doc map[string]interface{}) bool {
+
+	value, _ := lookupField(doc, expr.field)
+	s, ok := value.(string)
+
+	return ok && strings.HasPrefix(s, expr.prefix)
+}
+
+func (expr *filterNull) match(doc map[string]interface{}) bool {
+
+	value, _ := lookupField(doc, expr.field)
+
+	return value == nil
+}
+
+func equalValues(a interface{}, b interface{}) bool {
+
+	if c, ok := compareValues(a, b); ok {
+		return c == 0
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func compareValues(a interface{}, b interface{}) (int, bool) {
+
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+
+		if x < y {
+			return -1, true
+		} else if x > y {
+			return 1, true
+		}
+
+		return 0, true
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+
+		return strings.Compare(x, y), true
+	}
+
+	return 0, false
+}
+
+// Tokens of filter expression.
+const (
+	tokenEnd = iota
+	tokenIdent
+	tokenQuotedIdent
+	tokenString
+	tokenNumber
+	tokenSymbol
+)
+
+type filterToken struct {
+	kind  int
+	text  string
+	value interface{}
+	pos   int
+}
+
+func scanFilter(source string) ([]*filterToken, error) {
+
+	tokens := make([]*filterToken, 0)
+	for i := 0; i < len(source); {
+
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '(' || c == ')' || c == ',' || c == '=':
+			tokens = append(tokens, &filterToken{kind: tokenSymbol, text: source[i : i+1], pos: i})
+			i++
+			continue
+		case c == '!' || c == '<' || c == '>':
+			n := 1
+			if i+1 < len(source) && source[i+1] == '=' {
+				n = 2
+			}
+
+			if c == '!' && n == 1 {
+				return nil, fmt.Errorf("invalid filter: unexpected \"!\" at %d", i)
+			}
+
+			tokens = append(tokens, &filterToken{kind: tokenSymbol, text: source[i : i+n], pos: i})
+			i += n
+			continue
+		case c == '"' || c == '\'' || c == '`':
+			end, err := scanQuoted(source, i)
+			if err != nil {
+				return nil, err
+			}
+
+			tok := &filterToken{kind: tokenString, text: source[i:end], pos: i}
+			if c == '`' {
+				tok.kind = tokenQuotedIdent
+				tok.value = source[i+1 : end-1]
+			} else {
+				tok.value, err = unquoteFilterString(source[i:end])
+				if err != nil {
+					return nil, fmt.Errorf("invalid filter: invalid string at %d", i)
+				}
+			}
+
+			tokens = append(tokens, tok)
+			i = end
+			continue
+		case c == '-' || c == '+' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(source) && strings.IndexByte("0123456789.eE+-", source[end]) >= 0 {
+				end++
+			}
+
+			f, err := strconv.ParseFloat(source[i:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter: invalid number at %d", i)
+			}
+
+			tokens = append(tokens, &filterToken{kind: tokenNumber, text: source[i:end], value: f, pos: i})
+			i = end
+			continue
+		case c == '_' || c < 0x80 && unicode.IsLetter(rune(c)):
+			end := i + 1
+			for end < len(source) && isFilterIdentByte(source[end]) {
+				end++
+			}
+
+			tokens = append(tokens, &filterToken{kind: tokenIdent, text: source[i:end], pos: i})
+			i = end
+			continue
+		}
+
+		return nil, fmt.Errorf("invalid filter: unexpected \"%c\" at %d", c, i)
+	}
+
+	return append(tokens, &filterToken{kind: tokenEnd, text: "end of filter", pos: len(source)}), nil
+}
+
+func isFilterIdentByte(c byte) bool {
+	return c == '_' || c == '.' || c >= '0' && c <= '9' || c < 0x80 && unicode.IsLetter(rune(c))
+}
+
+// scanQuoted returns end of quoted text which starts at i, quotes are escaped
+// by backslash.
+func scanQuoted(source string, i int) (int, error) {
+
+	quote := source[i]
+	for j := i + 1; j < len(source); j++ {
+		switch source[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1, nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid filter: unterminated quote at %d", i)
+}
+
+// unquoteFilterString decodes string of JSON, or such string in single
+// quotes.
+func unquoteFilterString(text string) (string, error) {
+
+	if text[0] == '\'' {
+		var b strings.Builder
+		b.WriteByte('"')
+		for i := 1; i < len(text)-1; i++ {
+			switch {
+			case text[i] == '\\' && text[i+1] == '\'':
+				b.WriteByte('\'')
+				i++
+			case text[i] == '\\':
+				b.WriteString(text[i : i+2])
+				i++
+			case text[i] == '"':
+				b.WriteString(`\"`)
+			default:
+				b.WriteByte(text[i])
+			}
+		}
+		b.WriteByte('"')
+		text = b.String()
+	}
+
+	var s string
+	err := json.Unmarshal([]byte(text), &s)
+
+	return s, err
+}
+
+type filterParser struct {
+	tokens []*filterToken
+	pos    int
+}
+
+func (parser *filterParser) peek() *filterToken {
+	return parser.tokens[parser.pos]
+}
+
+func (parser *filterParser) next() *filterToken {
+
+	tok := parser.tokens[parser.pos]
+	if tok.kind != tokenEnd {
+		parser.pos++
+	}
+
+	return tok
+}
+
+func (parser *filterParser) errorAt(tok *filterToken, format string, args ...interface{}) error {
+	return fmt.Errorf("invalid filter: "+format+" at %d", append(args, tok.pos)...)
+}
+
+// keyword consumes the next token if it is keyword.
+func (parser *filterParser) keyword(word string) bool {
+
+	tok := parser.peek()
+	if tok.kind == tokenIdent && strings.EqualFold(tok.text, word) {
+		parser.pos++
+		return true
+	}
+
+	return false
+}
+
+func (parser *filterParser) symbol(text string) bool {
+
+	tok := parser.peek()
+	if tok.kind == tokenSymbol && tok.text == text {
+		parser.pos++
+		return true
+	}
+
+	return false
+}
+
+func (parser *filterParser) expectSymbol(text string) error {
+
+	if !parser.symbol(text) {
+		tok := parser.peek()
+		return parser.errorAt(tok, "expected \"%s\" instead of \"%s\"", text, tok.text)
+	}
+
+	return nil
+}
+
+func (parser *filterParser) parseOr(depth int) (filterExpr, error) {
+
+	if depth > maxFilterDepth {
+		return nil, parser.errorAt(parser.peek(), "too deeply nested expression")
+	}
+
+	terms := make([]filterExpr, 0, 1)
+	for {
+		term, err := parser.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+
+		terms = append(terms, term)
+		if !parser.keyword("OR") {
+			break
+		}
+	}
+
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+
+	return &filterOr{terms: terms}, nil
+}
+
+func (parser *filterParser) parseAnd(depth int) (filterExpr, error) {
+
+	terms := make([]filterExpr, 0, 1)
+	for {
+		term, err := parser.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+
+		// Nested conjunctions are flattened, so indexes can serve any term
+		if and, ok := term.(*filterAnd); ok {
+			terms = append(terms, and.terms...)
+		} else {
+			terms = append(terms, term)
+		}
+
+		if !parser.keyword("AND") {
+			break
+		}
+	}
+
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+
+	return &filterAnd{terms: terms}, nil
+}
+
+func (parser *filterParser) parseNot(depth int) (filterExpr, error) {
+
+	if parser.keyword("NOT") {
+		term, err := parser.parseNot(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		return &filterNot{term: term}, nil
+	}
+
+	if parser.symbol("(") {
+		expr, err := parser.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		return expr, parser.expectSymbol(")")
+	}
+
+	return parser.parseCondition()
+}
+
+func (parser *filterParser) parseCondition() (filterExpr, error) {
+
+	tok := parser.next()
+	var field string
+	switch tok.kind {
+	case tokenIdent:
+		field = tok.text
+	case tokenQuotedIdent:
+		field = tok.value.(string)
+	default:
+		return nil, parser.errorAt(tok, "expected field instead of \"%s\"", tok.text)
+	}
+
+	if field == "" {
+		return nil, parser.errorAt(tok, "empty field")
+	}
+
+	if parser.keyword("IS") {
+		negated := parser.keyword("NOT")
+		if !parser.keyword("NULL") {
+			return nil, parser.errorAt(parser.peek(), "expected NULL")
+		}
+
+		return negate(&filterNull{field: field}, negated), nil
+	}
+
+	if parser.keyword("STARTS") {
+		if !parser.keyword("WITH") {
+			return nil, parser.errorAt(parser.peek(), "expected WITH")
+		}
+
+		tok := parser.next()
+		if tok.kind != tokenString {
+			return nil, parser.errorAt(tok, "expected string instead of \"%s\"", tok.text)
+		}
+
+		return &filterPrefix{field: field, prefix: tok.value.(string)}, nil
+	}
+
+	negated := parser.keyword("NOT")
+	if parser.keyword("IN") {
+		values, err := parser.parseList()
+		if err != nil {
+			return nil, err
+		}
+
+		return negate(&filterIn{field: field, values: values}, negated), nil
+	} else if negated {
+		return nil, parser.errorAt(parser.peek(), "expected IN")
+	}
+
+	op := parser.next()
+	if op.kind != tokenSymbol || strings.IndexByte("=!<>", op.text[0]) < 0 {
+		return nil, parser.errorAt(op, "expected operator instead of \"%s\"", op.text)
+	}
+
+	value, err := parser.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return &filterCompare{field: field, op: op.text, value: value}, nil
+}
+
+func negate(expr filterExpr, negated bool) filterExpr {
+
+	if negated {
+		return &filterNot{term: expr}
+	}
+
+	return expr
+}
+
+func (parser *filterParser) parseList() ([]interface{}, error) {
+
+	err := parser.expectSymbol("(")
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, 0)
+	for {
+		value, err := parser.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+		if !parser.symbol(",") {
+			break
+		}
+	}
+
+	return values, parser.expectSymbol(")")
+}
+
+func (parser *filterParser) parseValue() (interface{}, error) {
+
+	tok := parser.next()
+	switch tok.kind {
+	case tokenString, tokenNumber:
+		return tok.value, nil
+	case tokenIdent:
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	}
+
+	return nil, parser.errorAt(tok, "expected value instead of \"%s\"", tok.text)
+}
+
+// indexScan is a set of ranges of index entries which hold all records that
+// may match filter. Ranges are in order and don't overlap, ends are
+// exclusive.
+type indexScan struct {
+	index  *Index
+	ranges [][2][]byte
+}
+
+// planIndexScan finds index whose leading field is matched by a term of
+// filter which all records have to match. Only indexes which were ready in
+// snapshot are used, lookups by value are preferred over prefixes and
+// ranges.
+func (database *Database) planIndexScan(snapshot store.Snapshot, filter *Filter) *indexScan {
+
+	terms := []filterExpr{filter.expr}
+	if and, ok := filter.expr.(*filterAnd); ok {
+		terms = and.terms
+	}
+
+	var best *indexScan
+	bestCost := 0
+	for _, index := range database.indexes {
+
+		if !isIndexReadyIn(snapshot, index) {
+			continue
+		}
+
+		for _, term := range terms {
+
+			ranges, cost := indexRanges(index, term)
+			if ranges == nil || (best != nil && cost >= bestCost) {
+				continue
+			}
+
+			best = &indexScan{
+				index:  index,
+				ranges: ranges,
+			}
+			bestCost = cost
+		}
+	}
+
+	return best
+}
+
+func isIndexReadyIn(snapshot store.Snapshot, index *Index) bool {
+
+	data, err := snapshot.Get(index.stateKey())
+	if err != nil {
+		return false
+	}
+
+	var def indexDefinition
+	err = json.Unmarshal(data, &def)
+
+	return err == nil && def.Ready && equalFields(def.Fields, index.Fields)
+}
+
+// indexRanges returns ranges of index entries for term along with cost of
+// scanning them, or nil if index can't serve term.
+func indexRanges(index *Index, term filterExpr) ([][2][]byte, int) {
+
+	prefix := index.prefix()
+	exact := func(value interface{}) [2][]byte {
+		key := appendIndexValue(cloneBytes(prefix), value)
+		return [2][]byte{key, store.PrefixEnd(key)}
+	}
+
+	switch t := term.(type) {
+	case *filterCompare:
+		if t.field != index.Fields[0] || t.op == "!=" {
+			return nil, 0
+		}
+
+		if t.op == "=" {
+			return [][2][]byte{exact(t.value)}, 0
+		}
+
+		// Ranges stop at the end of values of the same type
+		var tag byte
+		switch t.value.(type) {
+		case float64:
+			tag = indexTagNumber
+		case string:
+			tag = indexTagString
+		default:
+			return nil, 0
+		}
+
+		typeStart := append(cloneBytes(prefix), tag)
+		key := appendIndexValue(cloneBytes(prefix), t.value)
+		if t.op == "<" || t.op == "<=" {
+			return [][2][]byte{{typeStart, store.PrefixEnd(key)}}, 2
+		}
+
+		return [][2][]byte{{key, store.PrefixEnd(typeStart)}}, 2
+	case *filterIn:
+		if t.field != index.Fields[0] {
+			return nil, 0
+		}
+
+		ranges := make([][2][]byte, 0, len(t.values))
+		for _, value := range t.values {
+			ranges = append(ranges, exact(value))
+		}
+
+		sort.Slice(ranges, func(i, j int) bool {
+			return bytes.Compare(ranges[i][0], ranges[j][0]) < 0
+		})
+
+		unique := ranges[:1]
+		for _, r := range ranges[1:] {
+			if !bytes.Equal(r[0], unique[len(unique)-1][0]) {
+				unique = append(unique, r)
+			}
+		}
+
+		return unique, 0
+	case *filterNull:
+		if t.field != index.Fields[0] {
+			return nil, 0
+		}
+
+		return [][2][]byte{exact(nil)}, 0
+	case *filterPrefix:
+		if t.field != index.Fields[0] {
+			return nil, 0
+		}
+
+		// Encoded string without its terminator
+		key := appendIndexValue(cloneBytes(prefix), t.prefix)
+		key = key[:len(key)-2]
+
+		return [][2][]byte{{key, store.PrefixEnd(key)}}, 1
+	}
+
+	return nil, 0
+}
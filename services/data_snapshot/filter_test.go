@@ -0,0 +1,79 @@
+package data_snapshot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterMatch(t *testing.T) {
+
+	record := []byte(`{"id":1,"tenant":"acme-eu","name":"o'neil","n":3,"score":2.5,"active":false,"addr":{"city":"Oslo"},"tags":null}`)
+
+	tests := []struct {
+		source string
+		match  bool
+	}{
+		{`tenant = "acme-eu"`, true},
+		{`tenant = 'acme'`, false},
+		{`name = 'o\'neil'`, true},
+		{`tenant != "acme"`, true},
+		{`tenant STARTS WITH "acme"`, true},
+		{`tenant STARTS WITH "beta"`, false},
+		{`n IN (1, 2, 3)`, true},
+		{`n NOT IN (1, 2, 3)`, false},
+		{`n >= 3 AND n < 4`, true},
+		{`n > 3 OR score <= 2.5`, true},
+		{`NOT active = true`, true},
+		{`(n = 1 OR n = 3) AND score IS NOT NULL`, true},
+		{`addr.city = "Oslo"`, true},
+		{"`addr.city` IN (\"Rome\", null)", false},
+		{`missing IS NULL`, true},
+		{`tags IS NULL`, true},
+		{`tenant IS NOT NULL`, true},
+		{`score < "z"`, false},
+		{`tenant > 1`, false},
+		{`id = 1 and active = false`, true},
+	}
+
+	for _, test := range tests {
+
+		filter, err := ParseFilter(test.source)
+		if err != nil {
+			t.Fatalf("ParseFilter(%q): %v", test.source, err)
+		}
+
+		match, err := filter.Match(record)
+		if err != nil {
+			t.Fatalf("Match(%q): %v", test.source, err)
+		}
+
+		if match != test.match {
+			t.Errorf("Match(%q) = %v, want %v", test.source, match, test.match)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+
+	tests := []string{
+		``,
+		`tenant`,
+		`tenant = `,
+		`tenant == 1`,
+		`(a = 1`,
+		`a = 1 b = 2`,
+		`a IN ()`,
+		`a NOT = 1`,
+		`a = "x`,
+		`a STARTS WITH 1`,
+		`a ! 1`,
+		`a = abc`,
+		strings.Repeat("(", maxFilterDepth+1) + "a = 1" + strings.Repeat(")", maxFilterDepth+1),
+	}
+
+	for _, source := range tests {
+		if _, err := ParseFilter(source); err == nil {
+			t.Errorf("ParseFilter(%q) succeeded", source)
+		}
+	}
+}
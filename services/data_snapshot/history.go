@@ -0,0 +1,549 @@
+package data_snapshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	pb "gravity-data-snapshot/pb"
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrHistoryDisabled    = errors.New("history is not enabled for collection")
+	ErrHistoryUnavailable = errors.New("collection at requested sequence is no longer available")
+)
+
+const (
+	defaultHistoryGCInterval = 10 * time.Minute
+	historyChunkSize         = 1000
+)
+
+// HistoryPolicy decides how many versions of records are kept. Versions are
+// limited by count, age or both, the latest version of record is always kept
+// until record is deleted.
+type HistoryPolicy struct {
+	Versions  int
+	Retention time.Duration
+}
+
+// RecordVersion is state of record after change at sequence, deleted
+// versions carry primary key data only.
+type RecordVersion struct {
+	Sequence  uint64          `json:"seq"`
+	UpdatedAt int64           `json:"updated_at"`
+	Deleted   bool            `json:"deleted,omitempty"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func getHistoryPolicy(collection string) *HistoryPolicy {
+
+	policy := &HistoryPolicy{
+		Versions:  getCollectionInt(collection, "history_versions"),
+		Retention: getCollectionDuration(collection, "history_retention", 0),
+	}
+
+	if policy.Versions <= 0 && policy.Retention <= 0 {
+		return nil
+	}
+
+	return policy
+}
+
+func historyKey(pk []byte, sequence uint64) []byte {
+	return prefixedKey(historyRecordPrefix(pk), Uint64ToSortableBytes(sequence))
+}
+
+// historyRecordPrefix has length of primary key in front, so that versions of
+// records never interleave when primary keys are not hashed.
+func historyRecordPrefix(pk []byte) []byte {
+
+	length := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(length, uint64(len(pk)))
+
+	return prefixedKey(historyPrefix, length[:n], pk)
+}
+
+// parseHistoryKey returns primary key and sequence of version.
+func parseHistoryKey(key []byte) ([]byte, uint64) {
+
+	data := key[len(historyPrefix):]
+	length, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) != length+8 {
+		return nil, 0
+	}
+
+	pk := data[n : n+int(length)]
+
+	return pk, SortableBytesToUint64(data[n+int(length):])
+}
+
+// initHistory starts history of collection with current records, so that
+// collection is available as of current sequence onward. History is removed
+// if it was disabled since.
+func (database *Database) initHistory() error {
+
+	data, err := database.db.Get(historyHorizonKey)
+	if err != nil && err != store.ErrNotFound {
+		return err
+	}
+
+	if database.history == nil {
+		if err == store.ErrNotFound {
+			return nil
+		}
+
+		err := database.deletePrefix(historyPrefix)
+		if err != nil {
+			return err
+		}
+
+		log.WithFields(log.Fields{
+			"collection": database.name,
+		}).Info("Removed history of collection")
+
+		return database.db.Delete(historyHorizonKey)
+	}
+
+	if err == nil {
+		database.historyHorizon = BytesToUint64(data)
+		return nil
+	}
+
+	seq, err := database.GetSequence()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixNano()
+	batch := database.db.NewBatch()
+	iter := database.db.NewIterator(recordPrefix)
+	for iter.Next() {
+
+		pk := primaryKeyOf(iter.Key())
+
+		data, err := database.decodeValue(iter.Key(), iter.Value())
+		if err != nil {
+			iter.Release()
+			return err
+		}
+
+		// Records which were written before revisions existed are dated to
+		// current sequence
+		sequence := seq
+		revData, err := database.db.Get(revisionKey(pk))
+		if err == nil {
+			sequence = BytesToUint64(revData)
+		} else if err != store.ErrNotFound {
+			iter.Release()
+			return err
+		}
+
+		err = database.putVersion(batch, pk, &RecordVersion{
+			Sequence:  sequence,
+			UpdatedAt: now,
+			Data:      cloneBytes(data),
+		})
+		if err != nil {
+			iter.Release()
+			return err
+		}
+
+		if batch.Len() >= historyChunkSize {
+			err := database.db.Write(batch)
+			if err != nil {
+				iter.Release()
+				return err
+			}
+
+			batch = database.db.NewBatch()
+		}
+	}
+
+	err = iter.Error()
+	iter.Release()
+	if err != nil {
+		return err
+	}
+
+	batch.Put(historyHorizonKey, Uint64ToBytes(seq))
+
+	err = database.db.Write(batch)
+	if err != nil {
+		return err
+	}
+
+	database.historyHorizon = seq
+
+	return nil
+}
+
+func (database *Database) putVersion(batch store.Batch, pk []byte, version *RecordVersion) error {
+
+	data, err := json.Marshal(version)
+	if err != nil {
+		return err
+	}
+
+	key := historyKey(pk, version.Sequence)
+	value, err := database.encryptValue(key, database.compressValue(data))
+	if err != nil {
+		return err
+	}
+
+	batch.Put(key, value)
+
+	return nil
+}
+
+func (database *Database) decodeVersion(key []byte, value []byte) (*RecordVersion, error) {
+
+	data, err := database.decodeValue(key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	version := &RecordVersion{}
+	err = json.Unmarshal(data, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return version, nil
+}
+
+// raiseHistoryHorizon moves horizon past versions which are removed, state of
+// collection before it can't be rebuilt anymore.
+func (database *Database) raiseHistoryHorizon(batch store.Batch, sequence uint64) {
+
+	if sequence <= database.historyHorizon {
+		return
+	}
+
+	database.historyHorizon = sequence
+	batch.Put(historyHorizonKey, Uint64ToBytes(sequence))
+}
+
+// trackVersion adds version of record to history and removes the oldest
+// versions beyond history_versions.
+func (database *Database) trackVersion(batch store.Batch, sequence uint64, pk []byte, data []byte, deleted bool) error {
+
+	if database.history == nil {
+		return nil
+	}
+
+	err := database.putVersion(batch, pk, &RecordVersion{
+		Sequence:  sequence,
+		UpdatedAt: time.Now().UnixNano(),
+		Deleted:   deleted,
+		Data:      nullIfEmpty(data),
+	})
+	if err != nil {
+		return err
+	}
+
+	if database.history.Versions <= 0 {
+		return nil
+	}
+
+	sequences, err := database.versionSequences(database.db, pk)
+	if err != nil {
+		return err
+	}
+
+	sequences = append(sequences, sequence)
+	excess := len(sequences) - database.history.Versions
+	if excess <= 0 {
+		return nil
+	}
+
+	for _, seq := range sequences[:excess] {
+		batch.Delete(historyKey(pk, seq))
+	}
+
+	database.raiseHistoryHorizon(batch, sequences[excess])
+
+	return nil
+}
+
+// versionSequences returns sequences of versions of record in order.
+func (database *Database) versionSequences(reader store.Reader, pk []byte) ([]uint64, error) {
+
+	prefix := historyRecordPrefix(pk)
+	sequences := make([]uint64, 0)
+
+	iter := reader.NewIterator(prefix)
+	defer iter.Release()
+
+	for iter.Next() {
+		sequences = append(sequences, SortableBytesToUint64(iter.Key()[len(prefix):]))
+	}
+
+	return sequences, iter.Error()
+}
+
+// GetRecordHistory returns versions of record in order of sequence.
+func (database *Database) GetRecordHistory(pk []byte) (uint64, []*RecordVersion, error) {
+
+	if database.history == nil {
+		return 0, nil, ErrHistoryDisabled
+	}
+
+	snapshot, err := database.db.GetSnapshot()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer snapshot.Release()
+
+	seq, err := getSequence(snapshot)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	prefix := historyRecordPrefix(pk)
+	versions := make([]*RecordVersion, 0)
+
+	iter := snapshot.NewIterator(prefix)
+	defer iter.Release()
+
+	for iter.Next() {
+
+		version, err := database.decodeVersion(iter.Key(), iter.Value())
+		if err != nil {
+			return 0, nil, err
+		}
+
+		versions = append(versions, version)
+	}
+
+	return seq, versions, iter.Error()
+}
+
+// FetchSnapshotAt streams records as they were at sequence. Sequence of
+// entries is sequence of the change which made record that way.
+func (database *Database) FetchSnapshotAt(sequence uint64, stream pb.DataSnapshot_GetSnapshotAtServer) error {
+
+	log.WithFields(log.Fields{
+		"collection": database.name,
+		"at":         sequence,
+	}).Info("Client requests data at sequence")
+
+	writer := database.newPacketWriter(stream, sequence)
+
+	err := database.scanRecordsAt(sequence, func(pk []byte, version *RecordVersion) error {
+		return writer.Write(&pb.SnapshotEntry{
+			Data:     version.Data,
+			Sequence: version.Sequence,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return writer.Flush()
+}
+
+// scanRecordsAt calls fn with primary key and version of every record which
+// existed at sequence.
+func (database *Database) scanRecordsAt(sequence uint64, fn func(pk []byte, version *RecordVersion) error) error {
+
+	if database.history == nil {
+		return ErrHistoryDisabled
+	}
+
+	snapshot, err := database.db.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Release()
+
+	seq, err := getSequence(snapshot)
+	if err != nil {
+		return err
+	}
+
+	horizon := uint64(0)
+	data, err := snapshot.Get(historyHorizonKey)
+	if err == nil {
+		horizon = BytesToUint64(data)
+	} else if err != store.ErrNotFound {
+		return err
+	}
+
+	if sequence < horizon || sequence > seq {
+		return ErrHistoryUnavailable
+	}
+
+	// Versions are ordered by record and then sequence, the last version at
+	// or before sequence is state of record
+	var current []byte
+	var key []byte
+	var value []byte
+
+	emit := func() error {
+
+		if key == nil {
+			return nil
+		}
+
+		version, err := database.decodeVersion(key, value)
+		if err != nil {
+			return err
+		}
+
+		key = nil
+		if version.Deleted {
+			return nil
+		}
+
+		return fn(current, version)
+	}
+
+	iter := snapshot.NewIterator(historyPrefix)
+	defer iter.Release()
+
+	for iter.Next() {
+
+		pk, versionSeq := parseHistoryKey(iter.Key())
+		if pk == nil {
+			continue
+		}
+
+		if !bytes.Equal(pk, current) {
+			err := emit()
+			if err != nil {
+				return err
+			}
+
+			current = cloneBytes(pk)
+		}
+
+		if versionSeq <= sequence {
+			key = cloneBytes(iter.Key())
+			value = cloneBytes(iter.Value())
+		}
+	}
+
+	err = iter.Error()
+	if err != nil {
+		return err
+	}
+
+	return emit()
+}
+
+// CollectHistory removes versions which were replaced before retention, so
+// collection stays available as of any time within retention. Records which
+// were deleted before retention lose their history entirely.
+func (database *Database) CollectHistory(retention time.Duration) (int, error) {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	deadline := time.Now().Add(-retention).UnixNano()
+	batch := database.db.NewBatch()
+	count := 0
+
+	var current []byte
+	keys := make([][]byte, 0)
+	versions := make([]*RecordVersion, 0)
+
+	collect := func() {
+
+		if len(versions) == 0 {
+			return
+		}
+
+		latest := versions[len(versions)-1]
+		n := 0
+		if latest.Deleted && latest.UpdatedAt <= deadline {
+			n = len(versions)
+			database.raiseHistoryHorizon(batch, latest.Sequence)
+		} else {
+			for n < len(versions)-1 && versions[n+1].UpdatedAt <= deadline {
+				n++
+			}
+
+			if n > 0 {
+				database.raiseHistoryHorizon(batch, versions[n].Sequence)
+			}
+		}
+
+		for _, key := range keys[:n] {
+			batch.Delete(key)
+		}
+
+		count += n
+		keys = keys[:0]
+		versions = versions[:0]
+	}
+
+	iter := database.db.NewIterator(historyPrefix)
+	for iter.Next() {
+
+		pk, _ := parseHistoryKey(iter.Key())
+		if pk == nil {
+			continue
+		}
+
+		if !bytes.Equal(pk, current) {
+			collect()
+			current = cloneBytes(pk)
+		}
+
+		version, err := database.decodeVersion(iter.Key(), iter.Value())
+		if err != nil {
+			iter.Release()
+			return 0, err
+		}
+
+		// Data is not needed to decide
+		version.Data = nil
+		keys = append(keys, cloneBytes(iter.Key()))
+		versions = append(versions, version)
+	}
+
+	err := iter.Error()
+	iter.Release()
+	if err != nil {
+		return 0, err
+	}
+
+	collect()
+
+	if count == 0 {
+		return 0, nil
+	}
+
+	return count, database.db.Write(batch)
+}
+
+func (database *Database) runHistoryCollector() {
+
+	interval := getCollectionDuration(database.name, "history_gc_interval", defaultHistoryGCInterval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for database.tick(ticker) {
+
+		count, err := database.CollectHistory(database.history.Retention)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"collection": database.name,
+			}).Error(err)
+			continue
+		}
+
+		if count > 0 {
+			log.WithFields(log.Fields{
+				"collection": database.name,
+				"count":      count,
+			}).Info("Removed expired versions of records")
+		}
+	}
+}
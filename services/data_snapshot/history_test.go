@@ -0,0 +1,185 @@
+package data_snapshot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// historyOf returns versions of record as "seq" with "-" for deleted ones.
+func historyOf(t *testing.T, db *Database, id float64) string {
+
+	pk, err := db.primaryKeyOfValue(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, versions, err := db.GetRecordHistory(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	marks := make([]string, 0, len(versions))
+	for _, version := range versions {
+
+		mark := ""
+		if version.Deleted {
+			mark = "-"
+		}
+
+		marks = append(marks, fmt.Sprintf("%s%d", mark, version.Sequence))
+	}
+
+	return strings.Join(marks, ",")
+}
+
+// snapshotAt returns entries of collection at sequence as "seq:data" in
+// order of sequence.
+func snapshotAt(db *Database, sequence uint64) (string, error) {
+
+	stream := &testStream{}
+	err := db.FetchSnapshotAt(sequence, stream)
+	if err != nil {
+		return "", err
+	}
+
+	entries := make([]string, 0)
+	for _, entry := range stream.entries() {
+		entries = append(entries, fmt.Sprintf("%d:%s", entry.Sequence, entry.Data))
+	}
+
+	sort.Strings(entries)
+
+	return strings.Join(entries, " "), nil
+}
+
+// writeTestHistory writes two records, updates the first one and deletes it.
+func writeTestHistory(t *testing.T, db *Database) {
+
+	writeTestRecord(t, db, 1, 1)
+	writeTestRecord(t, db, 2, 1, Field{Name: "n", Value: float64(1)})
+	writeTestRecord(t, db, 3, 2)
+	deleteTestRecord(t, db, 4, 1)
+}
+
+func TestRecordHistory(t *testing.T) {
+
+	viper.Set("collections.t.history_versions", 10)
+	defer viper.Set("collections.t.history_versions", nil)
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	writeTestHistory(t, db)
+
+	if got := historyOf(t, db, 1); got != "1,2,-4" {
+		t.Errorf("history of record 1 = %s, want 1,2,-4", got)
+	}
+
+	if got := historyOf(t, db, 2); got != "3" {
+		t.Errorf("history of record 2 = %s, want 3", got)
+	}
+
+	tests := []struct {
+		sequence uint64
+		want     string
+	}{
+		{1, `1:{"id":1}`},
+		{2, `2:{"id":1,"n":1}`},
+		{3, `2:{"id":1,"n":1} 3:{"id":2}`},
+		{4, `3:{"id":2}`},
+	}
+
+	for _, test := range tests {
+
+		got, err := snapshotAt(db, test.sequence)
+		if err != nil {
+			t.Fatalf("at %d: %v", test.sequence, err)
+		}
+
+		if got != test.want {
+			t.Errorf("at %d: %s, want %s", test.sequence, got, test.want)
+		}
+	}
+
+	if _, err := snapshotAt(db, 5); err != ErrHistoryUnavailable {
+		t.Errorf("snapshot ahead of sequence = %v, want %v", err, ErrHistoryUnavailable)
+	}
+}
+
+func TestHistoryVersionLimit(t *testing.T) {
+
+	viper.Set("collections.t.history_versions", 2)
+	defer viper.Set("collections.t.history_versions", nil)
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	writeTestHistory(t, db)
+
+	// The oldest version is dropped, so collection is only available from
+	// the sequence of the next one on
+	if got := historyOf(t, db, 1); got != "2,-4" {
+		t.Errorf("history of record 1 = %s, want 2,-4", got)
+	}
+
+	if _, err := snapshotAt(db, 1); err != ErrHistoryUnavailable {
+		t.Errorf("snapshot before horizon = %v, want %v", err, ErrHistoryUnavailable)
+	}
+
+	if _, err := snapshotAt(db, 2); err != nil {
+		t.Errorf("snapshot at horizon = %v", err)
+	}
+}
+
+func TestCollectHistory(t *testing.T) {
+
+	viper.Set("collections.t.history_versions", 10)
+	defer viper.Set("collections.t.history_versions", nil)
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	writeTestHistory(t, db)
+
+	// Replaced versions and deleted records go, the latest versions stay
+	count, err := db.CollectHistory(0)
+	if err != nil || count != 3 {
+		t.Fatalf("CollectHistory = %d, %v, want 3", count, err)
+	}
+
+	if got := historyOf(t, db, 1); got != "" {
+		t.Errorf("history of deleted record = %s, want none", got)
+	}
+
+	if got := historyOf(t, db, 2); got != "3" {
+		t.Errorf("history of record 2 = %s, want 3", got)
+	}
+
+	if _, err := snapshotAt(db, 3); err != ErrHistoryUnavailable {
+		t.Errorf("snapshot before collected versions = %v, want %v", err, ErrHistoryUnavailable)
+	}
+
+	if got, err := snapshotAt(db, 4); err != nil || got != `3:{"id":2}` {
+		t.Errorf("snapshot at current sequence = %s, %v", got, err)
+	}
+}
+
+func TestHistoryDisabled(t *testing.T) {
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	writeTestRecord(t, db, 1, 1)
+
+	if _, _, err := db.GetRecordHistory([]byte("1")); err != ErrHistoryDisabled {
+		t.Errorf("GetRecordHistory = %v, want %v", err, ErrHistoryDisabled)
+	}
+
+	if _, err := snapshotAt(db, 1); err != ErrHistoryDisabled {
+		t.Errorf("FetchSnapshotAt = %v, want %v", err, ErrHistoryDisabled)
+	}
+}
@@ -0,0 +1,533 @@
+package data_snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync/atomic"
+
+	pb "gravity-data-snapshot/pb"
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrIndexNotFound  = errors.New("no such index")
+	ErrIndexNotReady  = errors.New("index is being built")
+	ErrInvalidQuery   = errors.New("invalid index query")
+	ErrIndexEncrypted = errors.New("indexes can't be used along with encryption")
+)
+
+var (
+	indexPrefix      = []byte("idx-")
+	indexStatePrefix = []byte("idxstate-")
+	indexNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+)
+
+const indexBuildChunkSize = 1000
+
+const (
+	indexStateClearing int32 = iota
+	indexStateBuilding
+	indexStateReady
+)
+
+type IndexConfig struct {
+	Name   string   `mapstructure:"name"`
+	Fields []string `mapstructure:"fields"`
+}
+
+// Index is a secondary index on one or more fields of records. Entries are
+// kept as idx-<name>-<encoded values><pk> and maintained in the same batch
+// as the record.
+type Index struct {
+	Name   string
+	Fields []string
+	state  int32
+}
+
+type indexDefinition struct {
+	Fields []string `json:"fields"`
+	Ready  bool     `json:"ready"`
+}
+
+type IndexQuery struct {
+	Index      string
+	Values     []interface{}
+	RangeStart interface{}
+	RangeEnd   interface{}
+	HasStart   bool
+	HasEnd     bool
+	Limit      int
+}
+
+func getIndexConfigs(collection string) ([]IndexConfig, error) {
+
+	key := fmt.Sprintf("collections.%s.indexes", collection)
+	if !viper.IsSet(key) {
+		return nil, nil
+	}
+
+	var configs []IndexConfig
+	err := viper.UnmarshalKey(key, &configs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, config := range configs {
+		if !indexNamePattern.MatchString(config.Name) {
+			return nil, fmt.Errorf("invalid index name \"%s\"", config.Name)
+		}
+
+		if len(config.Fields) == 0 {
+			return nil, fmt.Errorf("index \"%s\" has no fields", config.Name)
+		}
+	}
+
+	return configs, nil
+}
+
+func (index *Index) prefix() []byte {
+	return prefixedKey(indexPrefix, []byte(index.Name), []byte("-"))
+}
+
+func (index *Index) stateKey() []byte {
+	return prefixedKey(indexStatePrefix, []byte(index.Name))
+}
+
+func (index *Index) entryKey(doc map[string]interface{}, pk []byte) []byte {
+
+	key := index.prefix()
+	for _, field := range index.Fields {
+		value, _ := lookupField(doc, field)
+		key = appendIndexValue(key, value)
+	}
+
+	return append(key, pk...)
+}
+
+// primaryKeyOfEntry skips encoded values of index entry and returns the rest.
+func (index *Index) primaryKeyOfEntry(key []byte) ([]byte, error) {
+
+	data := key[len(index.prefix()):]
+	for range index.Fields {
+		n, err := skipIndexValue(data)
+		if err != nil {
+			return nil, err
+		}
+
+		data = data[n:]
+	}
+
+	return data, nil
+}
+
+func (index *Index) getState() int32 {
+	return atomic.LoadInt32(&index.state)
+}
+
+func (index *Index) setState(state int32) {
+	atomic.StoreInt32(&index.state, state)
+}
+
+func (index *Index) IsReady() bool {
+	return index.getState() == indexStateReady
+}
+
+func (database *Database) getIndex(name string) *Index {
+
+	for _, index := range database.indexes {
+		if index.Name == name {
+			return index
+		}
+	}
+
+	return nil
+}
+
+// initIndexes loads index definitions from config and starts building indexes
+// which are new or changed since last time.
+func (database *Database) initIndexes() error {
+
+	configs, err := getIndexConfigs(database.name)
+	if err != nil {
+		return err
+	}
+
+	// Entries keep values of fields in keys, which are never encrypted. Indexes
+	// are left out instead of failing collection, so events are still stored,
+	// and entries of earlier indexes are removed along with stale ones.
+	if len(configs) > 0 && database.getKeyring() != nil {
+		log.WithFields(log.Fields{
+			"collection": database.name,
+		}).Error(ErrIndexEncrypted)
+
+		configs = nil
+	}
+
+	pending := make([]*Index, 0)
+	configured := make(map[string]bool)
+	for _, config := range configs {
+
+		index := &Index{
+			Name:   config.Name,
+			Fields: config.Fields,
+		}
+
+		configured[index.Name] = true
+		database.indexes = append(database.indexes, index)
+
+		data, err := database.db.Get(index.stateKey())
+		if err != nil && err != store.ErrNotFound {
+			return err
+		}
+
+		if err == nil {
+			var def indexDefinition
+			err := json.Unmarshal(data, &def)
+			if err != nil {
+				return err
+			}
+
+			if def.Ready && equalFields(def.Fields, index.Fields) {
+				index.setState(indexStateReady)
+				continue
+			}
+		}
+
+		pending = append(pending, index)
+	}
+
+	// Indexes which were removed from config
+	stale := make([]string, 0)
+	iter := database.db.NewIterator(indexStatePrefix)
+	for iter.Next() {
+		name := string(iter.Key()[len(indexStatePrefix):])
+		if !configured[name] {
+			stale = append(stale, name)
+		}
+	}
+
+	err = iter.Error()
+	iter.Release()
+	if err != nil {
+		return err
+	}
+
+	if len(pending) > 0 || len(stale) > 0 {
+		database.startWorker(func() {
+			database.buildIndexes(pending, stale)
+		})
+	}
+
+	return nil
+}
+
+func (database *Database) buildIndexes(pending []*Index, stale []string) {
+
+	for _, name := range stale {
+
+		index := &Index{
+			Name: name,
+		}
+
+		err := database.deletePrefix(index.prefix())
+		if err == nil {
+			err = database.db.Delete(index.stateKey())
+		}
+
+		if err != nil {
+			log.WithFields(log.Fields{
+				"collection": database.name,
+				"index":      name,
+			}).Error(err)
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"collection": database.name,
+			"index":      name,
+		}).Info("Removed index")
+	}
+
+	for _, index := range pending {
+
+		if database.ctx.Err() != nil {
+			return
+		}
+
+		err := database.buildIndex(index)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"collection": database.name,
+				"index":      index.Name,
+			}).Error(err)
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"collection": database.name,
+			"index":      index.Name,
+		}).Info("Index is ready")
+	}
+}
+
+// buildIndex clears entries left by previous definition, then backfills
+// existing records in chunks. Writers start maintaining the index as soon as
+// it is cleared, so records changed during backfill stay consistent.
+func (database *Database) buildIndex(index *Index) error {
+
+	log.WithFields(log.Fields{
+		"collection": database.name,
+		"index":      index.Name,
+		"fields":     index.Fields,
+	}).Info("Building index")
+
+	err := database.putIndexDefinition(index, false)
+	if err != nil {
+		return err
+	}
+
+	err = database.deletePrefix(index.prefix())
+	if err != nil {
+		return err
+	}
+
+	index.setState(indexStateBuilding)
+
+	var lastKey []byte
+	for {
+		if database.ctx.Err() != nil {
+			return database.ctx.Err()
+		}
+
+		done, next, err := database.backfillIndex(index, lastKey)
+		if err != nil {
+			return err
+		}
+
+		if done {
+			break
+		}
+
+		lastKey = next
+	}
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	err = database.putIndexDefinition(index, true)
+	if err != nil {
+		return err
+	}
+
+	index.setState(indexStateReady)
+
+	return nil
+}
+
+func (database *Database) backfillIndex(index *Index, lastKey []byte) (bool, []byte, error) {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	iter := database.db.NewIterator(recordPrefix)
+	defer iter.Release()
+
+	ok := iter.Next()
+	if lastKey != nil {
+		ok = iter.Seek(lastKey)
+		if ok && bytes.Equal(iter.Key(), lastKey) {
+			ok = iter.Next()
+		}
+	}
+
+	batch := database.db.NewBatch()
+	count := 0
+	for ; ok && count < indexBuildChunkSize; ok = iter.Next() {
+
+		data, err := database.decodeValue(iter.Key(), iter.Value())
+		if err != nil {
+			return false, nil, err
+		}
+
+		doc := make(map[string]interface{})
+		err = json.Unmarshal(data, &doc)
+		if err != nil {
+			return false, nil, err
+		}
+
+		batch.Put(index.entryKey(doc, primaryKeyOf(iter.Key())), []byte{})
+		lastKey = cloneBytes(iter.Key())
+		count++
+	}
+
+	err := iter.Error()
+	if err != nil {
+		return false, nil, err
+	}
+
+	if count > 0 {
+		err = database.db.Write(batch)
+		if err != nil {
+			return false, nil, err
+		}
+	}
+
+	return !ok, lastKey, nil
+}
+
+func (database *Database) putIndexDefinition(index *Index, ready bool) error {
+
+	data, err := json.Marshal(&indexDefinition{
+		Fields: index.Fields,
+		Ready:  ready,
+	})
+	if err != nil {
+		return err
+	}
+
+	return database.db.Put(index.stateKey(), data)
+}
+
+// updateIndexes moves index entries of record from previous document to the
+// next one, either of them is nil when record is created or deleted.
+func (database *Database) updateIndexes(batch store.Batch, pk []byte, prev map[string]interface{}, next map[string]interface{}) {
+
+	for _, index := range database.indexes {
+
+		if index.getState() == indexStateClearing {
+			continue
+		}
+
+		var oldKey, newKey []byte
+		if prev != nil {
+			oldKey = index.entryKey(prev, pk)
+		}
+
+		if next != nil {
+			newKey = index.entryKey(next, pk)
+		}
+
+		if oldKey != nil && newKey != nil && bytes.Equal(oldKey, newKey) {
+			continue
+		}
+
+		if oldKey != nil {
+			batch.Delete(oldKey)
+		}
+
+		if newKey != nil {
+			batch.Put(newKey, []byte{})
+		}
+	}
+}
+
+// FetchIndex streams records whose leading indexed fields equal to values of
+// query, optionally within a range of the next field.
+func (database *Database) FetchIndex(query *IndexQuery, stream pb.DataSnapshot_QueryIndexServer) error {
+
+	index := database.getIndex(query.Index)
+	if index == nil {
+		return ErrIndexNotFound
+	}
+
+	if !index.IsReady() {
+		return ErrIndexNotReady
+	}
+
+	hasRange := query.HasStart || query.HasEnd
+	if len(query.Values) > len(index.Fields) || (hasRange && len(query.Values) >= len(index.Fields)) {
+		return ErrInvalidQuery
+	}
+
+	prefix := index.prefix()
+	for _, value := range query.Values {
+		prefix = appendIndexValue(prefix, value)
+	}
+
+	start := prefix
+	if query.HasStart {
+		start = appendIndexValue(cloneBytes(prefix), query.RangeStart)
+	}
+
+	var end []byte
+	if query.HasEnd {
+		end = appendIndexValue(cloneBytes(prefix), query.RangeEnd)
+	}
+
+	snapshot, err := database.db.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Release()
+
+	seq, err := getSequence(snapshot)
+	if err != nil {
+		return err
+	}
+
+	writer := database.newPacketWriter(stream, seq)
+
+	iter := snapshot.NewIterator(prefix)
+	defer iter.Release()
+
+	count := 0
+	for ok := iter.Seek(start); ok; ok = iter.Next() {
+
+		if end != nil && bytes.Compare(iter.Key(), end) >= 0 {
+			break
+		}
+
+		if query.Limit > 0 && count >= query.Limit {
+			break
+		}
+
+		pk, err := index.primaryKeyOfEntry(iter.Key())
+		if err != nil {
+			return err
+		}
+
+		data, err := database.getValue(snapshot, recordKey(pk))
+		if err == store.ErrNotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		err = writer.Write(&pb.SnapshotEntry{
+			Data: data,
+		})
+		if err != nil {
+			return err
+		}
+
+		count++
+	}
+
+	err = iter.Error()
+	if err != nil {
+		return err
+	}
+
+	return writer.Flush()
+}
+
+func equalFields(a []string, b []string) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,122 @@
+package data_snapshot
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+)
+
+// Values in index keys are encoded with a type tag so that byte order of
+// keys matches order of values: null < false < true < numbers < strings <
+// objects and arrays.
+const (
+	indexTagNull   = 0x01
+	indexTagFalse  = 0x02
+	indexTagTrue   = 0x03
+	indexTagNumber = 0x04
+	indexTagString = 0x05
+	indexTagJSON   = 0x06
+)
+
+var errInvalidIndexKey = errors.New("invalid index key")
+
+func appendIndexValue(buf []byte, value interface{}) []byte {
+
+	switch v := value.(type) {
+	case nil:
+		return append(buf, indexTagNull)
+	case bool:
+		if v {
+			return append(buf, indexTagTrue)
+		}
+		return append(buf, indexTagFalse)
+	case float64:
+		return appendIndexNumber(buf, v)
+	case float32:
+		return appendIndexNumber(buf, float64(v))
+	case int:
+		return appendIndexNumber(buf, float64(v))
+	case int64:
+		return appendIndexNumber(buf, float64(v))
+	case uint64:
+		return appendIndexNumber(buf, float64(v))
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return appendIndexString(append(buf, indexTagString), string(v))
+		}
+		return appendIndexNumber(buf, f)
+	case string:
+		return appendIndexString(append(buf, indexTagString), v)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return append(buf, indexTagNull)
+	}
+
+	return appendIndexString(append(buf, indexTagJSON), string(data))
+}
+
+func appendIndexNumber(buf []byte, f float64) []byte {
+
+	bits := math.Float64bits(f)
+	if f < 0 {
+		bits = ^bits
+	} else {
+		bits |= 1 << 63
+	}
+
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, bits)
+
+	return append(append(buf, indexTagNumber), b...)
+}
+
+// appendIndexString escapes zero bytes so the terminator keeps the order.
+func appendIndexString(buf []byte, s string) []byte {
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0x00 {
+			buf = append(buf, 0x00, 0xff)
+			continue
+		}
+
+		buf = append(buf, s[i])
+	}
+
+	return append(buf, 0x00, 0x01)
+}
+
+// skipIndexValue returns length of the encoded value at beginning of data.
+func skipIndexValue(data []byte) (int, error) {
+
+	if len(data) == 0 {
+		return 0, errInvalidIndexKey
+	}
+
+	switch data[0] {
+	case indexTagNull, indexTagFalse, indexTagTrue:
+		return 1, nil
+	case indexTagNumber:
+		if len(data) < 9 {
+			return 0, errInvalidIndexKey
+		}
+		return 9, nil
+	case indexTagString, indexTagJSON:
+		for i := 1; i < len(data)-1; i++ {
+			if data[i] != 0x00 {
+				continue
+			}
+
+			if data[i+1] == 0x01 {
+				return i + 2, nil
+			}
+
+			i++
+		}
+	}
+
+	return 0, errInvalidIndexKey
+}
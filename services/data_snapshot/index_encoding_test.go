@@ -0,0 +1,105 @@
+package data_snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestIndexValueOrder(t *testing.T) {
+
+	// Values in ascending order
+	values := []interface{}{
+		nil,
+		false,
+		true,
+		math.Inf(-1),
+		-1e10,
+		-2.5,
+		int64(-1),
+		0.0,
+		json.Number("0.5"),
+		1,
+		uint64(2),
+		float32(2.5),
+		1e10,
+		math.Inf(1),
+		"",
+		"a",
+		"a\x00",
+		"a\x00b",
+		"a\x01",
+		"ab",
+		"b",
+		[]interface{}{1, 2},
+		map[string]interface{}{"a": 1},
+	}
+
+	for i := 1; i < len(values); i++ {
+
+		a := appendIndexValue(nil, values[i-1])
+		b := appendIndexValue(nil, values[i])
+		if bytes.Compare(a, b) >= 0 {
+			t.Errorf("%#v isn't encoded before %#v: %x >= %x", values[i-1], values[i], a, b)
+		}
+	}
+}
+
+func TestIndexValueEquality(t *testing.T) {
+
+	tests := []struct {
+		a interface{}
+		b interface{}
+	}{
+		{1, 1.0},
+		{int64(3), uint64(3)},
+		{json.Number("2"), 2.0},
+		{json.Number("x"), "x"},
+		{float32(0.5), 0.5},
+	}
+
+	for _, test := range tests {
+		if !bytes.Equal(appendIndexValue(nil, test.a), appendIndexValue(nil, test.b)) {
+			t.Errorf("%#v and %#v are encoded differently", test.a, test.b)
+		}
+	}
+}
+
+func TestSkipIndexValue(t *testing.T) {
+
+	values := []interface{}{
+		nil,
+		true,
+		-2.5,
+		"",
+		"a\x00b\x00",
+		map[string]interface{}{"a": "\x00"},
+	}
+
+	for _, value := range values {
+
+		encoded := appendIndexValue(nil, value)
+
+		// Values are followed by other fields of key
+		n, err := skipIndexValue(append(append([]byte{}, encoded...), indexTagTrue))
+		if err != nil || n != len(encoded) {
+			t.Errorf("skipIndexValue of %#v = %d, %v, want %d", value, n, err, len(encoded))
+		}
+	}
+
+	invalid := [][]byte{
+		nil,
+		{0x7f},
+		{indexTagNumber, 1, 2},
+		{indexTagString, 'a'},
+		{indexTagString, 'a', 0x00},
+		{indexTagJSON, 0x00, 0xff},
+	}
+
+	for _, data := range invalid {
+		if _, err := skipIndexValue(data); err != errInvalidIndexKey {
+			t.Errorf("skipIndexValue(%x) = %v, want %v", data, err, errInvalidIndexKey)
+		}
+	}
+}
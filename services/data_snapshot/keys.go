@@ -0,0 +1,112 @@
+package data_snapshot
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Keyspace of a collection database, in shared layout every key is prefixed
+// with "<dir>/" of collection in catalog:
+//
+//	seq                     sequence of the last applied event
+//	key-<pk>                record data
+//	rev-<pk>                sequence of the last change of record
+//	chg-<seq>-<pk>          change log ordered by sequence
+//	tomb-<pk>               tombstone of deleted record
+//	delta-horizon           changes before this sequence are no longer tracked
+//	stats                   record count, total bytes and last updated time
+//	schema                  fields inferred from events
+//	mkl-<level><index>      node of hash tree of records
+//	idx-<name>-<values><pk> entry of secondary index
+//	idxstate-<name>         definition and state of secondary index
+//	exp-<time>-<pk>         expiration queue ordered by time
+//	ttl-<pk>                expiration time and primary key data of record
+//	dek-<version>           data key wrapped by master key
+//	dekstate                version of active data key
+//	hashkey                 key for hashing primary keys, wrapped by master key
+//	zdict-<id>              zstd dictionary for compression of records
+//	frozen                  ingestion of collection is paused
+//	pend-<seq>              event queued while collection is frozen
+//	rebuilding              collection is being rebuilt from event store
+//	hist-<len><pk><seq>     version of record after change at sequence
+//	history-horizon         collection is not available as of earlier sequences
+var (
+	sequenceKey       = []byte("seq")
+	recordPrefix      = []byte("key-")
+	revisionPrefix    = []byte("rev-")
+	changePrefix      = []byte("chg-")
+	tombstonePrefix   = []byte("tomb-")
+	deltaHorizonKey   = []byte("delta-horizon")
+	statsKey          = []byte("stats")
+	expirationPrefix  = []byte("exp-")
+	ttlPrefix         = []byte("ttl-")
+	historyPrefix     = []byte("hist-")
+	historyHorizonKey = []byte("history-horizon")
+)
+
+func prefixedKey(prefix []byte, parts ...[]byte) []byte {
+
+	key := make([]byte, 0, len(prefix)+32)
+	key = append(key, prefix...)
+	for _, part := range parts {
+		key = append(key, part...)
+	}
+
+	return key
+}
+
+func Uint64ToSortableBytes(n uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, n)
+
+	return b
+}
+
+func SortableBytesToUint64(data []byte) uint64 {
+	return binary.BigEndian.Uint64(data)
+}
+
+// primaryKeyOf strips record prefix from key of record.
+func primaryKeyOf(key []byte) []byte {
+	return bytes.TrimPrefix(key, recordPrefix)
+}
+
+func recordKey(pk []byte) []byte {
+	return prefixedKey(recordPrefix, pk)
+}
+
+func revisionKey(pk []byte) []byte {
+	return prefixedKey(revisionPrefix, pk)
+}
+
+func tombstoneKey(pk []byte) []byte {
+	return prefixedKey(tombstonePrefix, pk)
+}
+
+func ttlKey(pk []byte) []byte {
+	return prefixedKey(ttlPrefix, pk)
+}
+
+func changeKey(sequence uint64, pk []byte) []byte {
+	return orderedKey(changePrefix, sequence, pk)
+}
+
+func expirationKey(expiry uint64, pk []byte) []byte {
+	return orderedKey(expirationPrefix, expiry, pk)
+}
+
+// orderedKey makes key of queue which is ordered by number.
+func orderedKey(prefix []byte, n uint64, pk []byte) []byte {
+	return prefixedKey(prefix, Uint64ToSortableBytes(n), []byte("-"), pk)
+}
+
+// parseOrderedKey returns number and primary key of queue entry.
+func parseOrderedKey(prefix []byte, key []byte) (uint64, []byte) {
+
+	data := key[len(prefix):]
+	if len(data) < 9 {
+		return 0, nil
+	}
+
+	return SortableBytesToUint64(data[:8]), data[9:]
+}
@@ -0,0 +1,306 @@
+package data_snapshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Layouts of collections under database path. Directory layout keeps every
+// collection in a store of its own. Shared layout keeps all collections in
+// one store of database.engine under prefixes which come from their
+// directory names in catalog, so settings of engine for specific
+// collections don't apply. Engine of collection is recorded in catalog
+// either way, and changes only by migration of layout.
+const (
+	layoutDirectory = "directory"
+	layoutShared    = "shared"
+
+	sharedStoreDir = "shared"
+)
+
+func getLayout() (string, error) {
+
+	layout := viper.GetString("database.layout")
+	switch layout {
+	case "":
+		return layoutDirectory, nil
+	case layoutDirectory, layoutShared:
+		return layout, nil
+	}
+
+	return "", fmt.Errorf("unknown layout \"%s\"", layout)
+}
+
+func isSharedLayout() bool {
+	return viper.GetString("database.layout") == layoutShared
+}
+
+// Directory names in catalog never contain slash, so no prefix is a prefix
+// of another one.
+func collectionPrefix(entry *CatalogEntry) []byte {
+	return []byte(entry.Dir + "/")
+}
+
+func (catalog *Catalog) sharedPath() string {
+	return filepath.Join(catalog.dbpath, sharedStoreDir)
+}
+
+// openStore opens store of collection in layout with engine of catalog.
+func (catalog *Catalog) openStore(entry *CatalogEntry, layout string) (store.Store, error) {
+
+	if layout == layoutShared {
+		return store.OpenShared(entry.Engine, catalog.sharedPath(), collectionPrefix(entry))
+	}
+
+	return store.Open(entry.Engine, catalog.Path(entry))
+}
+
+// checkSharedEngine makes sure that all collections of shared layout are in
+// the store of database.engine. Engines which are set for specific
+// collections are ignored.
+func checkSharedEngine(catalog *Catalog) error {
+
+	for name := range viper.GetStringMap("collections") {
+		if viper.IsSet(fmt.Sprintf("collections.%s.engine", name)) {
+			log.WithFields(log.Fields{
+				"collection": name,
+			}).Warn("Engine of collection doesn't apply to shared layout")
+		}
+	}
+
+	engine := layoutEngine("", layoutShared)
+	for _, name := range catalog.Names() {
+		entry, _ := catalog.Lookup(name)
+		if entry.Engine != engine {
+			return fmt.Errorf("collection \"%s\" is in %s store but database.engine is %s", name, entry.Engine, engine)
+		}
+	}
+
+	return nil
+}
+
+// removeData removes data of collection in layout.
+func (catalog *Catalog) removeData(entry *CatalogEntry, layout string) error {
+
+	if layout != layoutShared {
+		return os.RemoveAll(catalog.Path(entry))
+	}
+
+	db, err := catalog.openStore(entry, layout)
+	if err != nil {
+		return err
+	}
+
+	err = clearStore(db)
+	closeErr := db.Close()
+	if err != nil {
+		return err
+	}
+
+	return closeErr
+}
+
+// openCollection opens database of collection in layout of config.
+func openCollection(catalog *Catalog, entry *CatalogEntry) (*Database, error) {
+
+	layout, err := getLayout()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := catalog.openStore(entry, layout)
+	if err != nil {
+		return nil, err
+	}
+
+	return initDatabase(entry.Name, entry.Engine, db)
+}
+
+// recoverCollection opens database of collection whose storage was found
+// corrupted. Shared store can't be recovered for one collection.
+func recoverCollection(catalog *Catalog, entry *CatalogEntry) (*Database, error) {
+
+	if isSharedLayout() {
+		return nil, store.ErrRecoveryUnsupported
+	}
+
+	return RecoverDatabase(entry.Name, entry.Engine, catalog.Path(entry))
+}
+
+func clearStore(db store.Store) error {
+
+	batch := db.NewBatch()
+	iter := db.NewIterator(nil)
+	for iter.Next() {
+
+		batch.Delete(cloneBytes(iter.Key()))
+		if batch.Len() >= deleteChunkSize {
+			err := db.Write(batch)
+			if err != nil {
+				iter.Release()
+				return err
+			}
+
+			batch = db.NewBatch()
+		}
+	}
+
+	err := iter.Error()
+	iter.Release()
+	if err != nil {
+		return err
+	}
+
+	return db.Write(batch)
+}
+
+// copyStore copies all pairs of store to another one, which is expected to
+// be empty.
+func copyStore(src store.Store, dst store.Store) error {
+
+	snapshot, err := src.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Release()
+
+	iter := snapshot.NewIterator(nil)
+	defer iter.Release()
+
+	batch := dst.NewBatch()
+	for iter.Next() {
+
+		batch.Put(cloneBytes(iter.Key()), cloneBytes(iter.Value()))
+		if batch.Len() >= deleteChunkSize {
+			err := dst.Write(batch)
+			if err != nil {
+				return err
+			}
+
+			batch = dst.NewBatch()
+		}
+	}
+
+	err = iter.Error()
+	if err != nil {
+		return err
+	}
+
+	return dst.Write(batch)
+}
+
+// MigrateLayout moves all collections in catalog from layout of config to
+// another layout. Collections of memory engine have nothing to move. Service
+// must not be running meanwhile, and database.layout has to be changed
+// afterward.
+func MigrateLayout(layout string) error {
+
+	configureStores()
+
+	from, err := getLayout()
+	if err != nil {
+		return err
+	}
+
+	if layout != layoutDirectory && layout != layoutShared {
+		return fmt.Errorf("unknown layout \"%s\"", layout)
+	}
+
+	if layout == from {
+		return fmt.Errorf("collections are in %s layout already", layout)
+	}
+
+	catalog, err := OpenCatalog(viper.GetString("database.dbpath"))
+	if err != nil {
+		return err
+	}
+
+	for _, name := range catalog.Names() {
+
+		entry, _ := catalog.Lookup(name)
+		engine := layoutEngine(name, layout)
+		if entry.Engine == "memory" {
+			err := catalog.SetEngine(name, engine)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if entry.Failure != nil {
+			return fmt.Errorf("collection \"%s\" failed, rebuild or drop it first", name)
+		}
+
+		seq, err := migrateCollection(catalog, entry, from, layout, engine)
+		if err != nil {
+			return fmt.Errorf("failed to migrate collection \"%s\": %v", name, err)
+		}
+
+		log.WithFields(log.Fields{
+			"collection": name,
+			"seq":        seq,
+			"layout":     layout,
+		}).Info("Migrated collection")
+	}
+
+	if layout == layoutDirectory {
+		return os.RemoveAll(catalog.sharedPath())
+	}
+
+	return nil
+}
+
+// migrateCollection copies collection into store of engine in layout, and
+// removes it from the old one once the copy is closed and catalog tells the
+// new engine.
+func migrateCollection(catalog *Catalog, entry *CatalogEntry, from string, to string, engine string) (uint64, error) {
+
+	target := *entry
+	target.Engine = engine
+
+	src, err := catalog.openStore(entry, from)
+	if err != nil {
+		return 0, err
+	}
+
+	dst, err := catalog.openStore(&target, to)
+	if err != nil {
+		src.Close()
+		return 0, err
+	}
+
+	// Copy of migration which was cut short is replaced
+	seq, err := getSequence(src)
+	if err == nil {
+		err = clearStore(dst)
+	}
+
+	if err == nil {
+		err = copyStore(src, dst)
+	}
+
+	closeErr := dst.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	src.Close()
+
+	if err == nil {
+		err = catalog.SetEngine(entry.Name, engine)
+	}
+
+	if err != nil {
+		catalog.removeData(&target, to)
+		return 0, err
+	}
+
+	return seq, catalog.removeData(entry, from)
+}
@@ -0,0 +1,138 @@
+package data_snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestMigrateLayout(t *testing.T) {
+
+	defer func() {
+		viper.Set("database.dbpath", "")
+		viper.Set("database.layout", "")
+		viper.Set("database.engine", "")
+	}()
+
+	tests := []struct {
+		from       string
+		fromEngine string
+		to         string
+		toEngine   string
+	}{
+		{layoutDirectory, "leveldb", layoutShared, "leveldb"},
+		{layoutDirectory, "bbolt", layoutShared, "pebble"},
+		{layoutShared, "pebble", layoutDirectory, "leveldb"},
+	}
+
+	for _, test := range tests {
+
+		dir, err := ioutil.TempDir("", "data-snapshot-test-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		viper.Set("database.dbpath", dir)
+		viper.Set("database.layout", test.from)
+		viper.Set("database.engine", test.fromEngine)
+
+		catalog, err := OpenCatalog(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Collections of shared layout are written next to each other
+		names := []string{"a", "b"}
+		for i, name := range names {
+
+			entry, err := catalog.Register(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			db, err := openCollection(catalog, entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for j := 0; j <= i*10; j++ {
+				writeTestRecord(t, db, uint64(j+1), float64(j))
+			}
+
+			db.Close()
+		}
+
+		err = MigrateLayout(test.from)
+		if err == nil {
+			t.Errorf("migration from %s to %s succeeded", test.from, test.from)
+		}
+
+		viper.Set("database.engine", test.toEngine)
+		err = MigrateLayout(test.to)
+		if err != nil {
+			t.Fatalf("migration from %s to %s: %v", test.from, test.to, err)
+		}
+
+		viper.Set("database.layout", test.to)
+		catalog, err = OpenCatalog(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, name := range names {
+
+			entry, ok := catalog.Lookup(name)
+			if !ok || entry.Engine != test.toEngine {
+				t.Fatalf("%s to %s: catalog entry of %s is %+v", test.from, test.to, name, entry)
+			}
+
+			// Data is only in the new layout
+			_, err := os.Stat(catalog.Path(entry))
+			if (test.to == layoutDirectory) != (err == nil) {
+				t.Errorf("%s to %s: directory of %s exists is %v", test.from, test.to, name, err == nil)
+			}
+
+			db, err := openCollection(catalog, entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			seq, err := db.GetSequence()
+			stream := &testStream{}
+			if err == nil {
+				err = db.FetchSnapshot(stream, &SnapshotOptions{})
+			}
+			db.Close()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if seq != uint64(i*10+1) || len(stream.entries()) != i*10+1 {
+				t.Errorf("%s to %s: %s has %d records at %d, want %d", test.from, test.to, name, len(stream.entries()), seq, i*10+1)
+			}
+		}
+
+		_, err = os.Stat(catalog.sharedPath())
+		if (test.to == layoutShared) != (err == nil) {
+			t.Errorf("%s to %s: shared store exists is %v", test.from, test.to, err == nil)
+		}
+
+		if test.to != layoutShared {
+			continue
+		}
+
+		// Shared store is only opened with engine it was migrated to
+		if err := checkSharedEngine(catalog); err != nil {
+			t.Errorf("%s to %s: %v", test.from, test.to, err)
+		}
+
+		viper.Set("database.engine", "memory")
+		if err := checkSharedEngine(catalog); err == nil {
+			t.Errorf("%s to %s: shared store of %s is accepted as memory", test.from, test.to, test.toEngine)
+		}
+	}
+}
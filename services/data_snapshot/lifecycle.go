@@ -0,0 +1,427 @@
+package data_snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrShutdownTimeout = errors.New("in-flight requests of collection didn't stop")
+
+var (
+	frozenKey     = []byte("frozen")
+	pendingPrefix = []byte("pend-")
+)
+
+const (
+	defaultCloseTimeout = 10 * time.Second
+	pendingChunkSize    = 1000
+)
+
+// Keys which survive truncation, everything else belongs to records.
+var truncateKeepKeys = [][]byte{
+	sequenceKey,
+	frozenKey,
+	rebuildingKey,
+	schemaKey,
+	pendingPrefix,
+	rejectedPrefix,
+	dataKeyPrefix,
+	dataKeyStateKey,
+	hashKeyKey,
+	dictionaryPrefix,
+}
+
+func pendingKey(sequence uint64) []byte {
+	return prefixedKey(pendingPrefix, Uint64ToSortableBytes(sequence))
+}
+
+func (database *Database) startWorker(fn func()) {
+
+	database.workers.Add(1)
+	go func() {
+		defer database.workers.Done()
+		fn()
+	}()
+}
+
+// tick waits for next tick of ticker, it returns false once database is
+// being closed.
+func (database *Database) tick(ticker *time.Ticker) bool {
+
+	select {
+	case <-database.ctx.Done():
+		return false
+	case <-ticker.C:
+		return true
+	}
+}
+
+func (database *Database) acquire() {
+	database.refs.Add(1)
+	atomic.AddInt32(&database.active, 1)
+	atomic.StoreInt64(&database.lastUsed, time.Now().UnixNano())
+}
+
+// Release gives back database which was obtained from DatabaseManager.
+func (database *Database) Release() {
+	atomic.StoreInt64(&database.lastUsed, time.Now().UnixNano())
+	atomic.AddInt32(&database.active, -1)
+	database.refs.Done()
+}
+
+// isIdle tells whether database can be closed without anyone noticing.
+// Collections of memory engine would lose their data, and rebuild would have
+// to start over.
+func (database *Database) isIdle() bool {
+	return atomic.LoadInt32(&database.active) == 0 && database.engine != "memory" && !database.isRebuilding()
+}
+
+// shutdown waits for in-flight requests to finish, requests which take longer
+// than close_timeout are aborted. Holders of references which outlive
+// requests, like checkpoints, give them back once draining is done. Background
+// workers are stopped afterward. Requests which are still running another
+// close_timeout after being aborted are left behind with ErrShutdownTimeout,
+// closeLater finishes shutdown then.
+func (database *Database) shutdown() error {
+
+	database.drain()
+
+	drained := make(chan struct{})
+	go func() {
+		database.refs.Wait()
+		close(drained)
+	}()
+
+	timeout := getCollectionDuration(database.name, "close_timeout", defaultCloseTimeout)
+	select {
+	case <-drained:
+	case <-time.After(timeout):
+		log.WithFields(log.Fields{
+			"collection": database.name,
+		}).Warn("Aborting in-flight requests of collection")
+	}
+
+	database.cancel()
+
+	select {
+	case <-drained:
+	case <-time.After(timeout):
+		return ErrShutdownTimeout
+	}
+
+	database.stopWorkers()
+
+	return nil
+}
+
+func (database *Database) stopWorkers() {
+
+	database.workers.Wait()
+
+	if c := database.getCompressor(); c != nil {
+		c.close()
+	}
+}
+
+// Close shuts database down and closes its storage. Storage is left open
+// along with ErrShutdownTimeout, since requests still read from it.
+func (database *Database) Close() error {
+
+	err := database.shutdown()
+	if err != nil {
+		return err
+	}
+
+	return database.db.Close()
+}
+
+// closeLater closes storage once requests which were left behind by shutdown
+// return.
+func (database *Database) closeLater() error {
+
+	database.refs.Wait()
+	database.stopWorkers()
+
+	return database.db.Close()
+}
+
+func (database *Database) initFrozenState() error {
+
+	_, err := database.db.Get(frozenKey)
+	if err == nil {
+		database.frozen = true
+		return nil
+	} else if err != store.ErrNotFound {
+		return err
+	}
+
+	return nil
+}
+
+// States of collections which are reported to clients.
+const (
+	stateOK         = "ok"
+	stateFrozen     = "frozen"
+	stateRebuilding = "rebuilding"
+	stateFailed     = "failed"
+	stateBusy       = "busy"
+	stateClosed     = "closed"
+	stateError      = "error"
+)
+
+// State tells whether collection ingests events.
+func (database *Database) State() string {
+
+	if database.isRebuilding() {
+		return stateRebuilding
+	} else if database.IsFrozen() {
+		return stateFrozen
+	}
+
+	return stateOK
+}
+
+func (database *Database) IsFrozen() bool {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	return database.frozen
+}
+
+// Freeze pauses ingestion of collection, events are queued until collection
+// is unfrozen while reads are still served.
+func (database *Database) Freeze() error {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	return database.freeze()
+}
+
+func (database *Database) freeze() error {
+
+	if database.frozen {
+		return nil
+	}
+
+	err := database.db.Put(frozenKey, []byte{1})
+	if err != nil {
+		return err
+	}
+
+	database.frozen = true
+
+	log.WithFields(log.Fields{
+		"collection": database.name,
+	}).Info("Froze collection")
+
+	return nil
+}
+
+// queueEvent keeps event of frozen collection in order of sequence. Events
+// carry record data, so they are encrypted like records.
+func (database *Database) queueEvent(sequence uint64, projection *Projection) error {
+
+	data, err := json.Marshal(projection)
+	if err != nil {
+		return err
+	}
+
+	value, err := database.encryptValue(pendingKey(sequence), data)
+	if err != nil {
+		return err
+	}
+
+	return database.db.Put(pendingKey(sequence), value)
+}
+
+// Unfreeze applies queued events in chunks and resumes ingestion once the
+// queue is empty. New events keep being queued until then. Collection stays
+// frozen if queued event breaks quota which stops ingestion.
+func (database *Database) Unfreeze() error {
+
+	defer database.notifyRejected()
+
+	for {
+		done, err := database.drainPending(pendingChunkSize)
+		if err != nil {
+			return err
+		}
+
+		if done {
+			log.WithFields(log.Fields{
+				"collection": database.name,
+			}).Info("Unfroze collection")
+
+			return nil
+		}
+	}
+}
+
+func (database *Database) drainPending(limit int) (bool, error) {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	if !database.frozen {
+		return true, nil
+	}
+
+	batch := database.db.NewBatch()
+	count := 0
+
+	iter := database.db.NewIterator(pendingPrefix)
+	for count < limit && iter.Next() {
+
+		key := cloneBytes(iter.Key())
+		sequence := SortableBytesToUint64(key[len(pendingPrefix):])
+
+		data, err := database.decodeValue(key, iter.Value())
+		if err != nil {
+			iter.Release()
+			return false, err
+		}
+
+		var projection Projection
+		err = json.Unmarshal(data, &projection)
+		if err == nil {
+			err = database.applyEvent(sequence, &projection)
+		}
+
+		// Event stays at the head of queue
+		if err == ErrQuotaExceeded {
+			iter.Release()
+			if batch.Len() > 0 {
+				writeErr := database.db.Write(batch)
+				if writeErr != nil {
+					return false, writeErr
+				}
+			}
+
+			return false, err
+		}
+
+		// Same as ingestion, broken events are not retried
+		if err != nil {
+			log.WithFields(log.Fields{
+				"collection": database.name,
+				"seq":        sequence,
+			}).Error(err)
+		}
+
+		batch.Delete(key)
+		count++
+	}
+
+	err := iter.Error()
+	iter.Release()
+	if err != nil {
+		return false, err
+	}
+
+	// Events which are applied already get skipped if deletion is lost
+	done := count < limit
+	if done {
+		batch.Delete(frozenKey)
+	}
+
+	if batch.Len() > 0 {
+		err = database.db.Write(batch)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if done {
+		database.frozen = false
+	}
+
+	return done, nil
+}
+
+// PendingCount returns number of events queued while collection is frozen.
+func (database *Database) PendingCount() (uint64, error) {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	count := uint64(0)
+	iter := database.db.NewIterator(pendingPrefix)
+	for iter.Next() {
+		count++
+	}
+
+	err := iter.Error()
+	iter.Release()
+
+	return count, err
+}
+
+// truncate removes records along with everything derived from them on
+// database which was shut down. Change log is gone as well, so delta horizon
+// moves past current sequence and clients have to fetch full snapshot.
+func (database *Database) truncate() (uint64, error) {
+
+	// Nothing writes to database which was shut down, and iterator of store
+	// doesn't keep bbolt from growing file for writes below
+	seq, err := getSequence(database.db)
+	if err != nil {
+		return 0, err
+	}
+
+	batch := database.db.NewBatch()
+	iter := database.db.NewIterator(nil)
+	for iter.Next() {
+
+		if keepOnTruncate(iter.Key()) {
+			continue
+		}
+
+		batch.Delete(cloneBytes(iter.Key()))
+		if batch.Len() >= deleteChunkSize {
+			err := database.db.Write(batch)
+			if err != nil {
+				iter.Release()
+				return 0, err
+			}
+
+			batch = database.db.NewBatch()
+		}
+	}
+
+	err = iter.Error()
+	iter.Release()
+	if err != nil {
+		return 0, err
+	}
+
+	batch.Put(deltaHorizonKey, Uint64ToBytes(seq+1))
+
+	err = database.db.Write(batch)
+	if err != nil {
+		return 0, err
+	}
+
+	updateStatsMetrics(database.name, seq, &CollectionStats{})
+
+	return seq, nil
+}
+
+func keepOnTruncate(key []byte) bool {
+
+	for _, keep := range truncateKeepKeys {
+		if bytes.HasPrefix(key, keep) {
+			return true
+		}
+	}
+
+	return false
+}
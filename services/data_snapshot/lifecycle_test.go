@@ -0,0 +1,175 @@
+package data_snapshot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	pb "gravity-data-snapshot/pb"
+
+	"github.com/spf13/viper"
+)
+
+// blockedStream never sends, like stream of a client which stopped reading.
+type blockedStream struct {
+	pb.DataSnapshot_GetSnapshotServer
+}
+
+func (stream *blockedStream) Send(packet *pb.SnapshotPacket) error {
+	select {}
+}
+
+func TestShutdownAbortsBlockedStream(t *testing.T) {
+
+	viper.Set("collections.t.close_timeout", "50ms")
+	defer viper.Set("collections.t.close_timeout", "")
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	for i := 0; i < packetSize; i++ {
+		writeTestRecord(t, db, uint64(i+1), float64(i))
+	}
+
+	fetched := make(chan error, 1)
+	db.acquire()
+	go func() {
+		defer db.Release()
+		fetched <- db.FetchSnapshot(&blockedStream{}, &SnapshotOptions{})
+	}()
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- db.Close()
+	}()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Errorf("Close = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close waits for blocked stream")
+	}
+
+	if err := <-fetched; err != ErrCollectionClosed {
+		t.Errorf("FetchSnapshot = %v, want %v", err, ErrCollectionClosed)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+
+	viper.Set("collections.t.close_timeout", "20ms")
+	defer viper.Set("collections.t.close_timeout", "")
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	// Request which ignores cancellation leaves storage open
+	db.acquire()
+	if err := db.Close(); err != ErrShutdownTimeout {
+		t.Fatalf("Close = %v, want %v", err, ErrShutdownTimeout)
+	}
+
+	if _, err := db.GetSequence(); err != nil {
+		t.Errorf("GetSequence of request which was left behind = %v", err)
+	}
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- db.closeLater()
+	}()
+
+	select {
+	case <-closed:
+		t.Fatal("storage is closed before request returns")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	db.Release()
+	if err := <-closed; err != nil {
+		t.Errorf("closeLater = %v", err)
+	}
+}
+
+func TestFreezeQueuesEvents(t *testing.T) {
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	writeTestRecord(t, db, 1, 1)
+
+	if err := db.Freeze(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Events of frozen collection wait in order
+	writeTestRecord(t, db, 2, 2)
+	writeTestRecord(t, db, 3, 1, Field{Name: "n", Value: float64(1)})
+
+	checkRecords(t, db, 1, 1)
+	if pending, err := db.PendingCount(); err != nil || pending != 2 || db.State() != stateFrozen {
+		t.Fatalf("collection is %s with %d pending events, %v", db.State(), pending, err)
+	}
+
+	if err := db.Unfreeze(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkRecords(t, db, 3, 2)
+	if pending, err := db.PendingCount(); err != nil || pending != 0 || db.State() != stateOK {
+		t.Errorf("collection is %s with %d pending events after Unfreeze, %v", db.State(), pending, err)
+	}
+
+	pk, err := db.primaryKeyOfValue(float64(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, records, err := db.GetRecords([][]byte{pk})
+	if err != nil || !strings.Contains(string(records[0]), `"n":1`) {
+		t.Errorf("queued update isn't applied: %s, %v", records[0], err)
+	}
+}
+
+func TestTruncateCollection(t *testing.T) {
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	openTestCollection(t, dm, "a", 3)
+
+	db := dm.GetExistingDatabase("a")
+	if err := db.Freeze(); err != nil {
+		t.Fatal(err)
+	}
+	writeTestRecord(t, db, 4, 4)
+	db.Release()
+
+	seq, err := dm.TruncateCollection("a")
+	if err != nil || seq != 3 {
+		t.Fatalf("TruncateCollection = %d, %v, want 3", seq, err)
+	}
+
+	// Sequence and queued events are kept, records and changes are not
+	db = dm.GetExistingDatabase("a")
+	if db == nil {
+		t.Fatal("truncated collection is gone")
+	}
+	defer db.Release()
+
+	checkRecords(t, db, 3, 0)
+	if pending, err := db.PendingCount(); err != nil || pending != 1 || db.State() != stateFrozen {
+		t.Errorf("truncated collection is %s with %d pending events, %v", db.State(), pending, err)
+	}
+
+	if err := db.FetchDelta(1, &testStream{}); err != ErrDeltaUnavailable {
+		t.Errorf("FetchDelta before truncation = %v, want %v", err, ErrDeltaUnavailable)
+	}
+
+	if err := db.Unfreeze(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkRecords(t, db, 4, 1)
+}
@@ -0,0 +1,41 @@
+package data_snapshot
+
+import "gravity-data-snapshot/services/data_snapshot/store"
+
+// GetRecords reads records of primary keys at the current sequence. Data is
+// nil for keys which have no record.
+func (database *Database) GetRecords(pks [][]byte) (uint64, [][]byte, error) {
+
+	snapshot, err := database.db.GetSnapshot()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer snapshot.Release()
+
+	return database.GetRecordsFrom(snapshot, pks)
+}
+
+// GetRecordsFrom is like GetRecords, with snapshot which was taken earlier,
+// e.g. by checkpoint.
+func (database *Database) GetRecordsFrom(snapshot store.Snapshot, pks [][]byte) (uint64, [][]byte, error) {
+
+	seq, err := getSequence(snapshot)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	records := make([][]byte, len(pks))
+	for i, pk := range pks {
+
+		data, err := database.getValue(snapshot, recordKey(pk))
+		if err == store.ErrNotFound {
+			continue
+		} else if err != nil {
+			return 0, nil, err
+		}
+
+		records[i] = data
+	}
+
+	return seq, records, nil
+}
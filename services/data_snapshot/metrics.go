@@ -0,0 +1,134 @@
+package data_snapshot
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	recordCountGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gravity_data_snapshot",
+		Name:      "records",
+		Help:      "Number of records in collection.",
+	}, []string{"collection"})
+
+	totalBytesGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gravity_data_snapshot",
+		Name:      "record_bytes",
+		Help:      "Total size of record data in collection.",
+	}, []string{"collection"})
+
+	lastUpdatedGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gravity_data_snapshot",
+		Name:      "last_updated_timestamp_seconds",
+		Help:      "Time of the last change of records in collection.",
+	}, []string{"collection"})
+
+	sequenceGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gravity_data_snapshot",
+		Name:      "sequence",
+		Help:      "Sequence of the last event applied to collection.",
+	}, []string{"collection"})
+
+	openCollectionsGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "gravity_data_snapshot",
+		Name:      "open_collections",
+		Help:      "Number of collections whose database is open.",
+	})
+
+	collectionOpensCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "gravity_data_snapshot",
+		Name:      "collection_opens_total",
+		Help:      "Number of times databases of collections were opened.",
+	})
+
+	collectionEvictionsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "gravity_data_snapshot",
+		Name:      "collection_evictions_total",
+		Help:      "Number of idle collections closed to stay within max_open.",
+	})
+
+	collectionRecoveriesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gravity_data_snapshot",
+		Name:      "collection_recoveries_total",
+		Help:      "Number of corrupted collections by result, recovered, quarantined or rebuilt.",
+	}, []string{"result"})
+
+	compactionsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gravity_data_snapshot",
+		Name:      "compactions_total",
+		Help:      "Number of compactions of collection.",
+	}, []string{"collection"})
+
+	storageBytesGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gravity_data_snapshot",
+		Name:      "storage_bytes",
+		Help:      "Disk space taken by collection as of its last storage report.",
+	}, []string{"collection"})
+
+	quotaLimitGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gravity_data_snapshot",
+		Name:      "quota_limit",
+		Help:      "Limit of collection, by records, bytes or record_size.",
+	}, []string{"collection", "limit"})
+
+	quotaViolationsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gravity_data_snapshot",
+		Name:      "quota_violations_total",
+		Help:      "Number of events which broke limit of collection.",
+	}, []string{"collection", "limit"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		recordCountGauge,
+		totalBytesGauge,
+		lastUpdatedGauge,
+		sequenceGauge,
+		openCollectionsGauge,
+		collectionOpensCounter,
+		collectionEvictionsCounter,
+		collectionRecoveriesCounter,
+		quotaLimitGauge,
+		quotaViolationsCounter,
+		compactionsCounter,
+		storageBytesGauge,
+	)
+}
+
+func deleteStatsMetrics(collection string) {
+	recordCountGauge.DeleteLabelValues(collection)
+	totalBytesGauge.DeleteLabelValues(collection)
+	lastUpdatedGauge.DeleteLabelValues(collection)
+	sequenceGauge.DeleteLabelValues(collection)
+	compactionsCounter.DeleteLabelValues(collection)
+	storageBytesGauge.DeleteLabelValues(collection)
+	quotaLimitGauge.DeletePartialMatch(prometheus.Labels{"collection": collection})
+	quotaViolationsCounter.DeletePartialMatch(prometheus.Labels{"collection": collection})
+}
+
+func updateQuotaMetrics(collection string, quota *QuotaPolicy) {
+
+	quotaLimitGauge.DeletePartialMatch(prometheus.Labels{"collection": collection})
+	if quota == nil {
+		return
+	}
+
+	limits := map[string]uint64{
+		"records":     quota.MaxRecords,
+		"bytes":       quota.MaxBytes,
+		"record_size": quota.MaxRecordSize,
+	}
+
+	for limit, value := range limits {
+		if value > 0 {
+			quotaLimitGauge.WithLabelValues(collection, limit).Set(float64(value))
+		}
+	}
+}
+
+func updateStatsMetrics(collection string, sequence uint64, stats *CollectionStats) {
+	recordCountGauge.WithLabelValues(collection).Set(float64(stats.RecordCount))
+	totalBytesGauge.WithLabelValues(collection).Set(float64(stats.TotalBytes))
+	lastUpdatedGauge.WithLabelValues(collection).Set(float64(stats.LastUpdated) / 1e9)
+	sequenceGauge.WithLabelValues(collection).Set(float64(sequence))
+}
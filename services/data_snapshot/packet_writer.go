@@ -0,0 +1,133 @@
+package data_snapshot
+
+import (
+	"errors"
+
+	pb "gravity-data-snapshot/pb"
+)
+
+var ErrCollectionClosed = errors.New("collection is being closed")
+
+const packetSize = 100
+
+type packetSender interface {
+	Send(*pb.SnapshotPacket) error
+}
+
+// packetWriter buffers entries and sends them in packets of packetSize.
+type packetWriter struct {
+	stream       packetSender
+	packet       *pb.SnapshotPacket
+	dictionaries [][]byte
+	cursor       *SnapshotCursor
+	done         <-chan struct{}
+}
+
+func newPacketWriter(stream packetSender, collection string, seq uint64) *packetWriter {
+	return &packetWriter{
+		stream: stream,
+		packet: &pb.SnapshotPacket{
+			Collection: collection,
+			Sequence:   seq,
+			Entries:    make([]*pb.SnapshotEntry, 0),
+		},
+	}
+}
+
+// SetDictionaries attaches compression dictionaries to the first packet.
+func (w *packetWriter) SetDictionaries(dictionaries [][]byte) {
+	w.dictionaries = dictionaries
+}
+
+// newPacketWriter makes a writer which stops streaming once database is
+// being closed.
+func (database *Database) newPacketWriter(stream packetSender, seq uint64) *packetWriter {
+
+	writer := newPacketWriter(stream, database.name, seq)
+	writer.done = database.ctx.Done()
+
+	return writer
+}
+
+// SetCursor makes packets carry cursor after their last entry, entries are
+// written by WriteKey then.
+func (w *packetWriter) SetCursor(checkpoint string, index string, digest uint64) {
+	w.cursor = &SnapshotCursor{
+		Checkpoint: checkpoint,
+		Collection: w.packet.Collection,
+		Index:      index,
+		Digest:     digest,
+	}
+}
+
+// WriteKey writes entry which was read at key.
+func (w *packetWriter) WriteKey(key []byte, entry *pb.SnapshotEntry) error {
+
+	if w.cursor != nil {
+		w.cursor.LastKey = cloneBytes(key)
+	}
+
+	return w.Write(entry)
+}
+
+func (w *packetWriter) Write(entry *pb.SnapshotEntry) error {
+
+	select {
+	case <-w.done:
+		return ErrCollectionClosed
+	default:
+	}
+
+	w.packet.Entries = append(w.packet.Entries, entry)
+
+	if len(w.packet.Entries) >= packetSize {
+		return w.Flush()
+	}
+
+	return nil
+}
+
+// Flush sends entries if buffer has data still.
+func (w *packetWriter) Flush() error {
+
+	if len(w.packet.Entries) == 0 {
+		return nil
+	}
+
+	packet := w.packet
+	packet.Dictionaries = w.dictionaries
+	if w.cursor != nil {
+		packet.Cursor = w.cursor.Encode()
+	}
+
+	w.packet = &pb.SnapshotPacket{
+		Collection: packet.Collection,
+		Sequence:   packet.Sequence,
+		Entries:    make([]*pb.SnapshotEntry, 0),
+	}
+	w.dictionaries = nil
+
+	return w.send(packet)
+}
+
+// send gives up on packet once database is being closed, so a client which
+// doesn't read its stream can't hold collection open. Packet is left to
+// stream then, and it fails as soon as request returns.
+func (w *packetWriter) send(packet *pb.SnapshotPacket) error {
+
+	if w.done == nil {
+		return w.stream.Send(packet)
+	}
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- w.stream.Send(packet)
+	}()
+
+	select {
+	case err := <-sent:
+		return err
+	case <-w.done:
+		return ErrCollectionClosed
+	}
+}
@@ -0,0 +1,85 @@
+package data_snapshot
+
+import (
+	"bytes"
+	"errors"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+)
+
+// Most partitions one snapshot is split into.
+const maxPartitions = 256
+
+var ErrInvalidPartition = errors.New("invalid partition")
+
+// Partition is a range of record keys of snapshot, start is inclusive and
+// end exclusive. Start of the first partition and end of the last one are
+// empty.
+type Partition struct {
+	Start       []byte
+	End         []byte
+	RecordCount uint64
+}
+
+// Partitions splits records of snapshot into ranges of about the same count
+// of records. Fewer partitions are returned if there are fewer records.
+func (database *Database) Partitions(snapshot store.Snapshot, count int) ([]*Partition, error) {
+
+	if count < 1 {
+		count = 1
+	} else if count > maxPartitions {
+		count = maxPartitions
+	}
+
+	stats, err := database.readStats(snapshot)
+	if err == store.ErrNotFound {
+		stats = &CollectionStats{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	size := (stats.RecordCount + uint64(count) - 1) / uint64(count)
+	if size == 0 {
+		return []*Partition{{}}, nil
+	}
+
+	partitions := make([]*Partition, 0, count)
+	current := &Partition{}
+
+	iter := snapshot.NewIterator(recordPrefix)
+	defer iter.Release()
+
+	for iter.Next() {
+
+		if current.RecordCount == size && len(partitions) < count-1 {
+			current.End = cloneBytes(iter.Key())
+			partitions = append(partitions, current)
+			current = &Partition{
+				Start: current.End,
+			}
+		}
+
+		current.RecordCount++
+	}
+
+	err = iter.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(partitions, current), nil
+}
+
+// checkPartition tells whether bounds of partition are record keys.
+func checkPartition(start []byte, end []byte) error {
+
+	if len(start) > 0 && !bytes.HasPrefix(start, recordPrefix) {
+		return ErrInvalidPartition
+	}
+
+	if len(end) > 0 && (!bytes.HasPrefix(end, recordPrefix) || bytes.Compare(start, end) >= 0) {
+		return ErrInvalidPartition
+	}
+
+	return nil
+}
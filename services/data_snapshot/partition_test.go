@@ -0,0 +1,130 @@
+package data_snapshot
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCheckPartition(t *testing.T) {
+
+	a := recordKey([]byte("a"))
+	b := recordKey([]byte("b"))
+
+	tests := []struct {
+		start []byte
+		end   []byte
+		valid bool
+	}{
+		{nil, nil, true},
+		{a, nil, true},
+		{nil, b, true},
+		{a, b, true},
+		{b, a, false},
+		{a, a, false},
+		{[]byte("x"), nil, false},
+		{nil, []byte("x"), false},
+		{a, recordPrefix, false},
+	}
+
+	for _, test := range tests {
+
+		err := checkPartition(test.start, test.end)
+		if (err == nil) != test.valid {
+			t.Errorf("checkPartition(%q, %q) = %v", test.start, test.end, err)
+		}
+	}
+}
+
+func TestPartitions(t *testing.T) {
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	const records = 1024
+	for i := 0; i < records; i++ {
+		writeTestRecord(t, db, uint64(i+1), float64(i))
+	}
+
+	tests := []struct {
+		count int
+		want  int
+	}{
+		{-1, 1},
+		{1, 1},
+		{3, 3},
+		{7, 7},
+		{records + 1, maxPartitions},
+	}
+
+	snapshot, err := db.db.GetSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Release()
+
+	for _, test := range tests {
+
+		partitions, err := db.Partitions(snapshot, test.count)
+		if err != nil {
+			t.Fatalf("Partitions(%d): %v", test.count, err)
+		}
+
+		if len(partitions) != test.want {
+			t.Fatalf("Partitions(%d) returned %d partitions, want %d", test.count, len(partitions), test.want)
+		}
+
+		// Partitions cover all records without gaps
+		total := uint64(0)
+		for i, partition := range partitions {
+
+			if i == 0 && partition.Start != nil || i == len(partitions)-1 && partition.End != nil {
+				t.Errorf("Partitions(%d): partition %d is open at wrong side", test.count, i)
+			}
+
+			if i > 0 && !bytes.Equal(partition.Start, partitions[i-1].End) {
+				t.Errorf("Partitions(%d): partition %d doesn't start at end of previous one", test.count, i)
+			}
+
+			if err := checkPartition(partition.Start, partition.End); err != nil {
+				t.Errorf("Partitions(%d): partition %d: %v", test.count, i, err)
+			}
+
+			stream := &testStream{}
+			err := db.FetchSnapshotFrom(snapshot, stream, &SnapshotOptions{Start: partition.Start, End: partition.End})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if uint64(len(stream.entries())) != partition.RecordCount {
+				t.Errorf("Partitions(%d): partition %d has %d records, want %d", test.count, i, len(stream.entries()), partition.RecordCount)
+			}
+
+			total += partition.RecordCount
+		}
+
+		if total != records {
+			t.Errorf("Partitions(%d) cover %d records, want %d", test.count, total, records)
+		}
+	}
+}
+
+func TestPartitionsEmpty(t *testing.T) {
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	snapshot, err := db.db.GetSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Release()
+
+	partitions, err := db.Partitions(snapshot, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(partitions) != 1 || partitions[0].Start != nil || partitions[0].End != nil || partitions[0].RecordCount != 0 {
+		t.Errorf("Partitions of empty collection = %+v", partitions)
+	}
+}
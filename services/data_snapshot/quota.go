@@ -0,0 +1,300 @@
+package data_snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var ErrQuotaExceeded = errors.New("quota of collection exceeded")
+
+var rejectedPrefix = []byte("rej-")
+
+const (
+	quotaReject = "reject"
+	quotaStop   = "stop"
+	quotaAlert  = "alert"
+)
+
+// QuotaPolicy limits what collection takes, zero means no limit. Sizes are
+// of record data as in stats. Events which break limits are rejected, stop
+// ingestion of collection or are applied with an alert, depending on policy.
+type QuotaPolicy struct {
+	MaxBytes      uint64
+	MaxRecords    uint64
+	MaxRecordSize uint64
+	Policy        string
+}
+
+// QuotaViolation tells which limit event would break.
+type QuotaViolation struct {
+	Limit string `json:"limit"`
+	Value uint64 `json:"value"`
+	Max   uint64 `json:"max"`
+}
+
+func (violation *QuotaViolation) Error() string {
+	return fmt.Sprintf("%s would be %d, limit is %d", violation.Limit, violation.Value, violation.Max)
+}
+
+// RejectedEvent is event which was not applied because of quota.
+type RejectedEvent struct {
+	Sequence   uint64          `json:"seq"`
+	Violation  *QuotaViolation `json:"violation"`
+	Projection *Projection     `json:"event"`
+	RejectedAt int64           `json:"rejected_at"`
+}
+
+// RejectedHandler hands rejected event over to dead letter. Event is kept by
+// collection and handed over again later on if handler fails.
+type RejectedHandler func(database *Database, event *RejectedEvent) error
+
+func rejectedKey(sequence uint64) []byte {
+	return prefixedKey(rejectedPrefix, Uint64ToSortableBytes(sequence))
+}
+
+func getCollectionSize(collection string, key string) uint64 {
+	return uint64(viper.GetSizeInBytes(collectionConfigKey(collection, key)))
+}
+
+func getQuotaPolicy(collection string) (*QuotaPolicy, error) {
+
+	policy := &QuotaPolicy{
+		MaxBytes:      getCollectionSize(collection, "max_bytes"),
+		MaxRecords:    uint64(getCollectionInt(collection, "max_records")),
+		MaxRecordSize: getCollectionSize(collection, "max_record_size"),
+		Policy:        getCollectionString(collection, "quota_policy"),
+	}
+
+	switch policy.Policy {
+	case "":
+		policy.Policy = quotaReject
+	case quotaReject, quotaStop, quotaAlert:
+	default:
+		return nil, fmt.Errorf("unknown quota policy \"%s\"", policy.Policy)
+	}
+
+	if policy.MaxBytes == 0 && policy.MaxRecords == 0 && policy.MaxRecordSize == 0 {
+		return nil, nil
+	}
+
+	return policy, nil
+}
+
+func (database *Database) initQuota() error {
+
+	policy, err := getQuotaPolicy(database.name)
+	if err != nil {
+		return err
+	}
+
+	database.quota = policy
+	updateQuotaMetrics(database.name, policy)
+
+	// Events which were rejected before collection was closed
+	iter := database.db.NewIterator(rejectedPrefix)
+	if iter.Next() {
+		atomic.StoreInt32(&database.hasRejected, 1)
+	}
+
+	err = iter.Error()
+	iter.Release()
+
+	return err
+}
+
+// checkQuota finds limit which change of record would break. Changes which
+// don't make collection bigger are always allowed.
+func (database *Database) checkQuota(countDelta int64, bytesDelta int64, recordSize int) *QuotaViolation {
+
+	quota := database.quota
+	if quota == nil {
+		return nil
+	}
+
+	if quota.MaxRecordSize > 0 && uint64(recordSize) > quota.MaxRecordSize && bytesDelta > 0 {
+		return &QuotaViolation{
+			Limit: "record_size",
+			Value: uint64(recordSize),
+			Max:   quota.MaxRecordSize,
+		}
+	}
+
+	count := uint64(int64(database.stats.RecordCount) + countDelta)
+	if quota.MaxRecords > 0 && countDelta > 0 && count > quota.MaxRecords {
+		return &QuotaViolation{
+			Limit: "records",
+			Value: count,
+			Max:   quota.MaxRecords,
+		}
+	}
+
+	size := uint64(int64(database.stats.TotalBytes) + bytesDelta)
+	if quota.MaxBytes > 0 && bytesDelta > 0 && size > quota.MaxBytes {
+		return &QuotaViolation{
+			Limit: "bytes",
+			Value: size,
+			Max:   quota.MaxBytes,
+		}
+	}
+
+	return nil
+}
+
+// enforceQuota applies policy to event which breaks quota. It returns true
+// if event should be applied anyway.
+func (database *Database) enforceQuota(sequence uint64, projection *Projection, violation *QuotaViolation) (bool, error) {
+
+	quotaViolationsCounter.WithLabelValues(database.name, violation.Limit).Inc()
+
+	fields := log.Fields{
+		"collection": database.name,
+		"seq":        sequence,
+		"limit":      violation.Limit,
+		"policy":     database.quota.Policy,
+	}
+
+	switch database.quota.Policy {
+	case quotaAlert:
+		log.WithFields(fields).Warn(violation.Error())
+		return true, nil
+	case quotaStop:
+		log.WithFields(fields).Error(violation.Error())
+		return false, ErrQuotaExceeded
+	}
+
+	log.WithFields(fields).Warn("Rejected event, " + violation.Error())
+
+	data, err := json.Marshal(&RejectedEvent{
+		Sequence:   sequence,
+		Violation:  violation,
+		Projection: projection,
+		RejectedAt: time.Now().UnixNano(),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	value, err := database.encryptValue(rejectedKey(sequence), data)
+	if err != nil {
+		return false, err
+	}
+
+	// Sequence moves on, so event is not applied again by replay, and event
+	// is kept along with it until it is handed over to dead letter
+	batch := database.db.NewBatch()
+	batch.Put(sequenceKey, Uint64ToBytes(sequence))
+	batch.Put(rejectedKey(sequence), value)
+
+	err = database.db.Write(batch)
+	if err != nil {
+		return false, err
+	}
+
+	atomic.StoreInt32(&database.hasRejected, 1)
+
+	return false, nil
+}
+
+// stopIngestion freezes collection with event which broke quota at the head
+// of queue, so it is applied first once quota is raised and collection is
+// unfrozen.
+func (database *Database) stopIngestion(sequence uint64, projection *Projection) error {
+
+	err := database.queueEvent(sequence, projection)
+	if err != nil {
+		return err
+	}
+
+	return database.freeze()
+}
+
+// SetRejectedHandler sets handler of rejected events, events which were kept
+// by collection are handed over to it in background.
+func (database *Database) SetRejectedHandler(handler RejectedHandler) {
+
+	database.mutex.Lock()
+	database.rejectedHandler = handler
+	database.mutex.Unlock()
+
+	if handler != nil && atomic.LoadInt32(&database.hasRejected) == 1 {
+		database.startWorker(database.notifyRejected)
+	}
+}
+
+// notifyRejected hands rejected events over to handler outside of lock of
+// database, and removes those which handler took. Events are handed over in
+// order of sequence, the rest is left for next time once handler fails.
+func (database *Database) notifyRejected() {
+
+	if atomic.LoadInt32(&database.hasRejected) == 0 {
+		return
+	}
+
+	database.mutex.Lock()
+	handler := database.rejectedHandler
+	database.mutex.Unlock()
+
+	if handler == nil {
+		return
+	}
+
+	// Events are handed over once, events which are rejected meanwhile are
+	// left to next call
+	if !atomic.CompareAndSwapInt32(&database.notifyingRejected, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&database.notifyingRejected, 0)
+
+	atomic.StoreInt32(&database.hasRejected, 0)
+
+	iter := database.db.NewIterator(rejectedPrefix)
+	defer iter.Release()
+
+	for iter.Next() {
+
+		key := cloneBytes(iter.Key())
+		err := database.handleRejected(handler, key, iter.Value())
+		if err != nil {
+			atomic.StoreInt32(&database.hasRejected, 1)
+			log.WithFields(log.Fields{
+				"collection": database.name,
+				"seq":        SortableBytesToUint64(key[len(rejectedPrefix):]),
+			}).Error("Failed to hand over rejected event: ", err)
+			return
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		atomic.StoreInt32(&database.hasRejected, 1)
+		log.WithFields(log.Fields{
+			"collection": database.name,
+		}).Error(err)
+	}
+}
+
+func (database *Database) handleRejected(handler RejectedHandler, key []byte, value []byte) error {
+
+	data, err := database.decodeValue(key, value)
+	if err != nil {
+		return err
+	}
+
+	event := &RejectedEvent{}
+	err = json.Unmarshal(data, event)
+	if err != nil {
+		return err
+	}
+
+	err = handler(database, event)
+	if err != nil {
+		return err
+	}
+
+	return database.db.Delete(key)
+}
@@ -0,0 +1,245 @@
+package data_snapshot
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var errDeadLetter = errors.New("dead letter unavailable")
+
+// testDeadLetter keeps events which were handed over, and fails while
+// broken is set.
+type testDeadLetter struct {
+	mutex  sync.Mutex
+	events []*RejectedEvent
+	broken bool
+}
+
+func (dl *testDeadLetter) handle(database *Database, event *RejectedEvent) error {
+
+	dl.mutex.Lock()
+	defer dl.mutex.Unlock()
+
+	if dl.broken {
+		return errDeadLetter
+	}
+
+	dl.events = append(dl.events, event)
+
+	return nil
+}
+
+func (dl *testDeadLetter) sequences() []uint64 {
+
+	dl.mutex.Lock()
+	defer dl.mutex.Unlock()
+
+	sequences := make([]uint64, 0, len(dl.events))
+	for _, event := range dl.events {
+		sequences = append(sequences, event.Sequence)
+	}
+
+	return sequences
+}
+
+func setTestQuota(settings map[string]interface{}) func() {
+
+	for key, value := range settings {
+		viper.Set("collections.t."+key, value)
+	}
+
+	return func() {
+		for key := range settings {
+			viper.Set("collections.t."+key, nil)
+		}
+	}
+}
+
+func checkRecords(t *testing.T, db *Database, seq uint64, count uint64) {
+
+	gotSeq, stats, err := db.GetStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gotSeq != seq || stats.RecordCount != count {
+		t.Errorf("collection has %d records at %d, want %d at %d", stats.RecordCount, gotSeq, count, seq)
+	}
+}
+
+func TestQuotaReject(t *testing.T) {
+
+	defer setTestQuota(map[string]interface{}{
+		"max_records": 2,
+	})()
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	dl := &testDeadLetter{broken: true}
+	db.SetRejectedHandler(dl.handle)
+
+	for i := 0; i < 3; i++ {
+		writeTestRecord(t, db, uint64(i+1), float64(i))
+	}
+
+	// Sequence moves past rejected event, which is kept while dead letter
+	// fails
+	checkRecords(t, db, 3, 2)
+	if _, err := db.db.Get(rejectedKey(3)); err != nil {
+		t.Fatalf("rejected event isn't kept: %v", err)
+	}
+
+	// Changes which don't add records are applied, and kept events are
+	// handed over along with them
+	dl.broken = false
+	writeTestRecord(t, db, 4, 0, Field{Name: "n", Value: float64(1)})
+	checkRecords(t, db, 4, 2)
+
+	if got := dl.sequences(); len(got) != 1 || got[0] != 3 {
+		t.Fatalf("dead letter has %v, want [3]", got)
+	}
+
+	if dl.events[0].Violation.Limit != "records" || dl.events[0].Projection.Collection != "t" {
+		t.Errorf("rejected event = %+v", dl.events[0])
+	}
+
+	if _, err := db.db.Get(rejectedKey(3)); err == nil {
+		t.Error("rejected event is kept after it was handed over")
+	}
+}
+
+func TestQuotaRejectedAfterReopen(t *testing.T) {
+
+	defer setTestQuota(map[string]interface{}{
+		"max_record_size": 10,
+	})()
+
+	dir, err := ioutil.TempDir("", "data-snapshot-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dbpath := filepath.Join(dir, "t")
+	db, err := OpenDatabase("t", "leveldb", dbpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestRecord(t, db, 1, 1, Field{Name: "data", Value: "too large for quota"})
+	writeTestRecord(t, db, 2, 2, Field{Name: "data", Value: "again too large"})
+	db.Close()
+
+	// Events which were rejected without handler are handed over once
+	// collection is opened again
+	db, err = OpenDatabase("t", "leveldb", dbpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	dl := &testDeadLetter{}
+	db.SetRejectedHandler(dl.handle)
+
+	for deadline := time.Now().Add(5 * time.Second); len(dl.sequences()) < 2; {
+		if time.Now().After(deadline) {
+			t.Fatalf("dead letter has %v, want [1 2]", dl.sequences())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := dl.sequences(); got[0] != 1 || got[1] != 2 {
+		t.Errorf("dead letter has %v, want [1 2]", got)
+	}
+
+	checkRecords(t, db, 2, 0)
+}
+
+func TestQuotaStop(t *testing.T) {
+
+	defer setTestQuota(map[string]interface{}{
+		"max_records":  2,
+		"quota_policy": quotaStop,
+	})()
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	for i := 0; i < 4; i++ {
+		writeTestRecord(t, db, uint64(i+1), float64(i))
+	}
+
+	// Event which broke quota waits in queue along with later ones
+	checkRecords(t, db, 2, 2)
+	if pending, err := db.PendingCount(); err != nil || pending != 2 || db.State() != stateFrozen {
+		t.Fatalf("collection is %s with %d pending events, %v", db.State(), pending, err)
+	}
+
+	// Queue stays while quota is not raised
+	if err := db.Unfreeze(); err != ErrQuotaExceeded {
+		t.Fatalf("Unfreeze within quota = %v, want %v", err, ErrQuotaExceeded)
+	}
+
+	if db.State() != stateFrozen {
+		t.Errorf("collection is %s after Unfreeze within quota", db.State())
+	}
+
+	// Quota is raised on handle, since workers read config meanwhile
+	db.quota.MaxRecords = 10
+
+	if err := db.Unfreeze(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkRecords(t, db, 4, 4)
+	if db.State() != stateOK {
+		t.Errorf("collection is %s after quota was raised", db.State())
+	}
+}
+
+func TestQuotaAlert(t *testing.T) {
+
+	defer setTestQuota(map[string]interface{}{
+		"max_bytes":    "16",
+		"quota_policy": quotaAlert,
+	})()
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	dl := &testDeadLetter{}
+	db.SetRejectedHandler(dl.handle)
+
+	for i := 0; i < 3; i++ {
+		writeTestRecord(t, db, uint64(i+1), float64(i), Field{Name: "data", Value: "over quota"})
+	}
+
+	checkRecords(t, db, 3, 3)
+	if got := dl.sequences(); len(got) != 0 {
+		t.Errorf("dead letter has %v under alert policy", got)
+	}
+}
+
+func TestQuotaPolicyConfig(t *testing.T) {
+
+	defer setTestQuota(map[string]interface{}{
+		"quota_policy": "drop",
+	})()
+
+	if _, err := getQuotaPolicy("t"); err == nil {
+		t.Error("unknown policy is accepted")
+	}
+
+	viper.Set("collections.t.quota_policy", nil)
+	if policy, err := getQuotaPolicy("t"); err != nil || policy != nil {
+		t.Errorf("policy without limits = %+v, %v, want none", policy, err)
+	}
+}
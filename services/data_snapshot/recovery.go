@@ -0,0 +1,461 @@
+package data_snapshot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrCollectionFailed     = errors.New("collection is corrupted and was quarantined")
+	ErrRebuildUnavailable   = errors.New("events can't be replayed to rebuild collection")
+	ErrCollectionRebuilding = errors.New("collection is being rebuilt already")
+)
+
+var rebuildingKey = []byte("rebuilding")
+
+const (
+	defaultRebuildIdleTimeout = 10 * time.Second
+	rebuildCheckInterval      = time.Second
+)
+
+// CollectionFailure tells why collection can't be opened. Failures of
+// quarantined collections are kept in catalog until collection is rebuilt,
+// other failures are retried on next use.
+type CollectionFailure struct {
+	Error      string `json:"error"`
+	Quarantine string `json:"quarantine,omitempty"`
+	FailedAt   int64  `json:"failed_at"`
+}
+
+// Replayer delivers events from sequence on to handler until stop is called.
+// Sequence of handler is of event store, events of all collections are
+// delivered.
+type Replayer func(start uint64, handler func(sequence uint64, projection *Projection)) (stop func(), err error)
+
+func (dm *DatabaseManager) SetReplayer(replayer Replayer) {
+	dm.replayer = replayer
+}
+
+// GetFailure returns failure of collection, or nil if it is fine.
+func (dm *DatabaseManager) GetFailure(name string) *CollectionFailure {
+
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+
+	if entry, ok := dm.catalog.Lookup(name); ok && entry.Failure != nil {
+		return entry.Failure
+	}
+
+	return dm.failures[name]
+}
+
+// AwaitsRebuild tells whether data of collection was quarantined or removed,
+// so that it only comes back by rebuild from event store.
+func (dm *DatabaseManager) AwaitsRebuild(name string) bool {
+
+	entry, ok := dm.catalog.Lookup(name)
+
+	return ok && entry.Failure != nil
+}
+
+// setFailure records failure of collection which is retried on next use, nil
+// clears it.
+func (dm *DatabaseManager) setFailure(name string, failure *CollectionFailure) {
+
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+
+	if failure == nil {
+		delete(dm.failures, name)
+		return
+	}
+
+	dm.failures[name] = failure
+}
+
+// open opens database of collection. Corrupted storage gets recovered by
+// engine if possible, otherwise it is moved to quarantine and collection is
+// rebuilt if auto_rebuild is set. Collection is opening or busy meanwhile, so
+// lock of manager is not held.
+func (dm *DatabaseManager) open(name string, entry *CatalogEntry) (*Database, error) {
+
+	db, err := openCollection(dm.catalog, entry)
+	if err == nil {
+		dm.setFailure(name, nil)
+		dm.resumeRebuild(db)
+		return db, nil
+	}
+
+	fields := log.Fields{
+		"collection": name,
+		"dir":        entry.Dir,
+	}
+
+	if !store.IsCorrupted(err) {
+		log.WithFields(fields).Error(err)
+		dm.setFailure(name, &CollectionFailure{
+			Error:    err.Error(),
+			FailedAt: time.Now().UnixNano(),
+		})
+		return nil, err
+	}
+
+	log.WithFields(fields).Error("Collection is corrupted, recovering: ", err)
+
+	db, recoverErr := recoverCollection(dm.catalog, entry)
+	if recoverErr == nil {
+		dm.setFailure(name, nil)
+		collectionRecoveriesCounter.WithLabelValues("recovered").Inc()
+		log.WithFields(fields).Warn("Recovered corrupted collection, latest changes may be lost")
+		return db, nil
+	}
+
+	log.WithFields(fields).Error("Failed to recover collection: ", recoverErr)
+
+	// Shared store is left to operator, other collections may still work
+	if isSharedLayout() {
+		dm.setFailure(name, &CollectionFailure{
+			Error:    err.Error(),
+			FailedAt: time.Now().UnixNano(),
+		})
+		return nil, err
+	}
+
+	quarantine, quarantineErr := quarantineDir(dm.catalog.Path(entry), entry.Dir)
+	if quarantineErr != nil {
+		log.WithFields(fields).Error(quarantineErr)
+		dm.setFailure(name, &CollectionFailure{
+			Error:    err.Error(),
+			FailedAt: time.Now().UnixNano(),
+		})
+		return nil, err
+	}
+
+	failure := &CollectionFailure{
+		Error:      err.Error(),
+		Quarantine: quarantine,
+		FailedAt:   time.Now().UnixNano(),
+	}
+
+	err = dm.catalog.SetFailure(name, failure)
+	if err != nil {
+		return nil, err
+	}
+
+	collectionRecoveriesCounter.WithLabelValues("quarantined").Inc()
+	fields["quarantine"] = quarantine
+	log.WithFields(fields).Error("Quarantined corrupted collection")
+
+	if !getCollectionBool(name, "auto_rebuild") {
+		return nil, ErrCollectionFailed
+	}
+
+	return dm.rebuild(name, entry)
+}
+
+// quarantineDir moves directory of collection out of database path, so it is
+// kept for investigation.
+func quarantineDir(path string, dir string) (string, error) {
+
+	root := viper.GetString("database.quarantine_dir")
+	if root == "" {
+		root = "./quarantine"
+	}
+
+	err := os.MkdirAll(root, 0700)
+	if err != nil {
+		return "", err
+	}
+
+	target := filepath.Join(root, fmt.Sprintf("%s-%s", dir, time.Now().UTC().Format("20060102T150405")))
+
+	return target, os.Rename(path, target)
+}
+
+// rebuild creates collection anew and replays events of event store into it.
+// Collection stays frozen meanwhile, so events of ingestion are queued and
+// applied once replay catches up. Collection is opening or busy meanwhile.
+func (dm *DatabaseManager) rebuild(name string, entry *CatalogEntry) (*Database, error) {
+
+	if dm.replayer == nil {
+		return nil, ErrRebuildUnavailable
+	}
+
+	db, err := openCollection(dm.catalog, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Freeze()
+	if err == nil {
+		err = db.db.Put(rebuildingKey, []byte{1})
+	}
+
+	if err == nil {
+		err = dm.catalog.SetFailure(name, nil)
+	}
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	dm.setFailure(name, nil)
+	atomic.StoreInt32(&db.rebuilding, 1)
+	db.startWorker(func() {
+		db.runRebuild(dm.replayer)
+	})
+
+	collectionRecoveriesCounter.WithLabelValues("rebuilt").Inc()
+	log.WithFields(log.Fields{
+		"collection": name,
+	}).Warn("Rebuilding collection from event store")
+
+	return db, nil
+}
+
+// RebuildCollection moves data of collection to quarantine and rebuilds it
+// from event store.
+func (dm *DatabaseManager) RebuildCollection(name string) error {
+
+	if dm.replayer == nil {
+		return ErrRebuildUnavailable
+	}
+
+	dbs, err := dm.checkOut(name)
+	if err != nil {
+		return err
+	}
+
+	entry, err := dm.quarantineForRebuild(name, dbs[0])
+	if err != nil {
+		dm.checkIn(name)
+		return err
+	}
+
+	db, err := dm.rebuild(name, entry)
+
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+
+	delete(dm.busy, name)
+
+	if err != nil {
+		return err
+	}
+
+	dm.register(name, db)
+
+	return nil
+}
+
+// quarantineForRebuild closes collection and moves its data to quarantine,
+// data in shared store is removed instead. Failure is recorded until rebuild
+// starts, so that collection is not opened empty meanwhile.
+func (dm *DatabaseManager) quarantineForRebuild(name string, db *Database) (*CatalogEntry, error) {
+
+	entry, ok := dm.catalog.Lookup(name)
+	if !ok {
+		return nil, ErrCollectionNotFound
+	}
+
+	if db != nil {
+		if db.isRebuilding() {
+			dm.restore(name, db)
+			return nil, ErrCollectionRebuilding
+		}
+
+		err := dm.closeDatabase(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if isSharedLayout() {
+		return entry, dm.catalog.removeData(entry, layoutShared)
+	}
+
+	path := dm.catalog.Path(entry)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return entry, nil
+	}
+
+	quarantine, err := quarantineDir(path, entry.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dm.catalog.SetFailure(name, &CollectionFailure{
+		Error:      "rebuild was requested",
+		Quarantine: quarantine,
+		FailedAt:   time.Now().UnixNano(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"collection": name,
+		"quarantine": quarantine,
+	}).Info("Quarantined collection for rebuild")
+
+	return entry, nil
+}
+
+// resumeRebuild carries on with rebuild which was cut short by close of
+// collection.
+func (dm *DatabaseManager) resumeRebuild(db *Database) {
+
+	if !db.isRebuilding() {
+		return
+	}
+
+	if dm.replayer == nil {
+		log.WithFields(log.Fields{
+			"collection": db.name,
+		}).Warn("Collection is being rebuilt but events can't be replayed, it stays frozen")
+		return
+	}
+
+	db.startWorker(func() {
+		db.runRebuild(dm.replayer)
+	})
+
+	log.WithFields(log.Fields{
+		"collection": db.name,
+	}).Info("Resumed rebuild of collection")
+}
+
+func (database *Database) initRebuildState() error {
+
+	_, err := database.db.Get(rebuildingKey)
+	if err == nil {
+		atomic.StoreInt32(&database.rebuilding, 1)
+		return nil
+	} else if err != store.ErrNotFound {
+		return err
+	}
+
+	return nil
+}
+
+func (database *Database) isRebuilding() bool {
+	return atomic.LoadInt32(&database.rebuilding) == 1
+}
+
+// replayEvent applies event of replay while collection is frozen.
+func (database *Database) replayEvent(sequence uint64, projection *Projection) error {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	err := database.applyEvent(sequence, projection)
+	if err == ErrQuotaExceeded {
+		return database.queueEvent(sequence, projection)
+	}
+
+	return err
+}
+
+// firstPending returns sequence of the first event queued by ingestion.
+func (database *Database) firstPending() (uint64, bool) {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	iter := database.db.NewIterator(pendingPrefix)
+	defer iter.Release()
+
+	if !iter.Next() {
+		return 0, false
+	}
+
+	return SortableBytesToUint64(iter.Key()[len(pendingPrefix):]), true
+}
+
+// runRebuild replays events until replay reaches events which were queued by
+// ingestion, or event store has nothing more to replay for a while.
+func (database *Database) runRebuild(replayer Replayer) {
+
+	lastSequence, err := database.GetSequence()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"collection": database.name,
+		}).Error(err)
+		return
+	}
+
+	lastReplayed := time.Now().UnixNano()
+
+	stop, err := replayer(lastSequence+1, func(sequence uint64, projection *Projection) {
+
+		if projection.Collection == database.name {
+			err := database.replayEvent(sequence, projection)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"collection": database.name,
+					"seq":        sequence,
+				}).Error(err)
+			}
+		}
+
+		atomic.StoreUint64(&lastSequence, sequence)
+		atomic.StoreInt64(&lastReplayed, time.Now().UnixNano())
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"collection": database.name,
+		}).Error("Failed to replay events: ", err)
+		return
+	}
+
+	idleTimeout := getCollectionDuration(database.name, "rebuild_idle_timeout", defaultRebuildIdleTimeout)
+
+	ticker := time.NewTicker(rebuildCheckInterval)
+	defer ticker.Stop()
+
+	for database.tick(ticker) {
+
+		first, ok := database.firstPending()
+		caughtUp := ok && atomic.LoadUint64(&lastSequence)+1 >= first
+		idle := time.Since(time.Unix(0, atomic.LoadInt64(&lastReplayed))) >= idleTimeout
+		if !caughtUp && !idle {
+			continue
+		}
+
+		stop()
+
+		err := database.db.Delete(rebuildingKey)
+		if err == nil {
+			err = database.Unfreeze()
+		}
+
+		if err != nil {
+			log.WithFields(log.Fields{
+				"collection": database.name,
+			}).Error(err)
+		}
+
+		atomic.StoreInt32(&database.rebuilding, 0)
+
+		seq, _ := database.GetSequence()
+		log.WithFields(log.Fields{
+			"collection": database.name,
+			"seq":        seq,
+		}).Info("Rebuilt collection")
+
+		return
+	}
+
+	// Collection was closed before replay finished, it goes on next time
+	stop()
+}
@@ -0,0 +1,216 @@
+package data_snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// testEvent is event of event store which testReplayer delivers.
+type testEvent struct {
+	sequence   uint64
+	projection *Projection
+}
+
+// testReplayer delivers events from start on at once.
+func testReplayer(events []testEvent) Replayer {
+	return func(start uint64, handler func(sequence uint64, projection *Projection)) (func(), error) {
+
+		for _, event := range events {
+			if event.sequence >= start {
+				handler(event.sequence, event.projection)
+			}
+		}
+
+		return func() {}, nil
+	}
+}
+
+func insertEvent(collection string, id float64) *Projection {
+	return &Projection{
+		Collection: collection,
+		Method:     "insert",
+		Fields:     []Field{{Name: "id", Value: id, Primary: true}},
+	}
+}
+
+// setTestQuarantine makes collections quarantined in a temporary directory,
+// which is removed by the returned function along with the setting.
+func setTestQuarantine(t *testing.T) (string, func()) {
+
+	dir, err := ioutil.TempDir("", "data-snapshot-quarantine-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Set("database.quarantine_dir", dir)
+
+	return dir, func() {
+		viper.Set("database.quarantine_dir", "")
+		os.RemoveAll(dir)
+	}
+}
+
+// corruptTestCollection registers collection of bbolt engine whose file is
+// garbage, bbolt can't recover it. Engine is set for collection in config.
+func corruptTestCollection(t *testing.T, dm *DatabaseManager, name string) {
+
+	entry, err := dm.catalog.Register(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := dm.catalog.Path(entry)
+	err = os.MkdirAll(path, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	garbage := make([]byte, 4*4096)
+	for i := range garbage {
+		garbage[i] = 0xab
+	}
+
+	err = ioutil.WriteFile(filepath.Join(path, "data.db"), garbage, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitRebuilt waits for rebuild of collection to finish.
+func waitRebuilt(t *testing.T, db *Database) {
+
+	for deadline := time.Now().Add(10 * time.Second); db.isRebuilding(); {
+		if time.Now().After(deadline) {
+			t.Fatal("collection is still being rebuilt")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOpenQuarantinesCorruptedCollection(t *testing.T) {
+
+	_, unset := setTestQuarantine(t)
+	defer unset()
+
+	viper.Set("collections.a.engine", "bbolt")
+	defer viper.Set("collections.a.engine", "")
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	corruptTestCollection(t, dm, "a")
+
+	if db := dm.GetDatabase("a"); db != nil {
+		db.Release()
+		t.Fatal("corrupted collection is opened")
+	}
+
+	failure := dm.GetFailure("a")
+	if failure == nil || failure.Quarantine == "" || !dm.AwaitsRebuild("a") {
+		t.Fatalf("failure of corrupted collection = %+v", failure)
+	}
+
+	if _, err := os.Stat(filepath.Join(failure.Quarantine, "data.db")); err != nil {
+		t.Errorf("data isn't kept in quarantine: %v", err)
+	}
+
+	// Quarantined collection isn't opened empty
+	if db := dm.GetDatabase("a"); db != nil {
+		db.Release()
+		t.Error("quarantined collection is opened")
+	}
+
+	if err := dm.RebuildCollection("a"); err != ErrRebuildUnavailable {
+		t.Errorf("RebuildCollection without replayer = %v, want %v", err, ErrRebuildUnavailable)
+	}
+}
+
+func TestAutoRebuildCorruptedCollection(t *testing.T) {
+
+	_, unset := setTestQuarantine(t)
+	defer unset()
+
+	viper.Set("collections.a.engine", "bbolt")
+	defer viper.Set("collections.a.engine", "")
+	viper.Set("collections.a.auto_rebuild", true)
+	defer viper.Set("collections.a.auto_rebuild", nil)
+	viper.Set("collections.a.rebuild_idle_timeout", "10ms")
+	defer viper.Set("collections.a.rebuild_idle_timeout", "")
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	corruptTestCollection(t, dm, "a")
+
+	dm.SetReplayer(testReplayer([]testEvent{
+		{1, insertEvent("a", 1)},
+		{2, insertEvent("b", 1)},
+		{3, insertEvent("a", 2)},
+	}))
+
+	db := dm.GetDatabase("a")
+	if db == nil {
+		t.Fatal("corrupted collection isn't rebuilt")
+	}
+	defer db.Release()
+
+	waitRebuilt(t, db)
+
+	// Events of other collections are skipped
+	checkRecords(t, db, 3, 2)
+	if db.State() != stateOK || dm.AwaitsRebuild("a") {
+		t.Errorf("rebuilt collection is %s", db.State())
+	}
+}
+
+func TestRebuildCollection(t *testing.T) {
+
+	dir, unset := setTestQuarantine(t)
+	defer unset()
+
+	dm, done := openTestManager(t)
+	defer done()
+
+	openTestCollection(t, dm, "a", 2)
+
+	events := make([]testEvent, 0)
+	for i := uint64(1); i <= 5; i++ {
+		events = append(events, testEvent{i, insertEvent("a", float64(i))})
+	}
+	dm.SetReplayer(testReplayer(events))
+
+	err := dm.RebuildCollection("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := dm.GetExistingDatabase("a")
+	if db == nil {
+		t.Fatal("rebuilt collection isn't open")
+	}
+	defer db.Release()
+
+	// Ingestion is queued until replay catches up with it
+	writeTestRecord(t, db, 6, 6)
+	if !db.IsFrozen() {
+		t.Error("collection isn't frozen while it is being rebuilt")
+	}
+
+	if err := dm.RebuildCollection("a"); err != ErrCollectionRebuilding {
+		t.Errorf("second RebuildCollection = %v, want %v", err, ErrCollectionRebuilding)
+	}
+
+	waitRebuilt(t, db)
+	checkRecords(t, db, 6, 6)
+
+	// Old data is kept in quarantine
+	quarantined, err := ioutil.ReadDir(dir)
+	if err != nil || len(quarantined) != 1 {
+		t.Errorf("quarantine has %d directories, %v, want 1", len(quarantined), err)
+	}
+}
@@ -0,0 +1,488 @@
+package data_snapshot
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+var schemaKey = []byte("schema")
+
+// Types of fields as in JSON Schema. Null is told by nullability instead.
+const (
+	typeBoolean = "boolean"
+	typeInteger = "integer"
+	typeNumber  = "number"
+	typeString  = "string"
+	typeObject  = "object"
+	typeArray   = "array"
+)
+
+// Largest integer which float64 of JSON holds exactly.
+const maxSafeInteger = 1 << 53
+
+// FieldSchema is what ingestion has seen of a top level field. Number is
+// given in order fields are first seen and never changes, so it can be used
+// as number of field in protobuf.
+type FieldSchema struct {
+	Name      string   `json:"name"`
+	Number    int32    `json:"number"`
+	Types     []string `json:"types"`
+	Nullable  bool     `json:"nullable"`
+	Primary   bool     `json:"primary,omitempty"`
+	FirstSeen uint64   `json:"first_seen"`
+	LastSeen  uint64   `json:"last_seen"`
+}
+
+// CollectionSchema is inferred from events of collection. It is maintained
+// in the same batch with changes of records, and is copied on change so
+// readers never see it half updated.
+type CollectionSchema struct {
+	Fields []*FieldSchema `json:"fields"`
+
+	index map[string]int
+}
+
+func (schema *CollectionSchema) buildIndex() {
+
+	schema.index = make(map[string]int, len(schema.Fields))
+	for i, field := range schema.Fields {
+		schema.index[field.Name] = i
+	}
+}
+
+func (schema *CollectionSchema) Field(name string) *FieldSchema {
+
+	i, ok := schema.index[name]
+	if !ok {
+		return nil
+	}
+
+	return schema.Fields[i]
+}
+
+// typeOfValue returns type of value which was decoded from JSON, or empty
+// string for null.
+func typeOfValue(value interface{}) string {
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case bool:
+		return typeBoolean
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) <= maxSafeInteger {
+			return typeInteger
+		}
+
+		return typeNumber
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return typeInteger
+		}
+
+		return typeNumber
+	case string:
+		return typeString
+	case []interface{}:
+		return typeArray
+	}
+
+	return typeObject
+}
+
+// observe returns schema with values of fields seen at sequence, or the
+// same schema if nothing changed.
+func (schema *CollectionSchema) observe(sequence uint64, fields []Field) *CollectionSchema {
+
+	var updated *CollectionSchema
+	for _, f := range fields {
+
+		valueType := typeOfValue(f.Value)
+
+		field := schema.Field(f.Name)
+		if field != nil && field.LastSeen >= sequence && field.hasType(valueType) && (field.Primary || !f.Primary) {
+			continue
+		}
+
+		if updated == nil {
+			updated = schema.clone()
+		}
+
+		updated.observeField(sequence, f.Name, valueType, f.Primary)
+	}
+
+	if updated == nil {
+		return schema
+	}
+
+	return updated
+}
+
+func (schema *CollectionSchema) observeField(sequence uint64, name string, valueType string, primary bool) {
+
+	i, ok := schema.index[name]
+	if !ok {
+		schema.index[name] = len(schema.Fields)
+		schema.Fields = append(schema.Fields, &FieldSchema{
+			Name:      name,
+			Number:    int32(len(schema.Fields) + 1),
+			Types:     []string{},
+			FirstSeen: sequence,
+		})
+		i = len(schema.Fields) - 1
+	}
+
+	// Fields are shared with the previous schema until changed
+	field := *schema.Fields[i]
+	field.LastSeen = sequence
+	field.Primary = field.Primary || primary
+
+	if valueType == "" {
+		field.Nullable = true
+	} else if !field.hasType(valueType) {
+		field.Types = append(append([]string{}, field.Types...), valueType)
+		sort.Strings(field.Types)
+	}
+
+	schema.Fields[i] = &field
+}
+
+func (field *FieldSchema) hasType(valueType string) bool {
+
+	if valueType == "" {
+		return field.Nullable
+	}
+
+	for _, t := range field.Types {
+		if t == valueType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (schema *CollectionSchema) clone() *CollectionSchema {
+
+	cloned := &CollectionSchema{
+		Fields: append(make([]*FieldSchema, 0, len(schema.Fields)+1), schema.Fields...),
+	}
+
+	cloned.buildIndex()
+
+	return cloned
+}
+
+// initSchema loads schema, or infers it from records of database which was
+// created before schema existed. Fields of such records are taken as seen at
+// the current sequence.
+func (database *Database) initSchema() error {
+
+	data, err := database.getValue(database.db, schemaKey)
+	if err == nil {
+		schema := &CollectionSchema{}
+		err := json.Unmarshal(data, schema)
+		if err != nil {
+			return err
+		}
+
+		schema.buildIndex()
+		database.schema = schema
+
+		return nil
+	} else if err != store.ErrNotFound {
+		return err
+	}
+
+	schema := &CollectionSchema{}
+	schema.buildIndex()
+
+	seq, err := getSequence(database.db)
+	if err != nil {
+		return err
+	}
+
+	iter := database.db.NewIterator(recordPrefix)
+	for iter.Next() {
+
+		data, err := database.decodeValue(iter.Key(), iter.Value())
+		if err != nil {
+			iter.Release()
+			return err
+		}
+
+		doc := make(map[string]interface{})
+		err = json.Unmarshal(data, &doc)
+		if err != nil {
+			iter.Release()
+			return err
+		}
+
+		fields := make([]Field, 0, len(doc))
+		for name, value := range doc {
+			fields = append(fields, Field{
+				Name:  name,
+				Value: value,
+			})
+		}
+
+		// Numbers of fields don't depend on order of map
+		sort.Slice(fields, func(i, j int) bool {
+			return fields[i].Name < fields[j].Name
+		})
+
+		schema = schema.observe(seq, fields)
+	}
+
+	err = iter.Error()
+	iter.Release()
+	if err != nil {
+		return err
+	}
+
+	if len(schema.Fields) > 0 {
+		log.WithFields(log.Fields{
+			"collection": database.name,
+			"fields":     len(schema.Fields),
+		}).Info("Inferred schema of collection from records")
+	}
+
+	data, err = database.encodeSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	err = database.db.Put(schemaKey, data)
+	if err != nil {
+		return err
+	}
+
+	database.schema = schema
+
+	return nil
+}
+
+// observeSchema puts schema with fields of event into batch. Schema is only
+// taken into use once batch is written.
+func (database *Database) observeSchema(batch store.Batch, sequence uint64, fields []Field) (*CollectionSchema, error) {
+
+	schema := database.schema.observe(sequence, fields)
+	if schema == database.schema {
+		return schema, nil
+	}
+
+	data, err := database.encodeSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	batch.Put(schemaKey, data)
+
+	return schema, nil
+}
+
+// encodeSchema encrypts schema like records, since names of fields tell about
+// content.
+func (database *Database) encodeSchema(schema *CollectionSchema) ([]byte, error) {
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return database.encryptValue(schemaKey, data)
+}
+
+func (database *Database) GetSchema() *CollectionSchema {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	return database.schema
+}
+
+// JSONSchema describes records of collection as JSON Schema. Integers are
+// described as numbers if field has seen both.
+func (schema *CollectionSchema) JSONSchema(title string) ([]byte, error) {
+
+	properties := make(map[string]interface{}, len(schema.Fields))
+	required := make([]string, 0)
+
+	for _, field := range schema.Fields {
+
+		types := make([]string, 0, len(field.Types)+1)
+		for _, t := range field.Types {
+			if t == typeInteger && field.hasType(typeNumber) {
+				continue
+			}
+
+			types = append(types, t)
+		}
+
+		if field.Nullable {
+			types = append(types, "null")
+		}
+
+		property := make(map[string]interface{})
+		if len(types) == 1 {
+			property["type"] = types[0]
+		} else if len(types) > 1 {
+			property["type"] = types
+		}
+
+		properties[field.Name] = property
+
+		if field.Primary {
+			required = append(required, field.Name)
+		}
+	}
+
+	doc := map[string]interface{}{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"title":      title,
+		"type":       "object",
+		"properties": properties,
+	}
+
+	if len(required) > 0 {
+		doc["required"] = required
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// Protobuf types of fields with a single type, nullable ones use wrappers.
+var protoScalarTypes = map[string]struct {
+	kind    descriptor.FieldDescriptorProto_Type
+	wrapper string
+}{
+	typeBoolean: {descriptor.FieldDescriptorProto_TYPE_BOOL, ".google.protobuf.BoolValue"},
+	typeInteger: {descriptor.FieldDescriptorProto_TYPE_INT64, ".google.protobuf.Int64Value"},
+	typeNumber:  {descriptor.FieldDescriptorProto_TYPE_DOUBLE, ".google.protobuf.DoubleValue"},
+	typeString:  {descriptor.FieldDescriptorProto_TYPE_STRING, ".google.protobuf.StringValue"},
+}
+
+// FileDescriptorSet describes records of collection as a protobuf message
+// named after collection, encoded as FileDescriptorSet. Objects, arrays and
+// fields of mixed types are described with well-known types of struct.proto.
+func (schema *CollectionSchema) FileDescriptorSet(collection string) ([]byte, error) {
+
+	message := &descriptor.DescriptorProto{
+		Name: proto.String(protoIdentifier(collection, true)),
+	}
+
+	used := make(map[string]bool, len(schema.Fields))
+	for _, field := range schema.Fields {
+
+		name := protoIdentifier(field.Name, false)
+		if used[name] {
+			name = name + "_" + strconv.Itoa(int(field.Number))
+		}
+		used[name] = true
+
+		fd := &descriptor.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(field.Name),
+			Number:   proto.Int32(field.Number),
+			Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+
+		setProtoType(fd, field)
+		message.Field = append(message.Field, fd)
+	}
+
+	file := &descriptor.FileDescriptorProto{
+		Name:        proto.String("gravity/snapshot/" + protoIdentifier(collection, false) + ".proto"),
+		Package:     proto.String("gravity.snapshot.collections"),
+		Syntax:      proto.String("proto3"),
+		Dependency:  []string{"google/protobuf/struct.proto", "google/protobuf/wrappers.proto"},
+		MessageType: []*descriptor.DescriptorProto{message},
+	}
+
+	return proto.Marshal(&descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{file},
+	})
+}
+
+func setProtoType(fd *descriptor.FieldDescriptorProto, field *FieldSchema) {
+
+	message := descriptor.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+
+	types := field.Types
+	if len(types) == 2 && field.hasType(typeInteger) && field.hasType(typeNumber) {
+		types = []string{typeNumber}
+	}
+
+	if len(types) != 1 {
+		fd.Type = message
+		fd.TypeName = proto.String(".google.protobuf.Value")
+		return
+	}
+
+	switch types[0] {
+	case typeObject:
+		fd.Type = message
+		fd.TypeName = proto.String(".google.protobuf.Struct")
+		return
+	case typeArray:
+		fd.Type = message
+		fd.TypeName = proto.String(".google.protobuf.ListValue")
+		return
+	}
+
+	scalar := protoScalarTypes[types[0]]
+	if field.Nullable {
+		fd.Type = message
+		fd.TypeName = proto.String(scalar.wrapper)
+		return
+	}
+
+	fd.Type = scalar.kind.Enum()
+}
+
+// protoIdentifier makes identifier of protobuf out of name, in CamelCase for
+// messages.
+func protoIdentifier(name string, camel bool) string {
+
+	var b strings.Builder
+	upper := camel
+	for _, r := range name {
+
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if camel {
+				upper = true
+			} else {
+				b.WriteRune('_')
+			}
+			continue
+		}
+
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+
+		b.WriteRune(r)
+	}
+
+	id := b.String()
+	if id == "" || unicode.IsDigit(rune(id[0])) {
+		if camel {
+			return "C" + id
+		}
+
+		return "f_" + id
+	}
+
+	return id
+}
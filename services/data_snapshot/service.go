@@ -3,19 +3,25 @@ package data_snapshot
 import (
 	"context"
 	"encoding/json"
+	"path"
+	"sort"
+	"time"
 
 	"github.com/nats-io/stan.go"
-	"github.com/prometheus/common/log"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	app "gravity-data-snapshot/app/interface"
 	pb "gravity-data-snapshot/pb"
+	"gravity-data-snapshot/services/data_snapshot/store"
 )
 
 type Service struct {
-	app   app.AppImpl
-	dbMgr *DatabaseManager
+	app         app.AppImpl
+	dbMgr       *DatabaseManager
+	checkpoints *CheckpointManager
 }
 
 type Field struct {
@@ -39,7 +45,17 @@ func CreateService(a app.AppImpl) *Service {
 	}
 
 	eb := a.GetEventBus()
-	err := eb.On("gravity.store.eventStored", func(msg *stan.Msg) {
+	dm.SetExpiredHandler(createExpiredNotifier(eb))
+	dm.SetRejectedHandler(createRejectedNotifier(eb))
+	dm.SetReplayer(createReplayer(eb))
+
+	// Ingestion resumes from start sequence after restore
+	opts := make([]stan.SubscriptionOption, 0)
+	if start := viper.GetUint64("event_store.start_sequence"); start > 0 {
+		opts = append(opts, stan.StartAtSequence(start))
+	}
+
+	_, err := eb.On("gravity.store.eventStored", func(msg *stan.Msg) {
 
 		log.Info(string(msg.Data))
 
@@ -53,8 +69,21 @@ func CreateService(a app.AppImpl) *Service {
 		// Getting database for specific collection
 		db := dm.GetDatabase(projection.Collection)
 		if db == nil {
+
+			// Rebuild replays events of quarantined collection later on,
+			// events of collections which failed to open otherwise are
+			// delivered again
+			if dm.AwaitsRebuild(projection.Collection) {
+				log.WithFields(log.Fields{
+					"collection": projection.Collection,
+					"seq":        msg.Sequence,
+				}).Warn("Skipped event of collection which awaits rebuild")
+				msg.Ack()
+			}
+
 			return
 		}
+		defer db.Release()
 
 		msg.Ack()
 
@@ -62,7 +91,7 @@ func CreateService(a app.AppImpl) *Service {
 		if err != nil {
 			log.Error(err)
 		}
-	})
+	}, opts...)
 
 	if err != nil {
 		return nil
@@ -70,34 +99,313 @@ func CreateService(a app.AppImpl) *Service {
 
 	// Preparing service
 	service := &Service{
-		app:   a,
-		dbMgr: dm,
+		app:         a,
+		dbMgr:       dm,
+		checkpoints: CreateCheckpointManager(dm),
 	}
 
 	return service
 }
 
+// createReplayer replays events of event store from sequence on for rebuild
+// of collections.
+func createReplayer(eb app.EventBusImpl) Replayer {
+	return func(start uint64, handler func(uint64, *Projection)) (func(), error) {
+
+		sub, err := eb.On("gravity.store.eventStored", func(msg *stan.Msg) {
+
+			var projection Projection
+			err := json.Unmarshal(msg.Data, &projection)
+			msg.Ack()
+			if err != nil {
+				return
+			}
+
+			handler(msg.Sequence, &projection)
+		}, stan.StartAtSequence(start))
+		if err != nil {
+			return nil, err
+		}
+
+		return func() {
+			err := sub.Unsubscribe()
+			if err != nil {
+				log.Error(err)
+			}
+		}, nil
+	}
+}
+
+type RejectedNotification struct {
+	Collection string `json:"collection"`
+	*RejectedEvent
+}
+
+// createRejectedNotifier emits events which were rejected by quota to dead
+// letter subject.
+func createRejectedNotifier(eb app.EventBusImpl) RejectedHandler {
+	return func(db *Database, event *RejectedEvent) error {
+
+		subject := getCollectionString(db.name, "quota_dead_letter_subject")
+		if subject == "" {
+			subject = "gravity.snapshot.eventRejected"
+		}
+
+		data, err := json.Marshal(&RejectedNotification{
+			Collection:    db.name,
+			RejectedEvent: event,
+		})
+		if err != nil {
+			return err
+		}
+
+		return eb.Emit(subject, data)
+	}
+}
+
+type ExpiredNotification struct {
+	Collection string `json:"collection"`
+	*ExpiredRecord
+}
+
+// createExpiredNotifier emits expired records to event bus for collections
+// which enable ttl_notify.
+func createExpiredNotifier(eb app.EventBusImpl) ExpiredHandler {
+	return func(db *Database, records []*ExpiredRecord) {
+
+		if !getCollectionBool(db.name, "ttl_notify") {
+			return
+		}
+
+		subject := getCollectionString(db.name, "ttl_notify_subject")
+		if subject == "" {
+			subject = "gravity.snapshot.recordExpired"
+		}
+
+		for _, record := range records {
+
+			data, err := json.Marshal(&ExpiredNotification{
+				Collection:    db.name,
+				ExpiredRecord: record,
+			})
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+
+			err = eb.Emit(subject, data)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"collection": db.name,
+				}).Error(err)
+			}
+		}
+	}
+}
+
+// missingCollection tells client why collection can't be read.
+func missingCollection(dm *DatabaseManager, name string) error {
+
+	if failure := dm.GetFailure(name); failure != nil {
+		return status.Error(codes.Unavailable, "Collection failed: "+failure.Error)
+	}
+
+	return status.Error(codes.NotFound, "No such collection")
+}
+
 func (service *Service) GetSnapshot(in *pb.GetSnapshotRequest, stream pb.DataSnapshot_GetSnapshotServer) error {
 
-	db := service.dbMgr.GetDatabase(in.Collection)
+	opts := &SnapshotOptions{
+		Compressed: in.AcceptCompressed,
+	}
+
+	if in.Filter != "" {
+		filter, err := ParseFilter(in.Filter)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		opts.Filter = filter
+	}
+
+	if len(in.IncludeFields) > 0 || len(in.ExcludeFields) > 0 {
+		mask, err := NewFieldMask(in.IncludeFields, in.ExcludeFields)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		opts.Fields = mask
+	}
+
+	if in.Partition != nil {
+		err := checkPartition(in.Partition.Start, in.Partition.End)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		opts.Start = in.Partition.Start
+		opts.End = in.Partition.End
+	}
+
+	checkpoint := in.Checkpoint
+	if len(in.Cursor) > 0 {
+		cursor, err := DecodeCursor(in.Cursor)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		if checkpoint != "" && checkpoint != cursor.Checkpoint {
+			return status.Error(codes.InvalidArgument, "Cursor is of another checkpoint")
+		}
+
+		checkpoint = cursor.Checkpoint
+		opts.Cursor = cursor
+
+		if service.checkpoints.IsStream(checkpoint) {
+			service.checkpoints.Renew(checkpoint, streamLease())
+		}
+	} else if in.Resumable && checkpoint == "" {
+		cp, err := service.checkpoints.CreateForStream(in.Collection)
+		if err == ErrCollectionNotFound {
+			return missingCollection(service.dbMgr, in.Collection)
+		} else if err != nil {
+			return checkpointError(err)
+		}
+
+		checkpoint = cp.Name
+	}
+
+	if in.Partition != nil && checkpoint == "" {
+		return status.Error(codes.InvalidArgument, "Partition is only read from its checkpoint")
+	}
+
+	if checkpoint != "" {
+		db, snapshot, done, err := service.checkpoints.Use(checkpoint, in.Collection)
+		if err != nil {
+			return checkpointError(err)
+		}
+
+		opts.Checkpoint = checkpoint
+		err = db.FetchSnapshotFrom(snapshot, stream, opts)
+		done()
+
+		// Checkpoint of stream is kept for resuming until stream is over,
+		// and lease of broken stream starts from when it broke
+		if service.checkpoints.IsStream(checkpoint) {
+			if err == nil {
+				service.checkpoints.Release(checkpoint)
+			} else {
+				service.checkpoints.Renew(checkpoint, streamLease())
+			}
+		}
+
+		return snapshotError(err)
+	}
+
+	db := service.dbMgr.GetExistingDatabase(in.Collection)
 	if db == nil {
-		return nil
+		return missingCollection(service.dbMgr, in.Collection)
 	}
+	defer db.Release()
 
-	err := db.FetchSnapshot(stream)
-	if err != nil {
+	return snapshotError(db.FetchSnapshot(stream, opts))
+}
+
+func snapshotError(err error) error {
+
+	switch err {
+	case ErrCollectionClosed:
+		return status.Error(codes.Aborted, err.Error())
+	case ErrInvalidCursor, ErrCursorMismatch:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return err
+}
+
+func (service *Service) GetSnapshotDelta(in *pb.GetSnapshotDeltaRequest, stream pb.DataSnapshot_GetSnapshotDeltaServer) error {
+
+	db := service.dbMgr.GetExistingDatabase(in.Collection)
+	if db == nil {
+		return missingCollection(service.dbMgr, in.Collection)
+	}
+	defer db.Release()
+
+	err := db.FetchDelta(in.SinceSequence, stream)
+	if err == ErrDeltaUnavailable {
+		return status.Error(codes.OutOfRange, "Changes are no longer available, full snapshot is required")
+	} else if err == ErrCollectionClosed {
+		return status.Error(codes.Aborted, err.Error())
+	} else if err != nil {
 		return err
 	}
 
 	return nil
 }
 
+func (service *Service) QueryIndex(in *pb.QueryIndexRequest, stream pb.DataSnapshot_QueryIndexServer) error {
+
+	db := service.dbMgr.GetExistingDatabase(in.Collection)
+	if db == nil {
+		return missingCollection(service.dbMgr, in.Collection)
+	}
+	defer db.Release()
+
+	query := &IndexQuery{
+		Index:  in.Index,
+		Values: make([]interface{}, len(in.Values)),
+		Limit:  int(in.Limit),
+	}
+
+	for i, data := range in.Values {
+		err := json.Unmarshal(data, &query.Values[i])
+		if err != nil {
+			return status.Error(codes.InvalidArgument, "Invalid value")
+		}
+	}
+
+	if len(in.RangeStart) > 0 {
+		err := json.Unmarshal(in.RangeStart, &query.RangeStart)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, "Invalid range start")
+		}
+
+		query.HasStart = true
+	}
+
+	if len(in.RangeEnd) > 0 {
+		err := json.Unmarshal(in.RangeEnd, &query.RangeEnd)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, "Invalid range end")
+		}
+
+		query.HasEnd = true
+	}
+
+	err := db.FetchIndex(query, stream)
+	switch err {
+	case nil:
+		return nil
+	case ErrIndexNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case ErrIndexNotReady:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case ErrInvalidQuery:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case ErrCollectionClosed:
+		return status.Error(codes.Aborted, err.Error())
+	}
+
+	return err
+}
+
 func (service *Service) GetSnapshotState(ctx context.Context, in *pb.GetSnapshotStateRequest) (*pb.GetSnapshotStateReply, error) {
 
-	db := service.dbMgr.GetDatabase(in.Collection)
+	db := service.dbMgr.GetExistingDatabase(in.Collection)
 	if db == nil {
-		return &pb.GetSnapshotStateReply{}, status.Error(codes.NotFound, "No such collection")
+		return &pb.GetSnapshotStateReply{}, missingCollection(service.dbMgr, in.Collection)
 	}
+	defer db.Release()
 
 	seq, err := db.GetSequence()
 	if err != nil {
@@ -109,3 +417,511 @@ func (service *Service) GetSnapshotState(ctx context.Context, in *pb.GetSnapshot
 		Sequence:   seq,
 	}, nil
 }
+
+func (service *Service) GetCollectionStats(ctx context.Context, in *pb.GetCollectionStatsRequest) (*pb.GetCollectionStatsReply, error) {
+
+	db := service.dbMgr.GetExistingDatabase(in.Collection)
+	if db == nil {
+		return &pb.GetCollectionStatsReply{}, missingCollection(service.dbMgr, in.Collection)
+	}
+	defer db.Release()
+
+	seq, stats, err := db.GetStats()
+	if err != nil {
+		return &pb.GetCollectionStatsReply{}, status.Error(codes.Internal, err.Error())
+	}
+
+	reply := &pb.GetCollectionStatsReply{
+		Collection:  in.Collection,
+		Sequence:    seq,
+		RecordCount: stats.RecordCount,
+		TotalBytes:  stats.TotalBytes,
+		LastUpdated: stats.LastUpdated,
+		Frozen:      db.IsFrozen(),
+	}
+
+	if quota := db.quota; quota != nil {
+		reply.MaxRecords = quota.MaxRecords
+		reply.MaxBytes = quota.MaxBytes
+		reply.MaxRecordSize = quota.MaxRecordSize
+		reply.QuotaPolicy = quota.Policy
+	}
+
+	return reply, nil
+}
+
+func (service *Service) GetRecordHistory(ctx context.Context, in *pb.GetRecordHistoryRequest) (*pb.GetRecordHistoryReply, error) {
+
+	db := service.dbMgr.GetExistingDatabase(in.Collection)
+	if db == nil {
+		return &pb.GetRecordHistoryReply{}, missingCollection(service.dbMgr, in.Collection)
+	}
+	defer db.Release()
+
+	var value interface{}
+	err := json.Unmarshal(in.Key, &value)
+	if err != nil {
+		return &pb.GetRecordHistoryReply{}, status.Error(codes.InvalidArgument, "Invalid key")
+	}
+
+	pk, err := db.primaryKeyOfValue(value)
+	if err != nil {
+		return &pb.GetRecordHistoryReply{}, status.Error(codes.InvalidArgument, "Invalid key")
+	}
+
+	seq, versions, err := db.GetRecordHistory(pk)
+	if err == ErrHistoryDisabled {
+		return &pb.GetRecordHistoryReply{}, status.Error(codes.FailedPrecondition, err.Error())
+	} else if err != nil {
+		return &pb.GetRecordHistoryReply{}, status.Error(codes.Internal, err.Error())
+	}
+
+	reply := &pb.GetRecordHistoryReply{
+		Collection: in.Collection,
+		Sequence:   seq,
+		Versions:   make([]*pb.RecordVersion, 0, len(versions)),
+	}
+
+	for _, version := range versions {
+		reply.Versions = append(reply.Versions, &pb.RecordVersion{
+			Sequence:  version.Sequence,
+			UpdatedAt: version.UpdatedAt,
+			Deleted:   version.Deleted,
+			Data:      version.Data,
+		})
+	}
+
+	return reply, nil
+}
+
+func (service *Service) GetSnapshotAt(in *pb.GetSnapshotAtRequest, stream pb.DataSnapshot_GetSnapshotAtServer) error {
+
+	db := service.dbMgr.GetExistingDatabase(in.Collection)
+	if db == nil {
+		return missingCollection(service.dbMgr, in.Collection)
+	}
+	defer db.Release()
+
+	err := db.FetchSnapshotAt(in.Sequence, stream)
+	switch err {
+	case nil:
+		return nil
+	case ErrHistoryDisabled:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case ErrHistoryUnavailable:
+		return status.Error(codes.OutOfRange, err.Error())
+	case ErrCollectionClosed:
+		return status.Error(codes.Aborted, err.Error())
+	}
+
+	return err
+}
+
+func (service *Service) DescribeCollection(ctx context.Context, in *pb.DescribeCollectionRequest) (*pb.DescribeCollectionReply, error) {
+
+	db := service.dbMgr.GetExistingDatabase(in.Collection)
+	if db == nil {
+		return &pb.DescribeCollectionReply{}, missingCollection(service.dbMgr, in.Collection)
+	}
+	defer db.Release()
+
+	schema := db.GetSchema()
+	reply := &pb.DescribeCollectionReply{
+		Collection: in.Collection,
+		Fields:     make([]*pb.SchemaField, 0, len(schema.Fields)),
+	}
+
+	for _, field := range schema.Fields {
+		reply.Fields = append(reply.Fields, &pb.SchemaField{
+			Name:      field.Name,
+			Number:    field.Number,
+			Types:     field.Types,
+			Nullable:  field.Nullable,
+			Primary:   field.Primary,
+			FirstSeen: field.FirstSeen,
+			LastSeen:  field.LastSeen,
+		})
+	}
+
+	var err error
+	switch in.Format {
+	case "":
+	case "json_schema":
+		var data []byte
+		data, err = schema.JSONSchema(in.Collection)
+		reply.JsonSchema = string(data)
+	case "protobuf":
+		reply.DescriptorSet, err = schema.FileDescriptorSet(in.Collection)
+	default:
+		return &pb.DescribeCollectionReply{}, status.Error(codes.InvalidArgument, "Unknown format of schema")
+	}
+
+	if err != nil {
+		return &pb.DescribeCollectionReply{}, status.Error(codes.Internal, err.Error())
+	}
+
+	return reply, nil
+}
+
+func (service *Service) GetChecksum(ctx context.Context, in *pb.GetChecksumRequest) (*pb.GetChecksumReply, error) {
+
+	var checksum *Checksum
+	var err error
+	if in.Checkpoint != "" {
+		db, snapshot, done, useErr := service.checkpoints.Use(in.Checkpoint, in.Collection)
+		if useErr != nil {
+			return &pb.GetChecksumReply{}, checkpointError(useErr)
+		}
+		defer done()
+
+		checksum, err = db.GetChecksumFrom(snapshot, in.Level, in.Nodes)
+	} else {
+		db := service.dbMgr.GetExistingDatabase(in.Collection)
+		if db == nil {
+			return &pb.GetChecksumReply{}, missingCollection(service.dbMgr, in.Collection)
+		}
+		defer db.Release()
+
+		if in.Sequence > 0 {
+			checksum, err = db.GetChecksumAt(in.Sequence, in.Level, in.Nodes)
+		} else {
+			checksum, err = db.GetChecksum(in.Level, in.Nodes)
+		}
+	}
+
+	switch err {
+	case nil:
+	case ErrChecksumLevel:
+		return &pb.GetChecksumReply{}, status.Error(codes.InvalidArgument, err.Error())
+	case ErrHistoryDisabled:
+		return &pb.GetChecksumReply{}, status.Error(codes.FailedPrecondition, err.Error())
+	case ErrHistoryUnavailable:
+		return &pb.GetChecksumReply{}, status.Error(codes.OutOfRange, err.Error())
+	case ErrCollectionClosed:
+		return &pb.GetChecksumReply{}, status.Error(codes.Aborted, err.Error())
+	default:
+		return &pb.GetChecksumReply{}, status.Error(codes.Internal, err.Error())
+	}
+
+	reply := &pb.GetChecksumReply{
+		Collection: in.Collection,
+		Sequence:   checksum.Sequence,
+		Root:       checksum.Root.Hash[:],
+		Count:      checksum.Root.Count,
+		Nodes:      make([]*pb.ChecksumNode, 0, len(checksum.Nodes)),
+		Depth:      checksumDepth,
+		Fanout:     checksumFanout,
+	}
+
+	for _, node := range checksum.Nodes {
+		hash := node.Hash
+		reply.Nodes = append(reply.Nodes, &pb.ChecksumNode{
+			Level: node.Level,
+			Index: node.Index,
+			Hash:  hash[:],
+			Count: node.Count,
+		})
+	}
+
+	return reply, nil
+}
+
+func (service *Service) GetChecksumLeaf(in *pb.GetChecksumLeafRequest, stream pb.DataSnapshot_GetChecksumLeafServer) error {
+
+	var err error
+	if in.Checkpoint != "" {
+		db, snapshot, done, useErr := service.checkpoints.Use(in.Checkpoint, in.Collection)
+		if useErr != nil {
+			return checkpointError(useErr)
+		}
+		defer done()
+
+		err = db.FetchChecksumLeaf(snapshot, stream, in.Leaf)
+	} else {
+		db := service.dbMgr.GetExistingDatabase(in.Collection)
+		if db == nil {
+			return missingCollection(service.dbMgr, in.Collection)
+		}
+		defer db.Release()
+
+		snapshot, snapshotErr := db.db.GetSnapshot()
+		if snapshotErr != nil {
+			return status.Error(codes.Internal, snapshotErr.Error())
+		}
+		defer snapshot.Release()
+
+		err = db.FetchChecksumLeaf(snapshot, stream, in.Leaf)
+	}
+
+	switch err {
+	case nil:
+		return nil
+	case ErrChecksumLeaf:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case ErrCollectionClosed:
+		return status.Error(codes.Aborted, err.Error())
+	}
+
+	return err
+}
+
+func (service *Service) ListCollections(ctx context.Context, in *pb.ListCollectionsRequest) (*pb.ListCollectionsReply, error) {
+
+	collections, err := service.dbMgr.ListCollections(in.Pattern)
+	if err == path.ErrBadPattern {
+		return &pb.ListCollectionsReply{}, status.Error(codes.InvalidArgument, "Invalid pattern of collection names")
+	} else if err != nil {
+		return &pb.ListCollectionsReply{}, status.Error(codes.Internal, err.Error())
+	}
+
+	reply := &pb.ListCollectionsReply{
+		Collections: make([]*pb.CollectionInfo, 0, len(collections)),
+	}
+
+	for _, info := range collections {
+
+		collection := &pb.CollectionInfo{
+			Collection: info.Name,
+			Sequence:   info.Sequence,
+			CreatedAt:  info.CreatedAt,
+			State:      info.State,
+			Error:      info.Error,
+		}
+
+		if info.Stats != nil {
+			collection.RecordCount = info.Stats.RecordCount
+			collection.TotalBytes = info.Stats.TotalBytes
+			collection.LastUpdated = info.Stats.LastUpdated
+		}
+
+		reply.Collections = append(reply.Collections, collection)
+	}
+
+	return reply, nil
+}
+
+func (service *Service) GetRecord(ctx context.Context, in *pb.GetRecordRequest) (*pb.GetRecordReply, error) {
+
+	seq, records, err := service.lookupRecords(in.Collection, in.Checkpoint, [][]byte{in.Key})
+	if err != nil {
+		return &pb.GetRecordReply{}, err
+	}
+
+	return &pb.GetRecordReply{
+		Collection: in.Collection,
+		Sequence:   seq,
+		Found:      records[0] != nil,
+		Data:       records[0],
+	}, nil
+}
+
+func (service *Service) BatchGet(ctx context.Context, in *pb.BatchGetRequest) (*pb.BatchGetReply, error) {
+
+	limit := viper.GetInt("database.max_batch_keys")
+	if limit > 0 && len(in.Keys) > limit {
+		return &pb.BatchGetReply{}, status.Error(codes.InvalidArgument, "Too many keys")
+	}
+
+	seq, records, err := service.lookupRecords(in.Collection, in.Checkpoint, in.Keys)
+	if err != nil {
+		return &pb.BatchGetReply{}, err
+	}
+
+	reply := &pb.BatchGetReply{
+		Collection: in.Collection,
+		Sequence:   seq,
+		Records:    make([]*pb.RecordLookup, 0, len(records)),
+	}
+
+	for i, data := range records {
+		reply.Records = append(reply.Records, &pb.RecordLookup{
+			Key:   in.Keys[i],
+			Found: data != nil,
+			Data:  data,
+		})
+	}
+
+	return reply, nil
+}
+
+func (service *Service) GetPartitions(ctx context.Context, in *pb.GetPartitionsRequest) (*pb.GetPartitionsReply, error) {
+
+	checkpoint := in.Checkpoint
+	if checkpoint == "" {
+		cp, err := service.checkpoints.Create("", []string{in.Collection}, 0)
+		if err == ErrCollectionNotFound {
+			return &pb.GetPartitionsReply{}, missingCollection(service.dbMgr, in.Collection)
+		} else if err != nil {
+			return &pb.GetPartitionsReply{}, checkpointError(err)
+		}
+
+		checkpoint = cp.Name
+	}
+
+	db, snapshot, done, err := service.checkpoints.Use(checkpoint, in.Collection)
+	if err != nil {
+		return &pb.GetPartitionsReply{}, checkpointError(err)
+	}
+
+	seq, err := getSequence(snapshot)
+	var partitions []*Partition
+	if err == nil {
+		partitions, err = db.Partitions(snapshot, int(in.Count))
+	}
+
+	done()
+
+	if err != nil {
+		// Checkpoint which was created for nothing is given up
+		if in.Checkpoint == "" {
+			service.checkpoints.Release(checkpoint)
+		}
+
+		return &pb.GetPartitionsReply{}, status.Error(codes.Internal, err.Error())
+	}
+
+	reply := &pb.GetPartitionsReply{
+		Collection: in.Collection,
+		Checkpoint: checkpoint,
+		Sequence:   seq,
+		Partitions: make([]*pb.SnapshotPartition, 0, len(partitions)),
+	}
+
+	for _, partition := range partitions {
+		reply.Partitions = append(reply.Partitions, &pb.SnapshotPartition{
+			Start:       partition.Start,
+			End:         partition.End,
+			RecordCount: partition.RecordCount,
+		})
+	}
+
+	return reply, nil
+}
+
+// lookupRecords reads records of JSON encoded primary keys from checkpoint,
+// or at the current sequence.
+func (service *Service) lookupRecords(collection string, checkpoint string, keys [][]byte) (uint64, [][]byte, error) {
+
+	var db *Database
+	var snapshot store.Snapshot
+	if checkpoint != "" {
+		cdb, s, done, err := service.checkpoints.Use(checkpoint, collection)
+		if err != nil {
+			return 0, nil, checkpointError(err)
+		}
+		defer done()
+
+		db = cdb
+		snapshot = s
+	} else {
+		db = service.dbMgr.GetExistingDatabase(collection)
+		if db == nil {
+			return 0, nil, missingCollection(service.dbMgr, collection)
+		}
+		defer db.Release()
+	}
+
+	pks := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+
+		var value interface{}
+		err := json.Unmarshal(key, &value)
+		if err != nil {
+			return 0, nil, status.Error(codes.InvalidArgument, "Invalid key")
+		}
+
+		pk, err := db.primaryKeyOfValue(value)
+		if err != nil {
+			return 0, nil, status.Error(codes.InvalidArgument, "Invalid key")
+		}
+
+		pks = append(pks, pk)
+	}
+
+	var seq uint64
+	var records [][]byte
+	var err error
+	if snapshot != nil {
+		seq, records, err = db.GetRecordsFrom(snapshot, pks)
+	} else {
+		seq, records, err = db.GetRecords(pks)
+	}
+
+	if err == ErrCollectionClosed {
+		return 0, nil, status.Error(codes.Aborted, err.Error())
+	} else if err != nil {
+		return 0, nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return seq, records, nil
+}
+
+func (service *Service) CreateCheckpoint(ctx context.Context, in *pb.CreateCheckpointRequest) (*pb.CheckpointReply, error) {
+
+	if len(in.Collections) == 0 {
+		return &pb.CheckpointReply{}, status.Error(codes.InvalidArgument, "No collections")
+	}
+
+	cp, err := service.checkpoints.Create(in.Name, in.Collections, time.Duration(in.Ttl)*time.Millisecond)
+	if err != nil {
+		return &pb.CheckpointReply{}, checkpointError(err)
+	}
+
+	return checkpointReply(cp), nil
+}
+
+func (service *Service) RenewCheckpoint(ctx context.Context, in *pb.RenewCheckpointRequest) (*pb.CheckpointReply, error) {
+
+	cp, err := service.checkpoints.Renew(in.Name, time.Duration(in.Ttl)*time.Millisecond)
+	if err != nil {
+		return &pb.CheckpointReply{}, checkpointError(err)
+	}
+
+	return checkpointReply(cp), nil
+}
+
+func (service *Service) ReleaseCheckpoint(ctx context.Context, in *pb.ReleaseCheckpointRequest) (*pb.ReleaseCheckpointReply, error) {
+
+	err := service.checkpoints.Release(in.Name)
+	if err != nil {
+		return &pb.ReleaseCheckpointReply{}, checkpointError(err)
+	}
+
+	return &pb.ReleaseCheckpointReply{
+		Name: in.Name,
+	}, nil
+}
+
+func checkpointReply(cp *Checkpoint) *pb.CheckpointReply {
+
+	reply := &pb.CheckpointReply{
+		Name:        cp.Name,
+		ExpiresAt:   cp.ExpiresAt.UnixNano(),
+		Collections: make([]*pb.CheckpointCollection, 0),
+	}
+
+	for name, seq := range cp.Sequences() {
+		reply.Collections = append(reply.Collections, &pb.CheckpointCollection{
+			Collection: name,
+			Sequence:   seq,
+		})
+	}
+
+	sort.Slice(reply.Collections, func(i, j int) bool {
+		return reply.Collections[i].Collection < reply.Collections[j].Collection
+	})
+
+	return reply
+}
+
+func checkpointError(err error) error {
+
+	switch err {
+	case ErrCheckpointNotFound, ErrCollectionNotFound, ErrCollectionNotInCheckpoint:
+		return status.Error(codes.NotFound, err.Error())
+	case ErrCheckpointExists:
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,153 @@
+package data_snapshot
+
+import (
+	"encoding/json"
+	"time"
+
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CollectionStats is maintained in the same batch with every change of
+// records, so it never drifts away from content of collection.
+type CollectionStats struct {
+	RecordCount uint64 `json:"count"`
+	TotalBytes  uint64 `json:"bytes"`
+	LastUpdated int64  `json:"updated_at"`
+}
+
+func (stats *CollectionStats) apply(countDelta int64, bytesDelta int64) *CollectionStats {
+	return &CollectionStats{
+		RecordCount: uint64(int64(stats.RecordCount) + countDelta),
+		TotalBytes:  uint64(int64(stats.TotalBytes) + bytesDelta),
+		LastUpdated: time.Now().UnixNano(),
+	}
+}
+
+// initStats loads stats, or counts records of database which was created
+// before stats existed.
+func (database *Database) initStats() error {
+
+	stats, err := database.readStats(database.db)
+	if err == nil {
+		database.stats = stats
+
+		return nil
+	} else if err != store.ErrNotFound {
+		return err
+	}
+
+	stats = &CollectionStats{}
+	iter := database.db.NewIterator(recordPrefix)
+	for iter.Next() {
+
+		data, err := database.decodeValue(iter.Key(), iter.Value())
+		if err != nil {
+			iter.Release()
+			return err
+		}
+
+		stats.RecordCount++
+		stats.TotalBytes += uint64(len(data))
+	}
+
+	err = iter.Error()
+	iter.Release()
+	if err != nil {
+		return err
+	}
+
+	if stats.RecordCount > 0 {
+		stats.LastUpdated = time.Now().UnixNano()
+
+		log.WithFields(log.Fields{
+			"collection": database.name,
+			"count":      stats.RecordCount,
+		}).Info("Initialized collection stats")
+	}
+
+	data, err := database.encodeStats(stats)
+	if err != nil {
+		return err
+	}
+
+	err = database.db.Put(statsKey, data)
+	if err != nil {
+		return err
+	}
+
+	database.stats = stats
+
+	return nil
+}
+
+// readStats reads stats which were written along with records, e.g. from
+// snapshot.
+func (database *Database) readStats(reader store.Reader) (*CollectionStats, error) {
+
+	data, err := reader.Get(statsKey)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = database.decryptValue(statsKey, data)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &CollectionStats{}
+	err = json.Unmarshal(data, stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (database *Database) encodeStats(stats *CollectionStats) ([]byte, error) {
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return nil, err
+	}
+
+	return database.encryptValue(statsKey, data)
+}
+
+// writeRecords writes batch with stats updated by the changes of records.
+func (database *Database) writeRecords(batch store.Batch, sequence uint64, countDelta int64, bytesDelta int64) error {
+
+	stats := database.stats.apply(countDelta, bytesDelta)
+	data, err := database.encodeStats(stats)
+	if err != nil {
+		return err
+	}
+
+	batch.Put(statsKey, data)
+
+	err = database.db.Write(batch)
+	if err != nil {
+		return err
+	}
+
+	database.stats = stats
+	updateStatsMetrics(database.name, sequence, stats)
+
+	return nil
+}
+
+func (database *Database) GetStats() (uint64, *CollectionStats, error) {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	seq, err := getSequence(database.db)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	stats := *database.stats
+
+	return seq, &stats, nil
+}
@@ -0,0 +1,409 @@
+package store
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var boltBucket = []byte("data")
+
+const (
+	boltOpenTimeout = 10 * time.Second
+	boltChunkSize   = 256
+)
+
+// BoltMmapSize is the initial size of memory map of bbolt files. Writers
+// which grow file beyond it wait for all read transactions to end.
+var BoltMmapSize = 1 << 30
+
+// BoltStore keeps all keys in a single bucket of a bbolt file. Snapshots are
+// long-running read transactions, so holding one prevents the file from being
+// remapped and delays writers which need to grow it beyond the memory map.
+// A goroutine which holds snapshot must not write to the same store, or it
+// waits for itself once file grows. Iterators of store read keys in chunks of
+// short transactions instead, so they can be used along with writes.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+type boltSnapshot struct {
+	tx *bolt.Tx
+}
+
+type boltBatch struct {
+	ops []boltOp
+}
+
+type boltOp struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+// boltStoreIterator sees writes made between its chunks, unlike iterators of
+// snapshots.
+type boltStoreIterator struct {
+	db      *bolt.DB
+	prefix  []byte
+	from    []byte
+	keys    [][]byte
+	values  [][]byte
+	pos     int
+	started bool
+	done    bool
+	err     error
+}
+
+type boltIterator struct {
+	cursor  *bolt.Cursor
+	prefix  []byte
+	key     []byte
+	value   []byte
+	started bool
+	err     error
+}
+
+func OpenBolt(path string) (Store, error) {
+
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(filepath.Join(path, "data.db"), 0644, &bolt.Options{
+		Timeout:         boltOpenTimeout,
+		InitialMmapSize: BoltMmapSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{
+		db: db,
+	}, nil
+}
+
+func isBoltCorrupted(err error) bool {
+	return err == bolt.ErrInvalid || err == bolt.ErrChecksum || err == bolt.ErrVersionMismatch
+}
+
+func (s *BoltStore) Get(key []byte) ([]byte, error) {
+
+	var data []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+
+		value := tx.Bucket(boltBucket).Get(key)
+		if value == nil {
+			return ErrNotFound
+		}
+
+		data = copyBytes(value)
+
+		return nil
+	})
+
+	return data, err
+}
+
+func (s *BoltStore) Put(key []byte, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put(key, value)
+	})
+}
+
+func (s *BoltStore) Delete(key []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete(key)
+	})
+}
+
+func (s *BoltStore) NewIterator(prefix []byte) Iterator {
+	return &boltStoreIterator{
+		db:     s.db,
+		prefix: prefix,
+		from:   prefix,
+	}
+}
+
+func (s *BoltStore) NewBatch() Batch {
+	return &boltBatch{
+		ops: make([]boltOp, 0),
+	}
+}
+
+func (s *BoltStore) Write(batch Batch) error {
+
+	ops := batch.(*boltBatch).ops
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(boltBucket)
+		for _, op := range ops {
+
+			if op.delete {
+				if err := bucket.Delete(op.key); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			if err := bucket.Put(op.key, op.value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *BoltStore) GetSnapshot() (Snapshot, error) {
+
+	tx, err := s.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &boltSnapshot{
+		tx: tx,
+	}, nil
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// Report has no levels, bbolt keeps freed pages for reuse instead of giving
+// them back to disk.
+func (s *BoltStore) Report() (*Report, error) {
+
+	info, err := os.Stat(s.db.Path())
+	if err != nil {
+		return nil, err
+	}
+
+	stats := s.db.Stats()
+
+	return &Report{
+		DiskSize: info.Size(),
+		Properties: map[string]string{
+			"page_size":     strconv.Itoa(s.db.Info().PageSize),
+			"free_pages":    strconv.Itoa(stats.FreePageN),
+			"pending_pages": strconv.Itoa(stats.PendingPageN),
+			"free_bytes":    strconv.Itoa(stats.FreeAlloc),
+			"open_read_tx":  strconv.Itoa(stats.OpenTxN),
+		},
+	}, nil
+}
+
+func (s *boltSnapshot) Get(key []byte) ([]byte, error) {
+
+	value := s.tx.Bucket(boltBucket).Get(key)
+	if value == nil {
+		return nil, ErrNotFound
+	}
+
+	return copyBytes(value), nil
+}
+
+func (s *boltSnapshot) NewIterator(prefix []byte) Iterator {
+	return newBoltIterator(s.tx, prefix)
+}
+
+func (s *boltSnapshot) Release() {
+	s.tx.Rollback()
+}
+
+func (b *boltBatch) Put(key []byte, value []byte) {
+	b.ops = append(b.ops, boltOp{
+		key:   copyBytes(key),
+		value: copyBytes(value),
+	})
+}
+
+func (b *boltBatch) Delete(key []byte) {
+	b.ops = append(b.ops, boltOp{
+		key:    copyBytes(key),
+		delete: true,
+	})
+}
+
+func (b *boltBatch) Len() int {
+	return len(b.ops)
+}
+
+func (it *boltStoreIterator) Seek(key []byte) bool {
+
+	if bytes.Compare(key, it.prefix) < 0 {
+		key = it.prefix
+	}
+
+	it.started = true
+	it.done = false
+	it.from = copyBytes(key)
+
+	return it.load()
+}
+
+func (it *boltStoreIterator) Next() bool {
+
+	if !it.started {
+		it.started = true
+		return it.load()
+	}
+
+	if it.pos+1 < len(it.keys) {
+		it.pos++
+		return true
+	}
+
+	return it.load()
+}
+
+// load reads the next chunk of keys starting at from.
+func (it *boltStoreIterator) load() bool {
+
+	it.keys = it.keys[:0]
+	it.values = it.values[:0]
+	it.pos = 0
+
+	if it.done || it.err != nil {
+		return false
+	}
+
+	it.err = it.db.View(func(tx *bolt.Tx) error {
+
+		cursor := tx.Bucket(boltBucket).Cursor()
+		for key, value := cursor.Seek(it.from); key != nil && bytes.HasPrefix(key, it.prefix); key, value = cursor.Next() {
+
+			if len(it.keys) == boltChunkSize {
+				it.from = copyBytes(key)
+				return nil
+			}
+
+			it.keys = append(it.keys, copyBytes(key))
+			it.values = append(it.values, copyBytes(value))
+		}
+
+		it.done = true
+
+		return nil
+	})
+
+	return it.err == nil && len(it.keys) > 0
+}
+
+func (it *boltStoreIterator) Key() []byte {
+
+	if it.pos >= len(it.keys) {
+		return nil
+	}
+
+	return it.keys[it.pos]
+}
+
+func (it *boltStoreIterator) Value() []byte {
+
+	if it.pos >= len(it.keys) {
+		return nil
+	}
+
+	return it.values[it.pos]
+}
+
+func (it *boltStoreIterator) Error() error {
+	return it.err
+}
+
+func (it *boltStoreIterator) Release() {
+	it.keys = nil
+	it.values = nil
+	it.done = true
+}
+
+func newBoltIterator(tx *bolt.Tx, prefix []byte) *boltIterator {
+	return &boltIterator{
+		cursor: tx.Bucket(boltBucket).Cursor(),
+		prefix: prefix,
+	}
+}
+
+func (it *boltIterator) Seek(key []byte) bool {
+
+	if it.cursor == nil {
+		return false
+	}
+
+	if bytes.Compare(key, it.prefix) < 0 {
+		key = it.prefix
+	}
+
+	it.started = true
+
+	return it.set(it.cursor.Seek(key))
+}
+
+func (it *boltIterator) Next() bool {
+
+	if it.cursor == nil {
+		return false
+	}
+
+	if !it.started {
+		it.started = true
+		return it.set(it.cursor.Seek(it.prefix))
+	}
+
+	if it.key == nil {
+		return false
+	}
+
+	return it.set(it.cursor.Next())
+}
+
+func (it *boltIterator) set(key []byte, value []byte) bool {
+
+	if key == nil || !bytes.HasPrefix(key, it.prefix) {
+		it.key = nil
+		it.value = nil
+		return false
+	}
+
+	it.key = key
+	it.value = value
+
+	return true
+}
+
+func (it *boltIterator) Key() []byte {
+	return it.key
+}
+
+func (it *boltIterator) Value() []byte {
+	return it.value
+}
+
+func (it *boltIterator) Error() error {
+	return it.err
+}
+
+func (it *boltIterator) Release() {
+
+	it.cursor = nil
+	it.key = nil
+	it.value = nil
+}
@@ -0,0 +1,216 @@
+package store
+
+import (
+	"strconv"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+type LevelDBStore struct {
+	db *leveldb.DB
+}
+
+type levelDBSnapshot struct {
+	snapshot *leveldb.Snapshot
+}
+
+type levelDBBatch struct {
+	batch *leveldb.Batch
+}
+
+type levelDBIterator struct {
+	iterator.Iterator
+}
+
+func OpenLevelDB(path string) (Store, error) {
+
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LevelDBStore{
+		db: db,
+	}, nil
+}
+
+// RecoverLevelDB rebuilds manifest of LevelDB from its tables, which is the
+// usual victim of unclean shutdowns.
+func RecoverLevelDB(path string) (Store, error) {
+
+	db, err := leveldb.RecoverFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LevelDBStore{
+		db: db,
+	}, nil
+}
+
+func isLevelDBCorrupted(err error) bool {
+	return errors.IsCorrupted(err)
+}
+
+// OpenMemory opens a LevelDB which keeps everything in memory, path is ignored.
+func OpenMemory(path string) (Store, error) {
+
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LevelDBStore{
+		db: db,
+	}, nil
+}
+
+// DB returns underlying LevelDB handle for engine specific operations.
+func (s *LevelDBStore) DB() *leveldb.DB {
+	return s.db
+}
+
+func (s *LevelDBStore) Get(key []byte) ([]byte, error) {
+	return levelDBGet(s.db.Get(key, nil))
+}
+
+func (s *LevelDBStore) Put(key []byte, value []byte) error {
+	return s.db.Put(key, value, nil)
+}
+
+func (s *LevelDBStore) Delete(key []byte) error {
+	return s.db.Delete(key, nil)
+}
+
+func (s *LevelDBStore) NewIterator(prefix []byte) Iterator {
+	return newLevelDBIterator(s.db.NewIterator(levelDBRange(prefix), nil))
+}
+
+func (s *LevelDBStore) NewBatch() Batch {
+	return &levelDBBatch{
+		batch: new(leveldb.Batch),
+	}
+}
+
+func (s *LevelDBStore) Write(batch Batch) error {
+	return s.db.Write(batch.(*levelDBBatch).batch, nil)
+}
+
+func (s *LevelDBStore) GetSnapshot() (Snapshot, error) {
+
+	snapshot, err := s.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	return &levelDBSnapshot{
+		snapshot: snapshot,
+	}, nil
+}
+
+func (s *LevelDBStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *LevelDBStore) Compact(start []byte, limit []byte) error {
+	return s.db.CompactRange(util.Range{
+		Start: start,
+		Limit: limit,
+	})
+}
+
+// Report tells write amplification as bytes written by flushes and
+// compactions of all levels to bytes flushed into level 0. Disk size counts
+// tables only, writes which are still in journal are not included.
+func (s *LevelDBStore) Report() (*Report, error) {
+
+	var stats leveldb.DBStats
+	err := s.db.Stats(&stats)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Levels:     make([]LevelReport, 0, len(stats.LevelSizes)),
+		OpenTables: int64(stats.OpenedTablesCount),
+		Properties: map[string]string{
+			"io_read":              strconv.FormatUint(stats.IORead, 10),
+			"io_write":             strconv.FormatUint(stats.IOWrite, 10),
+			"alive_snapshots":      strconv.Itoa(int(stats.AliveSnapshots)),
+			"alive_iterators":      strconv.Itoa(int(stats.AliveIterators)),
+			"write_delay_count":    strconv.Itoa(int(stats.WriteDelayCount)),
+			"write_delay_duration": stats.WriteDelayDuration.String(),
+			"block_cache_size":     strconv.Itoa(stats.BlockCacheSize),
+		},
+	}
+
+	written := int64(0)
+	for level, size := range stats.LevelSizes {
+		report.DiskSize += size
+		report.Levels = append(report.Levels, LevelReport{
+			Level:  level,
+			Tables: int64(stats.LevelTablesCounts[level]),
+			Size:   size,
+		})
+
+		written += stats.LevelWrite[level]
+	}
+
+	if len(stats.LevelWrite) > 0 && stats.LevelWrite[0] > 0 {
+		report.WriteAmplification = float64(written) / float64(stats.LevelWrite[0])
+	}
+
+	return report, nil
+}
+
+func (s *levelDBSnapshot) Get(key []byte) ([]byte, error) {
+	return levelDBGet(s.snapshot.Get(key, nil))
+}
+
+func (s *levelDBSnapshot) NewIterator(prefix []byte) Iterator {
+	return newLevelDBIterator(s.snapshot.NewIterator(levelDBRange(prefix), nil))
+}
+
+func (s *levelDBSnapshot) Release() {
+	s.snapshot.Release()
+}
+
+func (b *levelDBBatch) Put(key []byte, value []byte) {
+	b.batch.Put(key, value)
+}
+
+func (b *levelDBBatch) Delete(key []byte) {
+	b.batch.Delete(key)
+}
+
+func (b *levelDBBatch) Len() int {
+	return b.batch.Len()
+}
+
+func newLevelDBIterator(iter iterator.Iterator) Iterator {
+	return &levelDBIterator{
+		Iterator: iter,
+	}
+}
+
+func levelDBRange(prefix []byte) *util.Range {
+
+	if len(prefix) == 0 {
+		return nil
+	}
+
+	return util.BytesPrefix(prefix)
+}
+
+func levelDBGet(data []byte, err error) ([]byte, error) {
+
+	if err == leveldb.ErrNotFound {
+		return nil, ErrNotFound
+	}
+
+	return data, err
+}
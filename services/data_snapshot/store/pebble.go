@@ -0,0 +1,271 @@
+package store
+
+import (
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble"
+)
+
+type PebbleStore struct {
+	db *pebble.DB
+}
+
+type pebbleSnapshot struct {
+	snapshot *pebble.Snapshot
+}
+
+type pebbleBatch struct {
+	batch *pebble.Batch
+}
+
+type pebbleIterator struct {
+	iter    *pebble.Iterator
+	started bool
+	err     error
+}
+
+func OpenPebble(path string) (Store, error) {
+
+	db, err := pebble.Open(path, &pebble.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &PebbleStore{
+		db: db,
+	}, nil
+}
+
+// DB returns underlying Pebble handle for engine specific operations.
+func (s *PebbleStore) DB() *pebble.DB {
+	return s.db
+}
+
+func isPebbleCorrupted(err error) bool {
+	return errors.Is(err, pebble.ErrCorruption)
+}
+
+func (s *PebbleStore) Get(key []byte) ([]byte, error) {
+	return pebbleGet(s.db.Get(key))
+}
+
+func (s *PebbleStore) Put(key []byte, value []byte) error {
+	return s.db.Set(key, value, pebble.NoSync)
+}
+
+func (s *PebbleStore) Delete(key []byte) error {
+	return s.db.Delete(key, pebble.NoSync)
+}
+
+func (s *PebbleStore) NewIterator(prefix []byte) Iterator {
+	return newPebbleIterator(s.db.NewIter(pebbleIterOptions(prefix)))
+}
+
+func (s *PebbleStore) NewBatch() Batch {
+	return &pebbleBatch{
+		batch: s.db.NewBatch(),
+	}
+}
+
+func (s *PebbleStore) Write(batch Batch) error {
+
+	b := batch.(*pebbleBatch).batch
+	defer b.Close()
+
+	return s.db.Apply(b, pebble.NoSync)
+}
+
+func (s *PebbleStore) GetSnapshot() (Snapshot, error) {
+	return &pebbleSnapshot{
+		snapshot: s.db.NewSnapshot(),
+	}, nil
+}
+
+func (s *PebbleStore) Close() error {
+	return s.db.Close()
+}
+
+// Compact needs both ends of range, so missing ones are taken from the first
+// and the last key of store.
+func (s *PebbleStore) Compact(start []byte, limit []byte) error {
+
+	if start == nil {
+		start = []byte{}
+	}
+
+	if limit == nil {
+		iter, err := s.db.NewIter(nil)
+		if err != nil {
+			return err
+		}
+
+		if iter.Last() {
+			limit = append(append([]byte{}, iter.Key()...), 0)
+		}
+
+		err = iter.Close()
+		if err != nil {
+			return err
+		}
+
+		// Store is empty
+		if limit == nil {
+			return nil
+		}
+	}
+
+	return s.db.Compact(start, limit, true)
+}
+
+func (s *PebbleStore) Report() (*Report, error) {
+
+	metrics := s.db.Metrics()
+	total := metrics.Total()
+
+	report := &Report{
+		DiskSize:           int64(metrics.DiskSpaceUsage()),
+		Levels:             make([]LevelReport, 0, len(metrics.Levels)),
+		WriteAmplification: total.WriteAmp(),
+		OpenTables:         metrics.TableCache.Count,
+		Properties: map[string]string{
+			"compactions":     strconv.FormatInt(metrics.Compact.Count, 10),
+			"flushes":         strconv.FormatInt(metrics.Flush.Count, 10),
+			"memtable_size":   strconv.FormatUint(metrics.MemTable.Size, 10),
+			"wal_size":        strconv.FormatUint(metrics.WAL.PhysicalSize, 10),
+			"read_amp":        strconv.Itoa(metrics.ReadAmp()),
+			"compaction_debt": strconv.FormatUint(metrics.Compact.EstimatedDebt, 10),
+		},
+	}
+
+	for level, lm := range metrics.Levels {
+		report.Levels = append(report.Levels, LevelReport{
+			Level:  level,
+			Tables: lm.NumFiles,
+			Size:   lm.Size,
+		})
+	}
+
+	return report, nil
+}
+
+func (s *pebbleSnapshot) Get(key []byte) ([]byte, error) {
+	return pebbleGet(s.snapshot.Get(key))
+}
+
+func (s *pebbleSnapshot) NewIterator(prefix []byte) Iterator {
+	return newPebbleIterator(s.snapshot.NewIter(pebbleIterOptions(prefix)))
+}
+
+func (s *pebbleSnapshot) Release() {
+	s.snapshot.Close()
+}
+
+func (b *pebbleBatch) Put(key []byte, value []byte) {
+	b.batch.Set(key, value, nil)
+}
+
+func (b *pebbleBatch) Delete(key []byte) {
+	b.batch.Delete(key, nil)
+}
+
+func (b *pebbleBatch) Len() int {
+	return int(b.batch.Count())
+}
+
+func newPebbleIterator(iter *pebble.Iterator, err error) Iterator {
+	return &pebbleIterator{
+		iter: iter,
+		err:  err,
+	}
+}
+
+func (it *pebbleIterator) Seek(key []byte) bool {
+
+	if it.iter == nil {
+		return false
+	}
+
+	it.started = true
+
+	return it.iter.SeekGE(key)
+}
+
+func (it *pebbleIterator) Next() bool {
+
+	if it.iter == nil {
+		return false
+	}
+
+	if !it.started {
+		it.started = true
+		return it.iter.First()
+	}
+
+	return it.iter.Next()
+}
+
+func (it *pebbleIterator) Key() []byte {
+
+	if it.iter == nil || !it.iter.Valid() {
+		return nil
+	}
+
+	return it.iter.Key()
+}
+
+func (it *pebbleIterator) Value() []byte {
+
+	if it.iter == nil || !it.iter.Valid() {
+		return nil
+	}
+
+	return it.iter.Value()
+}
+
+func (it *pebbleIterator) Error() error {
+
+	if it.err != nil || it.iter == nil {
+		return it.err
+	}
+
+	return it.iter.Error()
+}
+
+func (it *pebbleIterator) Release() {
+
+	if it.iter == nil {
+		return
+	}
+
+	it.iter.Close()
+	it.iter = nil
+}
+
+func pebbleIterOptions(prefix []byte) *pebble.IterOptions {
+
+	if len(prefix) == 0 {
+		return nil
+	}
+
+	return &pebble.IterOptions{
+		LowerBound: prefix,
+		UpperBound: PrefixEnd(prefix),
+	}
+}
+
+func pebbleGet(value []byte, closer interface{ Close() error }, err error) ([]byte, error) {
+
+	if err == pebble.ErrNotFound {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	data := copyBytes(value)
+	closer.Close()
+
+	return data, nil
+}
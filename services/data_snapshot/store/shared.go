@@ -0,0 +1,235 @@
+package store
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrStoreClosed = errors.New("store: closed")
+
+// sharedStore is one store which keeps collections under their own key
+// prefixes. It is closed along with the last collection in it.
+type sharedStore struct {
+	store Store
+	path  string
+	refs  int
+}
+
+var shared = struct {
+	mutex  sync.Mutex
+	stores map[string]*sharedStore
+}{
+	stores: make(map[string]*sharedStore),
+}
+
+type prefixStore struct {
+	mutex  sync.Mutex
+	parent *sharedStore
+	prefix []byte
+	closed bool
+}
+
+// compactingPrefixStore is prefixStore of engine which supports compaction.
+type compactingPrefixStore struct {
+	*prefixStore
+}
+
+type prefixSnapshot struct {
+	snapshot Snapshot
+	prefix   []byte
+}
+
+type prefixBatch struct {
+	batch  Batch
+	prefix []byte
+}
+
+type prefixIterator struct {
+	iter   Iterator
+	prefix []byte
+}
+
+// OpenShared opens store of collection which shares store at path with other
+// collections, keys of collection are kept under prefix. Prefix of one
+// collection must never be a prefix of another one. Engine is only used by
+// the first collection which opens shared store.
+func OpenShared(engine string, path string, prefix []byte) (Store, error) {
+
+	shared.mutex.Lock()
+	defer shared.mutex.Unlock()
+
+	ss, ok := shared.stores[path]
+	if !ok {
+		s, err := Open(engine, path)
+		if err != nil {
+			return nil, err
+		}
+
+		ss = &sharedStore{
+			store: s,
+			path:  path,
+		}
+
+		shared.stores[path] = ss
+	}
+
+	ss.refs++
+
+	s := &prefixStore{
+		parent: ss,
+		prefix: copyBytes(prefix),
+	}
+
+	if _, ok := ss.store.(Compactor); ok {
+		return &compactingPrefixStore{s}, nil
+	}
+
+	return s, nil
+}
+
+func (s *prefixStore) Get(key []byte) ([]byte, error) {
+	return s.parent.store.Get(prefixKey(s.prefix, key))
+}
+
+func (s *prefixStore) Put(key []byte, value []byte) error {
+	return s.parent.store.Put(prefixKey(s.prefix, key), value)
+}
+
+func (s *prefixStore) Delete(key []byte) error {
+	return s.parent.store.Delete(prefixKey(s.prefix, key))
+}
+
+func (s *prefixStore) NewIterator(prefix []byte) Iterator {
+	return &prefixIterator{
+		iter:   s.parent.store.NewIterator(prefixKey(s.prefix, prefix)),
+		prefix: s.prefix,
+	}
+}
+
+func (s *prefixStore) NewBatch() Batch {
+	return &prefixBatch{
+		batch:  s.parent.store.NewBatch(),
+		prefix: s.prefix,
+	}
+}
+
+func (s *prefixStore) Write(batch Batch) error {
+	return s.parent.store.Write(batch.(*prefixBatch).batch)
+}
+
+func (s *prefixStore) GetSnapshot() (Snapshot, error) {
+
+	snapshot, err := s.parent.store.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	return &prefixSnapshot{
+		snapshot: snapshot,
+		prefix:   s.prefix,
+	}, nil
+}
+
+// Close gives up collection, shared store is closed once no collection uses
+// it.
+func (s *prefixStore) Close() error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return ErrStoreClosed
+	}
+
+	s.closed = true
+
+	shared.mutex.Lock()
+	defer shared.mutex.Unlock()
+
+	s.parent.refs--
+	if s.parent.refs > 0 {
+		return nil
+	}
+
+	delete(shared.stores, s.parent.path)
+
+	return s.parent.store.Close()
+}
+
+// Compact compacts key range of collection only.
+func (s *compactingPrefixStore) Compact(start []byte, limit []byte) error {
+
+	if limit == nil {
+		limit = PrefixEnd(s.prefix)
+	} else {
+		limit = prefixKey(s.prefix, limit)
+	}
+
+	return s.parent.store.(Compactor).Compact(prefixKey(s.prefix, start), limit)
+}
+
+func (s *prefixSnapshot) Get(key []byte) ([]byte, error) {
+	return s.snapshot.Get(prefixKey(s.prefix, key))
+}
+
+func (s *prefixSnapshot) NewIterator(prefix []byte) Iterator {
+	return &prefixIterator{
+		iter:   s.snapshot.NewIterator(prefixKey(s.prefix, prefix)),
+		prefix: s.prefix,
+	}
+}
+
+func (s *prefixSnapshot) Release() {
+	s.snapshot.Release()
+}
+
+func (b *prefixBatch) Put(key []byte, value []byte) {
+	b.batch.Put(prefixKey(b.prefix, key), value)
+}
+
+func (b *prefixBatch) Delete(key []byte) {
+	b.batch.Delete(prefixKey(b.prefix, key))
+}
+
+func (b *prefixBatch) Len() int {
+	return b.batch.Len()
+}
+
+func (iter *prefixIterator) Seek(key []byte) bool {
+	return iter.iter.Seek(prefixKey(iter.prefix, key))
+}
+
+func (iter *prefixIterator) Next() bool {
+	return iter.iter.Next()
+}
+
+// Key returns nil like iterators of engines while iterator isn't positioned.
+func (iter *prefixIterator) Key() []byte {
+
+	key := iter.iter.Key()
+	if key == nil {
+		return nil
+	}
+
+	return key[len(iter.prefix):]
+}
+
+func (iter *prefixIterator) Value() []byte {
+	return iter.iter.Value()
+}
+
+func (iter *prefixIterator) Error() error {
+	return iter.iter.Error()
+}
+
+func (iter *prefixIterator) Release() {
+	iter.iter.Release()
+}
+
+func prefixKey(prefix []byte, key []byte) []byte {
+
+	buf := make([]byte, 0, len(prefix)+len(key))
+	buf = append(buf, prefix...)
+
+	return append(buf, key...)
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	ErrNotFound            = errors.New("store: not found")
+	ErrRecoveryUnsupported = errors.New("store: engine can't recover corrupted data")
+)
+
+// Reader is the read side shared by stores and their snapshots.
+type Reader interface {
+	Get(key []byte) ([]byte, error)
+	NewIterator(prefix []byte) Iterator
+}
+
+// Store is a sorted key-value engine which keeps data of a collection.
+type Store interface {
+	Reader
+	Put(key []byte, value []byte) error
+	Delete(key []byte) error
+	NewBatch() Batch
+	Write(batch Batch) error
+	GetSnapshot() (Snapshot, error)
+	Close() error
+}
+
+// Snapshot is a consistent read-only view of a store at a point in time.
+type Snapshot interface {
+	Reader
+	Release()
+}
+
+// Batch collects writes which are applied atomically by Store.Write.
+type Batch interface {
+	Put(key []byte, value []byte)
+	Delete(key []byte)
+	Len() int
+}
+
+// Iterator walks keys in ascending order. It is not positioned until the
+// first call to Next or Seek, the same way goleveldb iterators behave.
+// Key and Value are only valid until the next call which moves the iterator.
+type Iterator interface {
+	Seek(key []byte) bool
+	Next() bool
+	Key() []byte
+	Value() []byte
+	Error() error
+	Release()
+}
+
+// Compactor is implemented by engines which compact key range on demand.
+// Nil start and limit mean the first and the last key.
+type Compactor interface {
+	Compact(start []byte, limit []byte) error
+}
+
+// Reporter is implemented by engines which report state of their storage.
+type Reporter interface {
+	Report() (*Report, error)
+}
+
+// Report describes storage of a store. Levels are empty for engines which
+// are not LSM trees, properties are specific to engine.
+type Report struct {
+	DiskSize           int64
+	Levels             []LevelReport
+	WriteAmplification float64
+	OpenTables         int64
+	Properties         map[string]string
+}
+
+type LevelReport struct {
+	Level  int
+	Tables int64
+	Size   int64
+}
+
+type OpenFunc func(path string) (Store, error)
+
+// DefaultEngine is used if no engine is named.
+const DefaultEngine = "leveldb"
+
+var engines = map[string]OpenFunc{
+	"leveldb": OpenLevelDB,
+	"bbolt":   OpenBolt,
+	"pebble":  OpenPebble,
+	"memory":  OpenMemory,
+}
+
+// Engines which are able to rebuild their metadata from data files.
+var recoverers = map[string]OpenFunc{
+	"leveldb": RecoverLevelDB,
+}
+
+// Open opens store at specific path with the named engine.
+func Open(engine string, path string) (Store, error) {
+
+	if engine == "" {
+		engine = DefaultEngine
+	}
+
+	open, ok := engines[engine]
+	if !ok {
+		return nil, fmt.Errorf("store: unknown engine \"%s\"", engine)
+	}
+
+	return open(path)
+}
+
+// Recover opens store whose data was found corrupted, keeping what can be
+// read.
+func Recover(engine string, path string) (Store, error) {
+
+	if engine == "" {
+		engine = DefaultEngine
+	}
+
+	recover, ok := recoverers[engine]
+	if !ok {
+		return nil, ErrRecoveryUnsupported
+	}
+
+	return recover(path)
+}
+
+// IsCorrupted tells whether error was caused by corrupted data of store.
+func IsCorrupted(err error) bool {
+	return isLevelDBCorrupted(err) || isPebbleCorrupted(err) || isBoltCorrupted(err)
+}
+
+// Engines returns names of all supported engines.
+func Engines() []string {
+
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// PrefixEnd returns the smallest key which is greater than all keys with
+// the prefix, or nil if there is no such key.
+func PrefixEnd(prefix []byte) []byte {
+
+	end := make([]byte, len(prefix))
+	copy(end, prefix)
+
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+
+	return nil
+}
+
+func copyBytes(data []byte) []byte {
+
+	if data == nil {
+		return nil
+	}
+
+	buf := make([]byte, len(data))
+	copy(buf, data)
+
+	return buf
+}
@@ -0,0 +1,276 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testStore struct {
+	name   string
+	open   func(path string) (Store, error)
+	reopen bool
+}
+
+// testStores returns all engines, along with shared stores of them where
+// another collection is kept next to the tested one.
+func testStores() []testStore {
+
+	stores := make([]testStore, 0)
+	for _, engine := range Engines() {
+
+		engine := engine
+		stores = append(stores, testStore{
+			name: engine,
+			open: func(path string) (Store, error) {
+				return Open(engine, path)
+			},
+			reopen: engine != "memory",
+		}, testStore{
+			name: "shared " + engine,
+			open: func(path string) (Store, error) {
+				return OpenShared(engine, path, []byte("b/"))
+			},
+			reopen: engine != "memory",
+		})
+	}
+
+	return stores
+}
+
+func withStore(t *testing.T, ts testStore, fn func(t *testing.T, s Store, path string)) {
+
+	dir, err := ioutil.TempDir("", "store-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "db")
+
+	// Keys of neighbour have to stay out of sight of shared store
+	if strings.HasPrefix(ts.name, "shared ") {
+		neighbour, err := OpenShared(strings.TrimPrefix(ts.name, "shared "), path, []byte("a/"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer neighbour.Close()
+
+		for _, key := range []string{"k1", "k2", "x"} {
+			if err := neighbour.Put([]byte(key), []byte("neighbour")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	s, err := ts.open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fn(t, s, path)
+}
+
+// dump returns pairs of iterator as "key=value" after seek if it is set.
+func dump(iter Iterator, seek []byte) string {
+
+	defer iter.Release()
+
+	pairs := make([]string, 0)
+	ok := false
+	if seek != nil {
+		ok = iter.Seek(seek)
+	} else {
+		ok = iter.Next()
+	}
+
+	for ; ok; ok = iter.Next() {
+		pairs = append(pairs, string(iter.Key())+"="+string(iter.Value()))
+	}
+
+	if err := iter.Error(); err != nil {
+		pairs = append(pairs, "error: "+err.Error())
+	}
+
+	return strings.Join(pairs, " ")
+}
+
+func TestStoreContract(t *testing.T) {
+
+	for _, ts := range testStores() {
+		t.Run(ts.name, func(t *testing.T) {
+			withStore(t, ts, func(t *testing.T, s Store, path string) {
+
+				for _, key := range []string{"k3", "k1", "j", "k2", "l"} {
+					if err := s.Put([]byte(key), []byte("v"+key)); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				if err := s.Delete([]byte("l")); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := s.Delete([]byte("missing")); err != nil {
+					t.Errorf("Delete of missing key: %v", err)
+				}
+
+				// Iterator which isn't positioned has no pair
+				iter := s.NewIterator([]byte("k"))
+				if iter.Key() != nil || iter.Value() != nil {
+					t.Errorf("iterator before Next has pair %q=%q", iter.Key(), iter.Value())
+				}
+
+				for iter.Next() {
+				}
+
+				if iter.Key() != nil || iter.Value() != nil {
+					t.Errorf("exhausted iterator has pair %q=%q", iter.Key(), iter.Value())
+				}
+				iter.Release()
+
+				tests := []struct {
+					prefix string
+					seek   string
+					want   string
+				}{
+					{"", "", "j=vj k1=vk1 k2=vk2 k3=vk3"},
+					{"k", "", "k1=vk1 k2=vk2 k3=vk3"},
+					{"k", "k2", "k2=vk2 k3=vk3"},
+					{"k", "k15", "k2=vk2 k3=vk3"},
+					{"k", "a", "k1=vk1 k2=vk2 k3=vk3"},
+					{"k", "k4", ""},
+					{"k2", "", "k2=vk2"},
+					{"x", "", ""},
+				}
+
+				for _, test := range tests {
+
+					var seek []byte
+					if test.seek != "" {
+						seek = []byte(test.seek)
+					}
+
+					if got := dump(s.NewIterator([]byte(test.prefix)), seek); got != test.want {
+						t.Errorf("iterator of %q after %q = %q, want %q", test.prefix, test.seek, got, test.want)
+					}
+				}
+
+				value, err := s.Get([]byte("k1"))
+				if err != nil || string(value) != "vk1" {
+					t.Errorf("Get = %q, %v", value, err)
+				}
+
+				for _, key := range []string{"l", "missing", "k"} {
+					if _, err := s.Get([]byte(key)); err != ErrNotFound {
+						t.Errorf("Get(%q) = %v, want %v", key, err, ErrNotFound)
+					}
+				}
+
+				// Snapshot doesn't see later writes
+				snapshot, err := s.GetSnapshot()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				batch := s.NewBatch()
+				batch.Put([]byte("k4"), []byte("vk4"))
+				batch.Delete([]byte("k1"))
+				batch.Put([]byte("k2"), []byte("new"))
+				if batch.Len() != 3 {
+					t.Errorf("Len of batch = %d, want 3", batch.Len())
+				}
+
+				if err := s.Write(batch); err != nil {
+					t.Fatal(err)
+				}
+
+				if got := dump(snapshot.NewIterator([]byte("k")), nil); got != "k1=vk1 k2=vk2 k3=vk3" {
+					t.Errorf("snapshot = %q", got)
+				}
+
+				if _, err := snapshot.Get([]byte("k4")); err != ErrNotFound {
+					t.Errorf("Get of later key from snapshot = %v, want %v", err, ErrNotFound)
+				}
+
+				snapshot.Release()
+
+				want := "j=vj k2=new k3=vk3 k4=vk4"
+				if got := dump(s.NewIterator(nil), nil); got != want {
+					t.Errorf("store after batch = %q, want %q", got, want)
+				}
+
+				if err := s.Close(); err != nil {
+					t.Fatal(err)
+				}
+
+				if !ts.reopen {
+					return
+				}
+
+				s, err = ts.open(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer s.Close()
+
+				if got := dump(s.NewIterator(nil), nil); got != want {
+					t.Errorf("reopened store = %q, want %q", got, want)
+				}
+			})
+		})
+	}
+}
+
+func TestStoreIteratorWithWrites(t *testing.T) {
+
+	// Writers which grow bbolt file must not wait for store iterators
+	mmapSize := BoltMmapSize
+	BoltMmapSize = 1 << 16
+	defer func() {
+		BoltMmapSize = mmapSize
+	}()
+
+	for _, ts := range testStores() {
+		t.Run(ts.name, func(t *testing.T) {
+			withStore(t, ts, func(t *testing.T, s Store, path string) {
+				defer s.Close()
+
+				const keys = 4 * boltChunkSize
+				for i := 0; i < keys; i++ {
+					if err := s.Put([]byte(fmt.Sprintf("k%05d", i)), []byte("v")); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				done := make(chan int)
+				go func() {
+
+					count := 0
+					iter := s.NewIterator([]byte("k"))
+					for iter.Next() {
+						count++
+						s.Put([]byte(fmt.Sprintf("w%05d", count)), bytes.Repeat([]byte("x"), 1024))
+					}
+					iter.Release()
+
+					done <- count
+				}()
+
+				select {
+				case count := <-done:
+					if count != keys {
+						t.Errorf("iterator read %d keys, want %d", count, keys)
+					}
+				case <-time.After(30 * time.Second):
+					t.Fatal("writes during iteration are blocked")
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package data_snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	pb "gravity-data-snapshot/pb"
+	"gravity-data-snapshot/services/data_snapshot/store"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrDeltaUnavailable = errors.New("changes since requested sequence are no longer available")
+
+const (
+	defaultTombstoneRetention  = 24 * time.Hour
+	defaultTombstoneGCInterval = 10 * time.Minute
+)
+
+// Tombstone is left behind by deleted record, so clients which hold an older
+// snapshot are able to learn which records disappeared.
+type Tombstone struct {
+	Sequence  uint64          `json:"seq"`
+	DeletedAt int64           `json:"deleted_at"`
+	Key       json.RawMessage `json:"key"`
+}
+
+// trackChange records sequence of the latest change of record to change log.
+func (database *Database) trackChange(batch store.Batch, sequence uint64, pk []byte) error {
+
+	revData, err := database.db.Get(revisionKey(pk))
+	if err == nil {
+		batch.Delete(changeKey(BytesToUint64(revData), pk))
+	} else if err != store.ErrNotFound {
+		return err
+	}
+
+	batch.Put(revisionKey(pk), Uint64ToBytes(sequence))
+	batch.Put(changeKey(sequence, pk), []byte{})
+
+	return nil
+}
+
+func (database *Database) putTombstone(batch store.Batch, sequence uint64, pk []byte, keyData []byte) error {
+
+	data, err := json.Marshal(&Tombstone{
+		Sequence:  sequence,
+		DeletedAt: time.Now().UnixNano(),
+		Key:       nullIfEmpty(keyData),
+	})
+	if err != nil {
+		return err
+	}
+
+	value, err := database.encryptValue(tombstoneKey(pk), data)
+	if err != nil {
+		return err
+	}
+
+	batch.Put(tombstoneKey(pk), value)
+
+	return database.trackChange(batch, sequence, pk)
+}
+
+// initDeltaHorizon starts change tracking for database which was created
+// before tombstones existed, records written until now are not in change log.
+// Horizon is the oldest sequence which clients can fetch delta since, changes
+// of earlier sequences may be missing from change log.
+func (database *Database) initDeltaHorizon() error {
+
+	_, err := database.db.Get(deltaHorizonKey)
+	if err != store.ErrNotFound {
+		return err
+	}
+
+	seq, err := database.GetSequence()
+	if err != nil {
+		return err
+	}
+
+	// Nothing was written at sequence 0
+	if seq > 0 {
+		seq++
+	}
+
+	return database.db.Put(deltaHorizonKey, Uint64ToBytes(seq))
+}
+
+func getDeltaHorizon(reader store.Reader) (uint64, error) {
+
+	data, err := reader.Get(deltaHorizonKey)
+	if err == store.ErrNotFound {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return BytesToUint64(data), nil
+}
+
+// CollectTombstones removes tombstones which are older than retention and
+// moves delta horizon forward accordingly.
+func (database *Database) CollectTombstones(retention time.Duration) (int, error) {
+
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+
+	horizon, err := getDeltaHorizon(database.db)
+	if err != nil {
+		return 0, err
+	}
+
+	deadline := time.Now().Add(-retention).UnixNano()
+	batch := database.db.NewBatch()
+	count := 0
+
+	iter := database.db.NewIterator(tombstonePrefix)
+	for iter.Next() {
+
+		data, err := database.decodeValue(iter.Key(), iter.Value())
+		if err != nil {
+			iter.Release()
+			return 0, err
+		}
+
+		var tombstone Tombstone
+		err = json.Unmarshal(data, &tombstone)
+		if err != nil {
+			iter.Release()
+			return 0, err
+		}
+
+		if tombstone.DeletedAt > deadline {
+			continue
+		}
+
+		pk := primaryKeyOfTombstone(iter.Key())
+		batch.Delete(tombstoneKey(pk))
+		batch.Delete(revisionKey(pk))
+		batch.Delete(changeKey(tombstone.Sequence, pk))
+		count++
+
+		// Delta since sequence of removed tombstone would miss it
+		if tombstone.Sequence >= horizon {
+			horizon = tombstone.Sequence + 1
+		}
+	}
+
+	err = iter.Error()
+	iter.Release()
+	if err != nil {
+		return 0, err
+	}
+
+	if count == 0 {
+		return 0, nil
+	}
+
+	batch.Put(deltaHorizonKey, Uint64ToBytes(horizon))
+
+	return count, database.db.Write(batch)
+}
+
+func (database *Database) runTombstoneCollector() {
+
+	retention := getCollectionDuration(database.name, "tombstone_retention", defaultTombstoneRetention)
+	interval := getCollectionDuration(database.name, "tombstone_gc_interval", defaultTombstoneGCInterval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for database.tick(ticker) {
+
+		count, err := database.CollectTombstones(retention)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"collection": database.name,
+			}).Error(err)
+			continue
+		}
+
+		if count > 0 {
+			log.WithFields(log.Fields{
+				"collection": database.name,
+				"count":      count,
+			}).Info("Removed expired tombstones")
+		}
+	}
+}
+
+func primaryKeyOfTombstone(key []byte) []byte {
+
+	pk := make([]byte, len(key)-len(tombstonePrefix))
+	copy(pk, key[len(tombstonePrefix):])
+
+	return pk
+}
+
+// FetchDelta streams records which were changed or deleted at or after the
+// sequence. Sequence is inclusive so entries are safe to be applied again.
+func (database *Database) FetchDelta(since uint64, stream pb.DataSnapshot_GetSnapshotDeltaServer) error {
+
+	snapshot, err := database.db.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Release()
+
+	seq, err := getSequence(snapshot)
+	if err != nil {
+		return err
+	}
+
+	horizon, err := getDeltaHorizon(snapshot)
+	if err != nil {
+		return err
+	}
+
+	if since < horizon {
+		return ErrDeltaUnavailable
+	}
+
+	log.WithFields(log.Fields{
+		"collection": database.name,
+		"seq":        seq,
+		"since":      since,
+	}).Info("Client requests changes")
+
+	writer := database.newPacketWriter(stream, seq)
+
+	iter := snapshot.NewIterator(changePrefix)
+	defer iter.Release()
+
+	for ok := iter.Seek(changeKey(since, nil)); ok; ok = iter.Next() {
+
+		sequence, pk := parseOrderedKey(changePrefix, iter.Key())
+
+		entry, err := database.getChangeEntry(snapshot, sequence, pk)
+		if err != nil {
+			return err
+		}
+
+		err = writer.Write(entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = iter.Error()
+	if err != nil {
+		return err
+	}
+
+	return writer.Flush()
+}
+
+func (database *Database) getChangeEntry(snapshot store.Snapshot, sequence uint64, pk []byte) (*pb.SnapshotEntry, error) {
+
+	data, err := database.getValue(snapshot, recordKey(pk))
+	if err == nil {
+		return &pb.SnapshotEntry{
+			Data:     data,
+			Sequence: sequence,
+		}, nil
+	} else if err != store.ErrNotFound {
+		return nil, err
+	}
+
+	data, err = database.getValue(snapshot, tombstoneKey(pk))
+	if err != nil {
+		return nil, err
+	}
+
+	var tombstone Tombstone
+	err = json.Unmarshal(data, &tombstone)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.SnapshotEntry{
+		Data:     tombstone.Key,
+		Sequence: sequence,
+		Deleted:  true,
+	}, nil
+}
@@ -0,0 +1,164 @@
+package data_snapshot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func deleteTestRecord(t *testing.T, db *Database, seq uint64, id float64) {
+
+	projection := &Projection{
+		Collection: "t",
+		Method:     "delete",
+		Fields:     []Field{{Name: "id", Value: id, Primary: true}},
+	}
+
+	err := db.ProcessData(seq, projection)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// deltaOf returns entries of delta as "seq:data" with "-" for deleted ones.
+func deltaOf(t *testing.T, db *Database, since uint64) (string, error) {
+
+	stream := &testStream{}
+	err := db.FetchDelta(since, stream)
+	if err != nil {
+		return "", err
+	}
+
+	entries := make([]string, 0)
+	for _, entry := range stream.entries() {
+
+		mark := ""
+		if entry.Deleted {
+			mark = "-"
+		}
+
+		entries = append(entries, fmt.Sprintf("%s%d:%s", mark, entry.Sequence, entry.Data))
+	}
+
+	return strings.Join(entries, " "), nil
+}
+
+func TestFetchDelta(t *testing.T) {
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	for i := 0; i < 5; i++ {
+		writeTestRecord(t, db, uint64(i+1), float64(i))
+	}
+
+	deleteTestRecord(t, db, 6, 1)
+	deleteTestRecord(t, db, 7, 3)
+	writeTestRecord(t, db, 8, 0)
+
+	tests := []struct {
+		since uint64
+		want  string
+	}{
+		{0, `3:{"id":2} 5:{"id":4} -6:{"id":1} -7:{"id":3} 8:{"id":0}`},
+		{6, `-6:{"id":1} -7:{"id":3} 8:{"id":0}`},
+		{8, `8:{"id":0}`},
+		{9, ``},
+	}
+
+	for _, test := range tests {
+
+		delta, err := deltaOf(t, db, test.since)
+		if err != nil {
+			t.Fatalf("FetchDelta(%d): %v", test.since, err)
+		}
+
+		if delta != test.want {
+			t.Errorf("FetchDelta(%d) = %s, want %s", test.since, delta, test.want)
+		}
+	}
+}
+
+func TestCollectTombstones(t *testing.T) {
+
+	db, done := openTestDatabase(t, "memory")
+	defer done()
+
+	for i := 0; i < 3; i++ {
+		writeTestRecord(t, db, uint64(i+1), float64(i))
+	}
+
+	deleteTestRecord(t, db, 4, 0)
+	deleteTestRecord(t, db, 5, 1)
+	writeTestRecord(t, db, 6, 2)
+
+	count, err := db.CollectTombstones(0)
+	if err != nil || count != 2 {
+		t.Fatalf("CollectTombstones = %d, %v, want 2", count, err)
+	}
+
+	// Horizon is past the last tombstone which was removed
+	horizon, err := getDeltaHorizon(db.db)
+	if err != nil || horizon != 6 {
+		t.Fatalf("horizon = %d, %v, want 6", horizon, err)
+	}
+
+	tests := []struct {
+		since uint64
+		want  string
+		err   error
+	}{
+		{0, ``, ErrDeltaUnavailable},
+		{5, ``, ErrDeltaUnavailable},
+		{6, `6:{"id":2}`, nil},
+		{7, ``, nil},
+	}
+
+	for _, test := range tests {
+
+		delta, err := deltaOf(t, db, test.since)
+		if err != test.err || delta != test.want {
+			t.Errorf("FetchDelta(%d) = %s, %v, want %s, %v", test.since, delta, err, test.want, test.err)
+		}
+	}
+
+	// Tombstones of retention are kept
+	deleteTestRecord(t, db, 7, 2)
+	count, err = db.CollectTombstones(defaultTombstoneRetention)
+	if err != nil || count != 0 {
+		t.Errorf("CollectTombstones within retention = %d, %v, want 0", count, err)
+	}
+}
+
+func TestInitDeltaHorizon(t *testing.T) {
+
+	tests := []struct {
+		records int
+		want    uint64
+	}{
+		{0, 0},
+		{3, 4},
+	}
+
+	for _, test := range tests {
+
+		db, done := openTestDatabase(t, "memory")
+
+		for i := 0; i < test.records; i++ {
+			writeTestRecord(t, db, uint64(i+1), float64(i))
+		}
+
+		// Database which was created before tombstones has no horizon
+		err := db.db.Delete(deltaHorizonKey)
+		if err == nil {
+			err = db.initDeltaHorizon()
+		}
+
+		horizon, _ := getDeltaHorizon(db.db)
+		if err != nil || horizon != test.want {
+			t.Errorf("initDeltaHorizon after %d records = %d, %v, want %d", test.records, horizon, err, test.want)
+		}
+
+		done()
+	}
+}